@@ -32,6 +32,33 @@ typedef struct {
 	u64 fd;
 } close_event_t;
 
+typedef struct {
+	// fields matching struct CommonEvent from tracer.go
+	common_event_t common;
+
+	// fields matching the struct for dup from event-structs-generated.go
+	u64 fildes;
+} dup_event_t;
+
+typedef struct {
+	// fields matching struct CommonEvent from tracer.go
+	common_event_t common;
+
+	// fields matching the struct for dup2 from event-structs-generated.go
+	u64 oldfd;
+	u64 newfd;
+} dup2_event_t;
+
+typedef struct {
+	// fields matching struct CommonEvent from tracer.go
+	common_event_t common;
+
+	// fields matching the struct for dup3 from event-structs-generated.go
+	u64 oldfd;
+	u64 newfd;
+	s64 flags;
+} dup3_event_t;
+
 typedef struct {
 	// fields matching struct CommonEvent from tracer.go
 	common_event_t common;
@@ -73,6 +100,16 @@ typedef struct {
 	s64 flag;
 } fchownat_event_t;
 
+typedef struct {
+	// fields matching struct CommonEvent from tracer.go
+	common_event_t common;
+
+	// fields matching the struct for fcntl from event-structs-generated.go
+	u64 fd;
+	u64 cmd;
+	u64 arg;
+} fcntl_event_t;
+
 typedef struct {
 	// fields matching struct CommonEvent from tracer.go
 	common_event_t common;
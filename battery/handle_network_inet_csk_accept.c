@@ -85,13 +85,13 @@ int kprobe__handle_inet_csk_accept(struct pt_regs *ctx)
 	net_ns_inum = 0;
 #endif
 
-	u64 *program_id = bpf_map_lookup_elem(&program_id_per_pid, &pid);
+	u64 program_id = lookup_program_id(pid >> 32);
 
 	if (check_family(skp, AF_INET)) {
 		tcp_v4_event_t ev = {
 			.common = {
 				.timestamp = bpf_ktime_get_ns(),
-				.program_id = program_id ? *program_id : 0,
+				.program_id = program_id,
 				.tgid = pid >> 32,
 				.ret = 0,
 				.name = "accept_v4",
@@ -113,7 +113,7 @@ int kprobe__handle_inet_csk_accept(struct pt_regs *ctx)
 		tcp_v6_event_t ev = {
 			.common = {
 				.timestamp = bpf_ktime_get_ns(),
-				.program_id = program_id ? *program_id : 0,
+				.program_id = program_id,
 				.tgid = pid >> 32,
 				.ret = 0,
 				.name = "accept_v6",
@@ -72,7 +72,7 @@ int kprobe__handle_tcp_close(struct pt_regs *ctx)
 		return 0;
 	}
 
-	u64 *program_id = bpf_map_lookup_elem(&program_id_per_pid, &pid);
+	u64 program_id = lookup_program_id(pid >> 32);
 
 	if (check_family(skp, AF_INET)) {
 		tuple_v4_t tup = { };
@@ -84,7 +84,7 @@ int kprobe__handle_tcp_close(struct pt_regs *ctx)
 		tcp_v4_event_t ev = {
 			.common = {
 				.timestamp = bpf_ktime_get_ns(),
-				.program_id = program_id ? *program_id : 0,
+				.program_id = program_id,
 				.tgid = pid >> 32,
 				.ret = 0,
 				.name = "close_v4",
@@ -110,7 +110,7 @@ int kprobe__handle_tcp_close(struct pt_regs *ctx)
 		tcp_v6_event_t ev = {
 			.common = {
 				.timestamp = bpf_ktime_get_ns(),
-				.program_id = program_id ? *program_id : 0,
+				.program_id = program_id,
 				.tgid = pid >> 32,
 				.ret = 0,
 				.name = "close_v6",
@@ -15,44 +15,51 @@
 
 #include "../bpf/events-map.h"
 #include "../bpf/program-id-map.h"
+#include "../bpf/syscall-args.h"
 
 /* This is a key/value store with the keys being pid_tgid and values being
- * struct pt_regs.
+ * struct syscall_entry: the arguments and the entry time of the syscall.
  *
  * It is used to keep context between kprobe/handle_{{ .Name }} and
- * kretprobe/handle_{{ .Name }}.
+ * kretprobe/handle_{{ .Name }} (or their tracepoint counterparts).
  * */
 struct bpf_map_def SEC("maps/{{ .Name }}_args") {{ .Name }}args =
 {
 	.type = BPF_MAP_TYPE_HASH,
 	.key_size = sizeof(__u64),
-	.value_size = sizeof(struct pt_regs),
+	.value_size = sizeof(struct syscall_entry),
 	.max_entries = 1024,
 };
 
-SEC("kretprobe/handle_{{ .Name }}")
-int kretprobe__handle_{{ .Name }}(struct pt_regs *ctx)
+/* Sends the event of the syscall returning ret_value, ctx is the context
+ * of the calling program.
+ */
+__attribute__((always_inline))
+static inline int emit_{{ .Name }}(void *ctx, s64 ret_value)
 {
+	struct syscall_entry *entry;
 	struct pt_regs *args;
 	u64 pid = bpf_get_current_pid_tgid();
 	u32 cpu = bpf_get_smp_processor_id();
-	u64 *program_id = bpf_map_lookup_elem(&program_id_per_pid, &pid);
+	u64 program_id = lookup_program_id(pid >> 32);
+	u64 now = bpf_ktime_get_ns();
 
-	args = bpf_map_lookup_elem(&{{ .Name }}args, &pid);
-	if (args == NULL) {
+	entry = bpf_map_lookup_elem(&{{ .Name }}args, &pid);
+	if (entry == NULL) {
 		return 0;
 	}
-	bpf_map_delete_elem(&{{ .Name }}args, &pid);
+	args = &entry->args;
 
 	{{ .Name }}_event_t evt = {
 		.common = {
-			.timestamp = bpf_ktime_get_ns(),
-			.program_id = program_id ? *program_id : 0,
+			.timestamp = now,
+			.program_id = program_id,
 			.name = "{{ .Name }}",
 			.tgid = pid >> 32,
-			.ret = PT_REGS_RC(ctx),
+			.ret = ret_value,
 			.hash = fnv64a_init(),
 			.flags = 0,
+			.duration = now - entry->start,
 		},
 	};
 
@@ -74,6 +81,9 @@ int kretprobe__handle_{{ .Name }}(struct pt_regs *ctx)
 		{{- end }}
 	{{- end }}
 
+	/* the entry is only needed until the arguments are copied */
+	bpf_map_delete_elem(&{{ .Name }}args, &pid);
+
 	{{range $index, $element := .Args }}
 	{{- if eq $element.HashFunc "skip" -}}
 	/* skip hash of evt.{{ $element.Name }} */
@@ -86,15 +96,42 @@ int kretprobe__handle_{{ .Name }}(struct pt_regs *ctx)
 
 	bpf_perf_event_output(ctx, &events, cpu, &evt, sizeof(evt));
 	return 0;
+}
+
+SEC("kretprobe/handle_{{ .Name }}")
+int kretprobe__handle_{{ .Name }}(struct pt_regs *ctx)
+{
+	return emit_{{ .Name }}(ctx, PT_REGS_RC(ctx));
 };
 
 SEC("kprobe/handle_{{ .Name }}")
 int kprobe__handle_{{ .Name }}(struct pt_regs *ctx)
 {
 	u64 pid = bpf_get_current_pid_tgid();
-	struct pt_regs args = { };
-	bpf_probe_read(&args, sizeof(args), ctx);
-	bpf_map_update_elem(&{{ .Name }}args, &pid, &args, BPF_ANY);
+	struct syscall_entry entry = { };
+	read_syscall_args(ctx, &entry.args);
+	entry.start = bpf_ktime_get_ns();
+	bpf_map_update_elem(&{{ .Name }}args, &pid, &entry, BPF_ANY);
+	return 0;
+}
+
+/* Tracepoint backend: these are tail called by the syscalls:sys_enter_{{ .Name }}
+ * and syscalls:sys_exit_{{ .Name }} tracepoint programs of trace_events.c.
+ */
+SEC("tracepoint/handle_{{ .Name }}_ret")
+int tracepoint__handle_{{ .Name }}_ret(struct syscall_exit_args *ctx)
+{
+	return emit_{{ .Name }}(ctx, ctx->ret);
+}
+
+SEC("tracepoint/handle_{{ .Name }}")
+int tracepoint__handle_{{ .Name }}(struct syscall_enter_args *ctx)
+{
+	u64 pid = bpf_get_current_pid_tgid();
+	struct syscall_entry entry = { };
+	read_tracepoint_syscall_args(ctx, &entry.args);
+	entry.start = bpf_ktime_get_ns();
+	bpf_map_update_elem(&{{ .Name }}args, &pid, &entry, BPF_ANY);
 	return 0;
 }
 
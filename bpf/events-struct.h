@@ -14,6 +14,9 @@ typedef struct {
 	char     name[64];
 	uint64_t hash;
 	uint64_t flags;
+	/* time spent in the syscall in nanoseconds, 0 for the events not
+	 * emitted by syscall handlers */
+	uint64_t duration;
 } common_event_t;
 
 #endif
@@ -4,6 +4,11 @@
 
 #pragma once
 
+#include <linux/version.h>
+#include <linux/sched.h>
+#include <linux/cgroup-defs.h>
+#include <linux/kernfs.h>
+
 #include "bpf_helpers.h"
 
 #ifndef PIN_GLOBAL_NS
@@ -22,3 +27,94 @@ struct bpf_map_def SEC("maps/program_id_per_pid") program_id_per_pid = {
 	.pinning = PIN_GLOBAL_NS,
 	.namespace = "traceleft",
 };
+
+/* Each cgroup with registered handlers has an opaque program_id, used for
+ * processes without a program_id of their own. The key is the cgroup id (see
+ * kernfs_node_id()). It is populated by userspace.
+ * */
+struct bpf_map_def SEC("maps/program_id_per_cgroup") program_id_per_cgroup = {
+	.type = BPF_MAP_TYPE_HASH,
+	.key_size = sizeof(__u64),
+	.value_size = sizeof(__u64),
+	.max_entries = 256,
+	.map_flags = 0,
+	.pinning = PIN_GLOBAL_NS,
+	.namespace = "traceleft",
+};
+
+/* Maximum depth of the cgroup hierarchy walked up by
+ * current_traced_cgroup_id(): loops must be bounded. Container cgroups are
+ * typically 3 to 5 levels deep (e.g.
+ * /kubepods.slice/kubepods-burstable.slice/<pod>.slice/<container>.scope).
+ */
+#define MAX_CGROUP_DEPTH 16
+
+/* Returns the id of a cgroup (in the unified hierarchy) given its kernfs
+ * node. It matches the file handle returned by name_to_handle_at() on the
+ * cgroup directory, or its inode number on kernels without file handle
+ * support for cgroups.
+ */
+__attribute__((always_inline))
+static inline u64 kernfs_node_id(struct kernfs_node *kn)
+{
+	u64 id = 0;
+
+#if LINUX_VERSION_CODE >= KERNEL_VERSION(5, 5, 0)
+	bpf_probe_read(&id, sizeof(id), &kn->id);
+#elif LINUX_VERSION_CODE >= KERNEL_VERSION(4, 14, 0)
+	bpf_probe_read(&id, sizeof(id), &kn->id.id);
+#else
+	bpf_probe_read(&id, sizeof(u32), &kn->ino);
+#endif
+
+	return id;
+}
+
+/* Returns the id of the closest cgroup with registered handlers among the
+ * cgroup of the current task (in the unified hierarchy) and its ancestors,
+ * or 0 if there is none, so that processes in the descendants of a traced
+ * cgroup are traced too.
+ */
+__attribute__((always_inline))
+static inline u64 current_traced_cgroup_id(void)
+{
+	struct task_struct *task = (struct task_struct *) bpf_get_current_task();
+	struct css_set *cgroups = NULL;
+	struct cgroup *cgrp = NULL;
+	struct kernfs_node *kn = NULL;
+
+	bpf_probe_read(&cgroups, sizeof(cgroups), &task->cgroups);
+	bpf_probe_read(&cgrp, sizeof(cgrp), &cgroups->dfl_cgrp);
+	bpf_probe_read(&kn, sizeof(kn), &cgrp->kn);
+
+#pragma unroll
+	for (int i = 0; i < MAX_CGROUP_DEPTH; i++) {
+		if (kn == NULL) {
+			break;
+		}
+		u64 id = kernfs_node_id(kn);
+		if (bpf_map_lookup_elem(&program_id_per_cgroup, &id) != NULL) {
+			return id;
+		}
+		bpf_probe_read(&kn, sizeof(kn), &kn->parent);
+	}
+
+	return 0;
+}
+
+/* Returns the program_id of the current process, falling back to the one of
+ * its cgroup. Must be called in the context of the process.
+ */
+__attribute__((always_inline))
+static inline u64 lookup_program_id(u32 tgid)
+{
+	u64 *program_id = bpf_map_lookup_elem(&program_id_per_pid, &tgid);
+	if (program_id != NULL) {
+		return *program_id;
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	program_id = bpf_map_lookup_elem(&program_id_per_cgroup, &cgroup_id);
+
+	return program_id ? *program_id : 0;
+}
@@ -0,0 +1,87 @@
+/* Map globally pinned used by both the main BPF module and the handlers.
+ * To use the map in a BPF program, just include this file.
+ */
+
+#pragma once
+
+#include <uapi/linux/ptrace.h>
+
+#include "bpf_helpers.h"
+
+#ifndef PIN_GLOBAL_NS
+#define PIN_GLOBAL_NS 2
+#endif
+
+/* Single entry (key 0) set to 1 by userspace when the syscalls are probed
+ * through their pt_regs wrappers (__x64_sys_* and the like, kernels >= 4.17
+ * with CONFIG_ARCH_HAS_SYSCALL_WRAPPER). In that case, the only argument of
+ * the probed function is a pointer to the registers of the syscall.
+ * */
+struct bpf_map_def SEC("maps/syscall_wrapper") syscall_wrapper = {
+	.type = BPF_MAP_TYPE_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = 1,
+	.map_flags = 0,
+	.pinning = PIN_GLOBAL_NS,
+	.namespace = "traceleft",
+};
+
+/* What the handlers keep between the entry and the exit of a syscall */
+struct syscall_entry {
+	struct pt_regs args;
+	/* bpf_ktime_get_ns() at the entry */
+	u64 start;
+};
+
+/* Copies the registers holding the arguments of the syscall probed by ctx in
+ * args, whatever the calling convention of the probed function.
+ */
+__attribute__((always_inline))
+static inline int read_syscall_args(struct pt_regs *ctx, struct pt_regs *args)
+{
+	u32 key = 0;
+	u32 *wrapped = bpf_map_lookup_elem(&syscall_wrapper, &key);
+
+	if (wrapped != NULL && *wrapped) {
+		int ret = bpf_probe_read(args, sizeof(*args), (void *) PT_REGS_PARM1(ctx));
+#if defined(__x86_64__)
+		/* the 4th argument of a syscall is passed in r10, not rcx */
+		args->cx = args->r10;
+#endif
+		return ret;
+	}
+
+	return bpf_probe_read(args, sizeof(*args), ctx);
+}
+
+/* Context of the syscalls:sys_enter_* tracepoints, see
+ * /sys/kernel/debug/tracing/events/syscalls/sys_enter_NAME/format. Arguments
+ * are stored as 8-byte fields whatever their type.
+ */
+struct syscall_enter_args {
+	unsigned long long common;
+	int syscall_nr;
+	unsigned long args[6];
+};
+
+/* Context of the syscalls:sys_exit_* tracepoints */
+struct syscall_exit_args {
+	unsigned long long common;
+	int syscall_nr;
+	long ret;
+};
+
+/* Copies the arguments of the syscall traced by ctx in args, at the place
+ * PT_REGS_PARM*() read them from, so that handlers behave the same with the
+ * kprobe and the tracepoint backends.
+ */
+__attribute__((always_inline))
+static inline void read_tracepoint_syscall_args(struct syscall_enter_args *ctx, struct pt_regs *args)
+{
+	PT_REGS_PARM1(args) = ctx->args[0];
+	PT_REGS_PARM2(args) = ctx->args[1];
+	PT_REGS_PARM3(args) = ctx->args[2];
+	PT_REGS_PARM4(args) = ctx->args[3];
+	PT_REGS_PARM5(args) = ctx->args[4];
+}
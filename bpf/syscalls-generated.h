@@ -0,0 +1,903 @@
+
+// Generated file, do not edit.
+// Source: metagenerator.go
+
+
+// Dispatch of the syscalls to their handlers, included by
+// bpf/trace_events.c. See dispatch() there.
+
+struct bpf_map_def SEC("maps/handle_chmod_progs") handle_chmod_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_chmod_progs_ret") handle_chmod_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_chmod_tp_progs") handle_chmod_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_chmod_tp_progs_ret") handle_chmod_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_chmod")
+int kprobe__sys_chmod(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_chmod_progs);
+}
+
+SEC("kretprobe/SyS_chmod")
+int kretprobe__sys_chmod(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_chmod_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_chmod")
+int tracepoint__sys_enter_chmod(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_chmod_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_chmod")
+int tracepoint__sys_exit_chmod(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_chmod_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_chown_progs") handle_chown_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_chown_progs_ret") handle_chown_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_chown_tp_progs") handle_chown_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_chown_tp_progs_ret") handle_chown_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_chown")
+int kprobe__sys_chown(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_chown_progs);
+}
+
+SEC("kretprobe/SyS_chown")
+int kretprobe__sys_chown(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_chown_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_chown")
+int tracepoint__sys_enter_chown(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_chown_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_chown")
+int tracepoint__sys_exit_chown(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_chown_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_close_progs") handle_close_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_close_progs_ret") handle_close_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_close_tp_progs") handle_close_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_close_tp_progs_ret") handle_close_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_close")
+int kprobe__sys_close(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_close_progs);
+}
+
+SEC("kretprobe/SyS_close")
+int kretprobe__sys_close(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_close_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_close")
+int tracepoint__sys_enter_close(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_close_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_close")
+int tracepoint__sys_exit_close(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_close_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_dup_progs") handle_dup_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_dup_progs_ret") handle_dup_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_dup_tp_progs") handle_dup_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_dup_tp_progs_ret") handle_dup_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_dup")
+int kprobe__sys_dup(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_dup_progs);
+}
+
+SEC("kretprobe/SyS_dup")
+int kretprobe__sys_dup(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_dup_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_dup")
+int tracepoint__sys_enter_dup(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_dup_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_dup")
+int tracepoint__sys_exit_dup(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_dup_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_dup2_progs") handle_dup2_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_dup2_progs_ret") handle_dup2_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_dup2_tp_progs") handle_dup2_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_dup2_tp_progs_ret") handle_dup2_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_dup2")
+int kprobe__sys_dup2(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_dup2_progs);
+}
+
+SEC("kretprobe/SyS_dup2")
+int kretprobe__sys_dup2(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_dup2_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_dup2")
+int tracepoint__sys_enter_dup2(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_dup2_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_dup2")
+int tracepoint__sys_exit_dup2(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_dup2_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_dup3_progs") handle_dup3_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_dup3_progs_ret") handle_dup3_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_dup3_tp_progs") handle_dup3_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_dup3_tp_progs_ret") handle_dup3_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_dup3")
+int kprobe__sys_dup3(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_dup3_progs);
+}
+
+SEC("kretprobe/SyS_dup3")
+int kretprobe__sys_dup3(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_dup3_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_dup3")
+int tracepoint__sys_enter_dup3(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_dup3_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_dup3")
+int tracepoint__sys_exit_dup3(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_dup3_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_fchmod_progs") handle_fchmod_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchmod_progs_ret") handle_fchmod_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchmod_tp_progs") handle_fchmod_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchmod_tp_progs_ret") handle_fchmod_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_fchmod")
+int kprobe__sys_fchmod(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_fchmod_progs);
+}
+
+SEC("kretprobe/SyS_fchmod")
+int kretprobe__sys_fchmod(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_fchmod_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_fchmod")
+int tracepoint__sys_enter_fchmod(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_fchmod_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_fchmod")
+int tracepoint__sys_exit_fchmod(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_fchmod_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_fchmodat_progs") handle_fchmodat_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchmodat_progs_ret") handle_fchmodat_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchmodat_tp_progs") handle_fchmodat_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchmodat_tp_progs_ret") handle_fchmodat_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_fchmodat")
+int kprobe__sys_fchmodat(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_fchmodat_progs);
+}
+
+SEC("kretprobe/SyS_fchmodat")
+int kretprobe__sys_fchmodat(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_fchmodat_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_fchmodat")
+int tracepoint__sys_enter_fchmodat(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_fchmodat_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_fchmodat")
+int tracepoint__sys_exit_fchmodat(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_fchmodat_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_fchown_progs") handle_fchown_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchown_progs_ret") handle_fchown_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchown_tp_progs") handle_fchown_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchown_tp_progs_ret") handle_fchown_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_fchown")
+int kprobe__sys_fchown(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_fchown_progs);
+}
+
+SEC("kretprobe/SyS_fchown")
+int kretprobe__sys_fchown(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_fchown_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_fchown")
+int tracepoint__sys_enter_fchown(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_fchown_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_fchown")
+int tracepoint__sys_exit_fchown(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_fchown_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_fchownat_progs") handle_fchownat_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchownat_progs_ret") handle_fchownat_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchownat_tp_progs") handle_fchownat_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fchownat_tp_progs_ret") handle_fchownat_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_fchownat")
+int kprobe__sys_fchownat(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_fchownat_progs);
+}
+
+SEC("kretprobe/SyS_fchownat")
+int kretprobe__sys_fchownat(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_fchownat_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_fchownat")
+int tracepoint__sys_enter_fchownat(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_fchownat_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_fchownat")
+int tracepoint__sys_exit_fchownat(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_fchownat_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_fcntl_progs") handle_fcntl_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fcntl_progs_ret") handle_fcntl_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fcntl_tp_progs") handle_fcntl_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_fcntl_tp_progs_ret") handle_fcntl_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_fcntl")
+int kprobe__sys_fcntl(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_fcntl_progs);
+}
+
+SEC("kretprobe/SyS_fcntl")
+int kretprobe__sys_fcntl(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_fcntl_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_fcntl")
+int tracepoint__sys_enter_fcntl(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_fcntl_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_fcntl")
+int tracepoint__sys_exit_fcntl(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_fcntl_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_mkdir_progs") handle_mkdir_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_mkdir_progs_ret") handle_mkdir_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_mkdir_tp_progs") handle_mkdir_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_mkdir_tp_progs_ret") handle_mkdir_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_mkdir")
+int kprobe__sys_mkdir(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_mkdir_progs);
+}
+
+SEC("kretprobe/SyS_mkdir")
+int kretprobe__sys_mkdir(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_mkdir_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_mkdir")
+int tracepoint__sys_enter_mkdir(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_mkdir_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_mkdir")
+int tracepoint__sys_exit_mkdir(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_mkdir_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_mkdirat_progs") handle_mkdirat_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_mkdirat_progs_ret") handle_mkdirat_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_mkdirat_tp_progs") handle_mkdirat_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_mkdirat_tp_progs_ret") handle_mkdirat_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_mkdirat")
+int kprobe__sys_mkdirat(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_mkdirat_progs);
+}
+
+SEC("kretprobe/SyS_mkdirat")
+int kretprobe__sys_mkdirat(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_mkdirat_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_mkdirat")
+int tracepoint__sys_enter_mkdirat(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_mkdirat_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_mkdirat")
+int tracepoint__sys_exit_mkdirat(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_mkdirat_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_open_progs") handle_open_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_open_progs_ret") handle_open_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_open_tp_progs") handle_open_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_open_tp_progs_ret") handle_open_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_open")
+int kprobe__sys_open(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_open_progs);
+}
+
+SEC("kretprobe/SyS_open")
+int kretprobe__sys_open(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_open_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_open")
+int tracepoint__sys_enter_open(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_open_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_open")
+int tracepoint__sys_exit_open(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_open_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_read_progs") handle_read_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_read_progs_ret") handle_read_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_read_tp_progs") handle_read_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_read_tp_progs_ret") handle_read_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_read")
+int kprobe__sys_read(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_read_progs);
+}
+
+SEC("kretprobe/SyS_read")
+int kretprobe__sys_read(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_read_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_read")
+int tracepoint__sys_enter_read(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_read_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_read")
+int tracepoint__sys_exit_read(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_read_tp_progs_ret);
+}
+
+struct bpf_map_def SEC("maps/handle_write_progs") handle_write_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_write_progs_ret") handle_write_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_write_tp_progs") handle_write_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_write_tp_progs_ret") handle_write_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_write")
+int kprobe__sys_write(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_write_progs);
+}
+
+SEC("kretprobe/SyS_write")
+int kretprobe__sys_write(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_write_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_write")
+int tracepoint__sys_enter_write(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_write_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_write")
+int tracepoint__sys_exit_write(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_write_tp_progs_ret);
+}
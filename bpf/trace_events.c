@@ -4,12 +4,26 @@
 #pragma clang diagnostic push
 #pragma clang diagnostic ignored "-Waddress-of-packed-member"
 #include <linux/fs.h>
+#include <linux/version.h>
+#include <linux/sched.h>
+#if LINUX_VERSION_CODE >= KERNEL_VERSION(4, 11, 0)
+#include <linux/sched/signal.h>
+#endif
 #include <uapi/linux/bpf.h>
 #pragma clang diagnostic pop
 #include "bpf_helpers.h"
 
 #include "events-map.h"
 #include "program-id-map.h"
+#include "syscall-args.h"
+
+/* Prog arrays are indexed by TGID for handlers registered for a process,
+ * followed by slots allocated by userspace for handlers registered for a
+ * cgroup. This must match probe.go.
+ */
+#define MAX_PIDS 32768
+#define MAX_CGROUP_SLOTS 256
+#define PROG_ARRAY_ENTRIES (MAX_PIDS + MAX_CGROUP_SLOTS)
 
 /* This is a set of PIDs (technically TGIDs) to ignore when tracking. Values
  * are ignored. It is populated by userspace. */
@@ -21,207 +35,13 @@ struct bpf_map_def SEC("maps/untracked_pids") untracked_pids = {
 	.map_flags = 0,
 };
 
-/* Syscalls */
-
-struct bpf_map_def SEC("maps/handle_open_progs") handle_open_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_open_progs_ret") handle_open_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_close_progs") handle_close_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_close_progs_ret") handle_close_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_read_progs") handle_read_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_read_progs_ret") handle_read_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_write_progs") handle_write_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_write_progs_ret") handle_write_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_mkdir_progs") handle_mkdir_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_mkdir_progs_ret") handle_mkdir_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_mkdirat_progs") handle_mkdirat_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_mkdirat_progs_ret") handle_mkdirat_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_fchown_progs") handle_fchown_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_fchown_progs_ret") handle_fchown_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_fchownat_progs") handle_fchownat_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_fchownat_progs_ret") handle_fchownat_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_fchmod_progs") handle_fchmod_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_fchmod_progs_ret") handle_fchmod_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_fchmodat_progs") handle_fchmodat_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_fchmodat_progs_ret") handle_fchmodat_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_chmod_progs") handle_chmod_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_chmod_progs_ret") handle_chmod_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_chown_progs") handle_chown_progs = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-struct bpf_map_def SEC("maps/handle_chown_progs_ret") handle_chown_progs_ret = {
-	.type = BPF_MAP_TYPE_PROG_ARRAY,
-	.key_size = sizeof(__u32),
-	.value_size = sizeof(__u32),
-	.max_entries = 32768,
-	.map_flags = 0,
-};
-
-/* This is a key/value store with the keys being pid_tgid and values being
- * fd_install_t.
- *
- * It is populated by userspace and read by the eBPF program to know which pids
- * to watch.
+/* This is a key/value store with the keys being the TGIDs of followed
+ * processes and the values being the TGID whose handlers they use. It is
+ * populated by userspace for processes with registered handlers when following
+ * children is enabled, and by kprobe/wake_up_new_task for their children so
+ * they are traced before userspace registers handlers for them.
  * */
-struct bpf_map_def SEC("maps/file_events_pids_to_watch") file_events_pids_to_watch = {
+struct bpf_map_def SEC("maps/follow_pids") follow_pids = {
 	.type = BPF_MAP_TYPE_HASH,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
@@ -229,327 +49,25 @@ struct bpf_map_def SEC("maps/file_events_pids_to_watch") file_events_pids_to_wat
 	.map_flags = 0,
 };
 
-typedef struct {
-	unsigned long fd;
-	struct file *file;
-} fd_install_t;
-
-/* This is a key/value store with the keys being pid_tgid and values being
- * fd_install_t.
- *
- * It is used to keep context between kprobe/fd_install and
- * kretprobe/fd_install.
- * */
-struct bpf_map_def SEC("maps/fdinstall_args") fdinstall_args = {
-	.type = BPF_MAP_TYPE_HASH,
-	.key_size = sizeof(__u64),
-	.value_size = sizeof(fd_install_t),
-	.max_entries = 1024,
-};
-
-typedef struct {
-	common_event_t common;
-	u64 fd;
-	u64 ino;
-	u64 major;
-	u64 minor;
-} file_event_t;
-
-SEC("kprobe/fd_install")
-int kprobe__fd_install(struct pt_regs *ctx)
-{
-	u64 pid = bpf_get_current_pid_tgid();
-	u32 tgid = pid >> 32;
-	u32 *exists = NULL;
-	unsigned long fd = (unsigned long) PT_REGS_PARM1(ctx);
-	struct file *f = (struct file *) PT_REGS_PARM2(ctx);
-
-	fd_install_t fd_i = {
-		.fd = fd,
-		.file = f,
-	};
-
-	exists = bpf_map_lookup_elem(&file_events_pids_to_watch, &tgid);
-	if (exists == NULL || !*exists) {
-		return 0;
-	}
-
-	bpf_map_update_elem(&fdinstall_args, &pid, &fd_i, BPF_ANY);
-
-	return 0;
-}
-
-SEC("kretprobe/fd_install")
-int kretprobe__fd_install(struct pt_regs *ctx)
-{
-	u64 pid = bpf_get_current_pid_tgid();
-	u32 cpu = bpf_get_smp_processor_id();
-	fd_install_t *fd_ip;
-	fd_ip = bpf_map_lookup_elem(&fdinstall_args, &pid);
-	if (fd_ip == NULL) {
-		return 0; // missed entry
-	}
-	bpf_map_delete_elem(&fdinstall_args, &pid);
-
-	fd_install_t fd_i = {};
-	bpf_probe_read(&fd_i, sizeof(fd_i), fd_ip);
-
-	struct inode *f_inode = NULL;
-	unsigned long i_ino = 0;
-	struct super_block *sb = NULL;
-	dev_t s_dev = 0;
-
-	bpf_probe_read(&f_inode, sizeof(f_inode), &fd_i.file->f_inode);
-	bpf_probe_read(&i_ino, sizeof(i_ino), &f_inode->i_ino);
-	bpf_probe_read(&sb, sizeof(sb), &f_inode->i_sb);
-	bpf_probe_read(&s_dev, sizeof(s_dev), &sb->s_dev);
-
-	u64 *program_id = bpf_map_lookup_elem(&program_id_per_pid, &pid);
-
-	file_event_t ev = {
-		.common = {
-			.timestamp = bpf_ktime_get_ns(),
-			.program_id = program_id ? *program_id : 0,
-			.tgid = pid >> 32,
-			.ret = 0,
-			.name = "fd_install",
-			.hash = 0,
-			.flags = 0,
-		},
-		.fd = fd_i.fd,
-		.ino = i_ino,
-		.major = MAJOR(s_dev),
-		.minor = MINOR(s_dev),
-	};
-
-	bpf_perf_event_output(ctx, &events, cpu, &ev, sizeof(ev));
-
-	return 0;
-}
-
-SEC("kprobe/SyS_read")
-int kprobe__sys_read(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_read_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_read_progs, 0);
-
-	return 0;
-}
-
-SEC("kretprobe/SyS_read")
-int kretprobe__sys_read(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_read_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_read_progs_ret, 0);
-
-	return 0;
-}
-
-SEC("kprobe/SyS_write")
-int kprobe__sys_write(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_write_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_write_progs, 0);
-
-	return 0;
-}
-
-SEC("kretprobe/SyS_write")
-int kretprobe__handle_write(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_write_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_write_progs_ret, 0);
-
-	return 0;
-}
-
-SEC("kprobe/SyS_open")
-int kprobe__handle_open(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_open_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_open_progs, 0);
-
-	return 0;
-}
-
-SEC("kretprobe/SyS_open")
-int kretprobe__handle_open(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_open_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_open_progs_ret, 0);
-
-	return 0;
-}
-
-SEC("kprobe/SyS_close")
-int kprobe__handle_close(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_close_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_close_progs, 0);
-
-	return 0;
-}
-
-SEC("kretprobe/SyS_close")
-int kretprobe__handle_close(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_close_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_close_progs_ret, 0);
-
-	return 0;
-}
-
-SEC("kprobe/SyS_mkdir")
-int kprobe__handle_mkdir(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_mkdir_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_mkdir_progs, 0);
-
-	return 0;
-}
-
-SEC("kretprobe/SyS_mkdir")
-int kretprobe__handle_mkdir(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_mkdir_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_mkdir_progs_ret, 0);
-
-	return 0;
-}
-
-SEC("kprobe/SyS_mkdirat")
-int kprobe__handle_mkdirat(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_mkdirat_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_mkdirat_progs, 0);
-
-	return 0;
-}
-
-SEC("kretprobe/SyS_mkdirat")
-int kretprobe__handle_mkdirat(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_mkdirat_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_mkdirat_progs_ret, 0);
-
-	return 0;
-}
-
-SEC("kprobe/SyS_chown")
-int kprobe__handle_chown(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
-
-	bpf_tail_call(ctx, (void *)&handle_chown_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_chown_progs, 0);
-
-	return 0;
-}
+/* This is a key/value store with the keys being cgroup ids (see
+ * kernfs_node_id()) and the values being their slot in the prog arrays.
+ * It is populated by userspace, along with program_id_per_cgroup.
+ * */
+struct bpf_map_def SEC("maps/cgroup_slots") cgroup_slots = {
+	.type = BPF_MAP_TYPE_HASH,
+	.key_size = sizeof(__u64),
+	.value_size = sizeof(__u32),
+	.max_entries = MAX_CGROUP_SLOTS,
+	.map_flags = 0,
+};
 
-SEC("kretprobe/SyS_chown")
-int kretprobe__handle_chwon(struct pt_regs *ctx)
+/* Tail calls the handler of the current process in progs: the one
+ * registered for its TGID, or for the followed process it was forked from, or
+ * for its cgroup or closest traced ancestor cgroup, or the default handler.
+ * Returns 0 if there is none.
+ */
+__attribute__((always_inline))
+static inline int dispatch(void *ctx, void *progs)
 {
 	u64 pid_tgid = bpf_get_current_pid_tgid();
 	u32 tgid = pid_tgid>>32;
@@ -559,189 +77,274 @@ int kretprobe__handle_chwon(struct pt_regs *ctx)
 		return 0;
 	}
 
-	bpf_tail_call(ctx, (void *)&handle_chown_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_chown_progs_ret, 0);
-
-	return 0;
-}
+	bpf_tail_call(ctx, progs, tgid);
 
-SEC("kprobe/SyS_fchown")
-int kprobe__handle_fchown(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, progs, *followed);
+	}
 
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, progs, *slot);
 	}
 
-	bpf_tail_call(ctx, (void *)&handle_fchown_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_fchown_progs, 0);
+	bpf_tail_call(ctx, progs, 0);
 
 	return 0;
 }
 
-SEC("kretprobe/SyS_fchown")
-int kretprobe__handle_fchwon(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
+/* This is a key/value store with the keys being pid_tgid and values being
+ * fd_install_t.
+ *
+ * It is populated by userspace and read by the eBPF program to know which pids
+ * to watch.
+ * */
+struct bpf_map_def SEC("maps/file_events_pids_to_watch") file_events_pids_to_watch = {
+	.type = BPF_MAP_TYPE_HASH,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = 32768,
+	.map_flags = 0,
+};
 
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
+typedef struct {
+	unsigned long fd;
+	struct file *file;
+} fd_install_t;
 
-	bpf_tail_call(ctx, (void *)&handle_fchown_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_fchown_progs_ret, 0);
+/* This is a key/value store with the keys being pid_tgid and values being
+ * fd_install_t.
+ *
+ * It is used to keep context between kprobe/fd_install and
+ * kretprobe/fd_install.
+ * */
+struct bpf_map_def SEC("maps/fdinstall_args") fdinstall_args = {
+	.type = BPF_MAP_TYPE_HASH,
+	.key_size = sizeof(__u64),
+	.value_size = sizeof(fd_install_t),
+	.max_entries = 1024,
+};
 
-	return 0;
-}
+typedef struct {
+	common_event_t common;
+	u64 fd;
+	u64 ino;
+	u64 major;
+	u64 minor;
+} file_event_t;
 
-SEC("kprobe/SyS_fchownat")
-int kprobe__handle_fchownat(struct pt_regs *ctx)
+SEC("kprobe/fd_install")
+int kprobe__fd_install(struct pt_regs *ctx)
 {
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
+	u64 pid = bpf_get_current_pid_tgid();
+	u32 tgid = pid >> 32;
+	u32 *exists = NULL;
+	unsigned long fd = (unsigned long) PT_REGS_PARM1(ctx);
+	struct file *f = (struct file *) PT_REGS_PARM2(ctx);
 
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
+	fd_install_t fd_i = {
+		.fd = fd,
+		.file = f,
+	};
+
+	exists = bpf_map_lookup_elem(&file_events_pids_to_watch, &tgid);
+	if (exists == NULL || !*exists) {
+		// processes in a traced cgroup are watched too
+		u64 cgroup_id = current_traced_cgroup_id();
+		if (bpf_map_lookup_elem(&cgroup_slots, &cgroup_id) == NULL) {
+			return 0;
+		}
 	}
 
-	bpf_tail_call(ctx, (void *)&handle_fchownat_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_fchownat_progs, 0);
+	bpf_map_update_elem(&fdinstall_args, &pid, &fd_i, BPF_ANY);
 
 	return 0;
 }
 
-SEC("kretprobe/SyS_fchownat")
-int kretprobe__handle_fchownat(struct pt_regs *ctx)
+SEC("kretprobe/fd_install")
+int kretprobe__fd_install(struct pt_regs *ctx)
 {
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
+	u64 pid = bpf_get_current_pid_tgid();
+	u32 cpu = bpf_get_smp_processor_id();
+	fd_install_t *fd_ip;
+	fd_ip = bpf_map_lookup_elem(&fdinstall_args, &pid);
+	if (fd_ip == NULL) {
+		return 0; // missed entry
 	}
+	bpf_map_delete_elem(&fdinstall_args, &pid);
 
-	bpf_tail_call(ctx, (void *)&handle_fchownat_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_fchownat_progs_ret, 0);
+	fd_install_t fd_i = {};
+	bpf_probe_read(&fd_i, sizeof(fd_i), fd_ip);
 
-	return 0;
-}
+	struct inode *f_inode = NULL;
+	unsigned long i_ino = 0;
+	struct super_block *sb = NULL;
+	dev_t s_dev = 0;
 
-SEC("kprobe/SyS_chmod")
-int kprobe__handle_chmod(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
+	bpf_probe_read(&f_inode, sizeof(f_inode), &fd_i.file->f_inode);
+	bpf_probe_read(&i_ino, sizeof(i_ino), &f_inode->i_ino);
+	bpf_probe_read(&sb, sizeof(sb), &f_inode->i_sb);
+	bpf_probe_read(&s_dev, sizeof(s_dev), &sb->s_dev);
 
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
-	}
+	u64 program_id = lookup_program_id(pid >> 32);
+
+	file_event_t ev = {
+		.common = {
+			.timestamp = bpf_ktime_get_ns(),
+			.program_id = program_id,
+			.tgid = pid >> 32,
+			.ret = 0,
+			.name = "fd_install",
+			.hash = 0,
+			.flags = 0,
+		},
+		.fd = fd_i.fd,
+		.ino = i_ino,
+		.major = MAJOR(s_dev),
+		.minor = MINOR(s_dev),
+	};
 
-	bpf_tail_call(ctx, (void *)&handle_chmod_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_chmod_progs, 0);
+	bpf_perf_event_output(ctx, &events, cpu, &ev, sizeof(ev));
 
 	return 0;
 }
 
-SEC("kretprobe/SyS_chmod")
-int kretprobe__handle_chmod(struct pt_regs *ctx)
+/* Process events */
+
+typedef struct {
+	common_event_t common;
+	u64 child_pid;
+} fork_event_t;
+
+/* wake_up_new_task is called by the parent once the new task is ready. If the
+ * parent is followed, the child inherits its handlers and program id. */
+SEC("kprobe/wake_up_new_task")
+int kprobe__wake_up_new_task(struct pt_regs *ctx)
 {
 	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
+	u32 tgid = pid_tgid >> 32;
+	u32 cpu = bpf_get_smp_processor_id();
+	struct task_struct *p = (struct task_struct *) PT_REGS_PARM1(ctx);
+	pid_t child_pid = 0, child_tgid = 0;
 
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed == NULL) {
 		return 0;
 	}
+	u32 handlers_tgid = *followed;
 
-	bpf_tail_call(ctx, (void *)&handle_chmod_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_chmod_progs_ret, 0);
-
-	return 0;
-}
-
-SEC("kprobe/SyS_fchmod")
-int kprobe__handle_fchmod(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
+	bpf_probe_read(&child_pid, sizeof(child_pid), &p->pid);
+	bpf_probe_read(&child_tgid, sizeof(child_tgid), &p->tgid);
 
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
+	// new threads are already traced with the handlers of their thread group
+	if (child_pid != child_tgid) {
 		return 0;
 	}
 
-	bpf_tail_call(ctx, (void *)&handle_fchmod_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_fchmod_progs, 0);
+	u32 child = child_tgid;
+	bpf_map_update_elem(&follow_pids, &child, &handlers_tgid, BPF_ANY);
 
-	return 0;
-}
-
-SEC("kretprobe/SyS_fchmod")
-int kretprobe__handle_fchmod(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
+	u64 *program_id = bpf_map_lookup_elem(&program_id_per_pid, &tgid);
+	if (program_id != NULL) {
+		bpf_map_update_elem(&program_id_per_pid, &child, program_id, BPF_ANY);
+	}
 
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
-		return 0;
+	u32 *watch = bpf_map_lookup_elem(&file_events_pids_to_watch, &tgid);
+	if (watch != NULL) {
+		bpf_map_update_elem(&file_events_pids_to_watch, &child, watch, BPF_ANY);
 	}
 
-	bpf_tail_call(ctx, (void *)&handle_fchmod_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_fchmod_progs_ret, 0);
+	fork_event_t ev = {
+		.common = {
+			.timestamp = bpf_ktime_get_ns(),
+			.program_id = program_id ? *program_id : 0,
+			.tgid = tgid,
+			.ret = 0,
+			.name = "fork",
+			.hash = 0,
+			.flags = 0,
+		},
+		.child_pid = child,
+	};
+
+	bpf_perf_event_output(ctx, &events, cpu, &ev, sizeof(ev));
 
 	return 0;
 }
 
-SEC("kprobe/SyS_fchmodat")
-int kprobe__handle_fchmodat(struct pt_regs *ctx)
+typedef struct {
+	common_event_t common;
+	s64 exit_code;
+	s64 signal;
+} exit_event_t;
+
+/* sched_process_exit is hit by each exiting thread, once it has left its
+ * thread group. Only the exit of the last thread of a traced process is
+ * reported, so userspace can clean up its state for the process: the leader
+ * may exit first (e.g. with pthread_exit) while the other threads keep
+ * running. Threads exiting at the same time may all see the group dead, the
+ * event is then reported more than once. */
+SEC("tracepoint/sched/sched_process_exit")
+int tracepoint__sched_process_exit(void *ctx)
 {
 	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
+	u32 tgid = pid_tgid >> 32;
+	u32 cpu = bpf_get_smp_processor_id();
+	struct task_struct *task = (struct task_struct *) bpf_get_current_task();
+	struct signal_struct *signal = NULL;
+	int live = 0;
+	int code = 0;
 
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
+	bpf_probe_read(&signal, sizeof(signal), &task->signal);
+	bpf_probe_read(&live, sizeof(live), &signal->live.counter);
+	if (live != 0) {
 		return 0;
 	}
 
-	bpf_tail_call(ctx, (void *)&handle_fchmodat_progs, tgid);
-	bpf_tail_call(ctx, (void *)&handle_fchmodat_progs, 0);
-
-	return 0;
-}
-
-SEC("kretprobe/SyS_fchmodat")
-int kretprobe__handle_fchmodat(struct pt_regs *ctx)
-{
-	u64 pid_tgid = bpf_get_current_pid_tgid();
-	u32 tgid = pid_tgid>>32;
-
-	void *untracked = bpf_map_lookup_elem(&untracked_pids, &tgid);
-	if (untracked != NULL) {
+	u64 *program_id = bpf_map_lookup_elem(&program_id_per_pid, &tgid);
+	u32 *watch = bpf_map_lookup_elem(&file_events_pids_to_watch, &tgid);
+	if (program_id == NULL && watch == NULL) {
 		return 0;
 	}
 
-	bpf_tail_call(ctx, (void *)&handle_fchmodat_progs_ret, tgid);
-	bpf_tail_call(ctx, (void *)&handle_fchmodat_progs_ret, 0);
+	// exit_code has the format of a wait status, set before the tracepoint
+	bpf_probe_read(&code, sizeof(code), &task->exit_code);
+	exit_event_t ev = {
+		.common = {
+			.timestamp = bpf_ktime_get_ns(),
+			.program_id = program_id ? *program_id : 0,
+			.tgid = tgid,
+			.ret = (code >> 8) & 0xff,
+			.name = "exit",
+			.hash = 0,
+			.flags = 0,
+		},
+		.exit_code = (code >> 8) & 0xff,
+		.signal = code & 0x7f,
+	};
+
+	bpf_perf_event_output(ctx, &events, cpu, &ev, sizeof(ev));
 
 	return 0;
 }
 
+/* Syscalls
+ *
+ * The prog arrays, kprobes and tracepoint programs of the syscalls are
+ * generated by the metagenerator from the config, see
+ * metagenerator/metagenerator.go.
+ */
+#include "syscalls-generated.h"
+
 /* Network Events */
 
 struct bpf_map_def SEC("maps/handle_tcp_v4_connect_progs") handle_tcp_v4_connect_progs = {
 	.type = BPF_MAP_TYPE_PROG_ARRAY,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
-	.max_entries = 32768,
+	.max_entries = PROG_ARRAY_ENTRIES,
 	.map_flags = 0,
 };
 
@@ -749,7 +352,7 @@ struct bpf_map_def SEC("maps/handle_tcp_v4_connect_progs_ret") handle_tcp_v4_con
 	.type = BPF_MAP_TYPE_PROG_ARRAY,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
-	.max_entries = 32768,
+	.max_entries = PROG_ARRAY_ENTRIES,
 	.map_flags = 0,
 };
 
@@ -757,7 +360,7 @@ struct bpf_map_def SEC("maps/handle_tcp_v6_connect_progs") handle_tcp_v6_connect
 	.type = BPF_MAP_TYPE_PROG_ARRAY,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
-	.max_entries = 32768,
+	.max_entries = PROG_ARRAY_ENTRIES,
 	.map_flags = 0,
 };
 
@@ -765,7 +368,7 @@ struct bpf_map_def SEC("maps/handle_tcp_v6_connect_progs_ret") handle_tcp_v6_con
 	.type = BPF_MAP_TYPE_PROG_ARRAY,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
-	.max_entries = 32768,
+	.max_entries = PROG_ARRAY_ENTRIES,
 	.map_flags = 0,
 };
 
@@ -773,7 +376,7 @@ struct bpf_map_def SEC("maps/handle_inet_csk_accept_progs") handle_inet_csk_acce
 	.type = BPF_MAP_TYPE_PROG_ARRAY,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
-	.max_entries = 32768,
+	.max_entries = PROG_ARRAY_ENTRIES,
 	.map_flags = 0,
 };
 
@@ -781,7 +384,7 @@ struct bpf_map_def SEC("maps/handle_inet_csk_accept_progs_ret") handle_inet_csk_
 	.type = BPF_MAP_TYPE_PROG_ARRAY,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
-	.max_entries = 32768,
+	.max_entries = PROG_ARRAY_ENTRIES,
 	.map_flags = 0,
 };
 
@@ -789,7 +392,7 @@ struct bpf_map_def SEC("maps/handle_tcp_set_state_progs") handle_tcp_set_state_p
 	.type = BPF_MAP_TYPE_PROG_ARRAY,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
-	.max_entries = 32768,
+	.max_entries = PROG_ARRAY_ENTRIES,
 	.map_flags = 0,
 };
 
@@ -797,7 +400,7 @@ struct bpf_map_def SEC("maps/handle_tcp_set_state_progs_ret") handle_tcp_set_sta
 	.type = BPF_MAP_TYPE_PROG_ARRAY,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
-	.max_entries = 32768,
+	.max_entries = PROG_ARRAY_ENTRIES,
 	.map_flags = 0,
 };
 
@@ -805,7 +408,7 @@ struct bpf_map_def SEC("maps/handle_tcp_close_progs") handle_tcp_close_progs = {
 	.type = BPF_MAP_TYPE_PROG_ARRAY,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
-	.max_entries = 32768,
+	.max_entries = PROG_ARRAY_ENTRIES,
 	.map_flags = 0,
 };
 
@@ -813,7 +416,7 @@ struct bpf_map_def SEC("maps/handle_tcp_close_progs_ret") handle_tcp_close_progs
 	.type = BPF_MAP_TYPE_PROG_ARRAY,
 	.key_size = sizeof(__u32),
 	.value_size = sizeof(__u32),
-	.max_entries = 32768,
+	.max_entries = PROG_ARRAY_ENTRIES,
 	.map_flags = 0,
 };
 
@@ -829,6 +432,18 @@ int kprobe__handle_tcp_v4_connect(struct pt_regs *ctx)
 	}
 
 	bpf_tail_call(ctx, (void *)&handle_tcp_v4_connect_progs, tgid);
+
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_v4_connect_progs, *followed);
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_v4_connect_progs, *slot);
+	}
+
 	bpf_tail_call(ctx, (void *)&handle_tcp_v4_connect_progs, 0);
 
 	return 0;
@@ -846,6 +461,18 @@ int kretprobe__handle_tcp_v4_connect(struct pt_regs *ctx)
 	}
 
 	bpf_tail_call(ctx, (void *)&handle_tcp_v4_connect_progs_ret, tgid);
+
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_v4_connect_progs_ret, *followed);
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_v4_connect_progs_ret, *slot);
+	}
+
 	bpf_tail_call(ctx, (void *)&handle_tcp_v4_connect_progs_ret, 0);
 
 	return 0;
@@ -863,6 +490,18 @@ int kprobe__handle_tcp_v6_connect(struct pt_regs *ctx)
 	}
 
 	bpf_tail_call(ctx, (void *)&handle_tcp_v6_connect_progs, tgid);
+
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_v6_connect_progs, *followed);
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_v6_connect_progs, *slot);
+	}
+
 	bpf_tail_call(ctx, (void *)&handle_tcp_v6_connect_progs, 0);
 
 	return 0;
@@ -880,6 +519,18 @@ int kretprobe__handle_tcp_v6_connect(struct pt_regs *ctx)
 	}
 
 	bpf_tail_call(ctx, (void *)&handle_tcp_v6_connect_progs_ret, tgid);
+
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_v6_connect_progs_ret, *followed);
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_v6_connect_progs_ret, *slot);
+	}
+
 	bpf_tail_call(ctx, (void *)&handle_tcp_v6_connect_progs_ret, 0);
 
 	return 0;
@@ -897,6 +548,18 @@ int kprobe__handle_inet_csk_accept(struct pt_regs *ctx)
 	}
 
 	bpf_tail_call(ctx, (void *)&handle_inet_csk_accept_progs, tgid);
+
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_inet_csk_accept_progs, *followed);
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_inet_csk_accept_progs, *slot);
+	}
+
 	bpf_tail_call(ctx, (void *)&handle_inet_csk_accept_progs, 0);
 
 	return 0;
@@ -914,6 +577,18 @@ int kretprobe__handle_inet_csk_accept(struct pt_regs *ctx)
 	}
 
 	bpf_tail_call(ctx, (void *)&handle_inet_csk_accept_progs_ret, tgid);
+
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_inet_csk_accept_progs_ret, *followed);
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_inet_csk_accept_progs_ret, *slot);
+	}
+
 	bpf_tail_call(ctx, (void *)&handle_inet_csk_accept_progs_ret, 0);
 
 	return 0;
@@ -931,6 +606,18 @@ int kprobe__handle_tcp_set_state(struct pt_regs *ctx)
 	}
 
 	bpf_tail_call(ctx, (void *)&handle_tcp_set_state_progs, tgid);
+
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_set_state_progs, *followed);
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_set_state_progs, *slot);
+	}
+
 	bpf_tail_call(ctx, (void *)&handle_tcp_set_state_progs, 0);
 
 	return 0;
@@ -948,6 +635,18 @@ int kretprobe__handle_tcp_set_state(struct pt_regs *ctx)
 	}
 
 	bpf_tail_call(ctx, (void *)&handle_tcp_set_state_progs_ret, tgid);
+
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_set_state_progs_ret, *followed);
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_set_state_progs_ret, *slot);
+	}
+
 	bpf_tail_call(ctx, (void *)&handle_tcp_set_state_progs_ret, 0);
 
 	return 0;
@@ -965,6 +664,18 @@ int kprobe__handle_tcp_close(struct pt_regs *ctx)
 	}
 
 	bpf_tail_call(ctx, (void *)&handle_tcp_close_progs, tgid);
+
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_close_progs, *followed);
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_close_progs, *slot);
+	}
+
 	bpf_tail_call(ctx, (void *)&handle_tcp_close_progs, 0);
 
 	return 0;
@@ -982,6 +693,18 @@ int kretprobe__handle_tcp_close(struct pt_regs *ctx)
 	}
 
 	bpf_tail_call(ctx, (void *)&handle_tcp_close_progs_ret, tgid);
+
+	u32 *followed = bpf_map_lookup_elem(&follow_pids, &tgid);
+	if (followed != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_close_progs_ret, *followed);
+	}
+
+	u64 cgroup_id = current_traced_cgroup_id();
+	u32 *slot = bpf_map_lookup_elem(&cgroup_slots, &cgroup_id);
+	if (slot != NULL) {
+		bpf_tail_call(ctx, (void *)&handle_tcp_close_progs_ret, *slot);
+	}
+
 	bpf_tail_call(ctx, (void *)&handle_tcp_close_progs_ret, 0);
 
 	return 0;
@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+// #include <inttypes.h>
+// #include "../../bpf/events-struct.h"
+import "C"
+
+const outputFormats = "text|json|ndjson|logfmt|template=<go-template>"
+
+// eventRecord is what is printed for each event by the structured output
+// formats. It is also the data passed to --output template=...
+type eventRecord struct {
+	Timestamp  uint64           `json:"timestamp"`
+	ProgramID  uint64           `json:"program_id"`
+	Pid        int64            `json:"pid"`
+	Ret        int64            `json:"ret"`
+	Errno      string           `json:"errno,omitempty"`
+	Name       string           `json:"name"`
+	Hash       uint64           `json:"hash"`
+	Flags      uint64           `json:"flags"`
+	Duration   uint64           `json:"duration_ns"`
+	Incomplete bool             `json:"incomplete"`
+	Container  bool             `json:"container"`
+	Args       tracer.EventArgs `json:"args"`
+
+	Process *tracer.ProcessInfo `json:"process,omitempty"`
+}
+
+// Arg returns the value of the argument with the given name, or nil
+func (r *eventRecord) Arg(name string) interface{} {
+	for _, arg := range r.Args {
+		if arg.Name == name {
+			return arg.Value
+		}
+	}
+	return nil
+}
+
+func newEventRecord(event *tracer.EventData) *eventRecord {
+	r := &eventRecord{
+		Timestamp:  event.Common.Timestamp,
+		ProgramID:  event.Common.ProgramID,
+		Pid:        event.Common.Pid,
+		Ret:        event.Common.Ret,
+		Errno:      tracer.Errno(event.Common.Ret),
+		Name:       event.Common.Name,
+		Hash:       event.Common.Hash,
+		Flags:      event.Common.Flags,
+		Duration:   event.Common.Duration,
+		Incomplete: event.Common.Flags == C.COMMON_EVENT_FLAG_INCOMPLETE_PROBE_READ,
+		Args:       event.Event.Args(event.Common.Ret),
+		Process:    event.Process,
+	}
+	if event.Process != nil {
+		r.Container = event.Process.Container
+	}
+	return r
+}
+
+type eventPrinter struct {
+	w      io.Writer
+	format string
+	tmpl   *template.Template
+	count  int
+
+	// show the time spent in the syscalls in the text output
+	durations bool
+}
+
+func newEventPrinter(w io.Writer, format string, durations bool) (*eventPrinter, error) {
+	p := &eventPrinter{w: w, format: format, durations: durations}
+
+	switch {
+	case format == "text", format == "json", format == "ndjson", format == "logfmt":
+	case strings.HasPrefix(format, "template="):
+		text := strings.TrimPrefix(format, "template=")
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		tmpl, err := template.New("output").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output template: %v", err)
+		}
+		p.format = "template"
+		p.tmpl = tmpl
+	default:
+		return nil, fmt.Errorf("unknown output format %q, expected %s", format, outputFormats)
+	}
+
+	return p, nil
+}
+
+func (p *eventPrinter) print(event *tracer.EventData) error {
+	// only the events written count, e.g. for the separators of the JSON
+	// array
+	if err := p.printEvent(event); err != nil {
+		return err
+	}
+	p.count++
+	return nil
+}
+
+func (p *eventPrinter) printEvent(event *tracer.EventData) error {
+	if p.format == "text" {
+		return p.printText(event)
+	}
+
+	r := newEventRecord(event)
+	switch p.format {
+	case "json":
+		b, err := json.MarshalIndent(r, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n  "
+		if p.count == 0 {
+			sep = "[\n  "
+		}
+		_, err = fmt.Fprintf(p.w, "%s%s", sep, b)
+		return err
+	case "ndjson":
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", b)
+		return err
+	case "logfmt":
+		return p.printLogfmt(r)
+	case "template":
+		return p.tmpl.Execute(p.w, r)
+	}
+
+	return nil
+}
+
+// close terminates the output, e.g. the JSON array
+func (p *eventPrinter) close() error {
+	if p.format != "json" {
+		return nil
+	}
+	if p.count == 0 {
+		_, err := io.WriteString(p.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(p.w, "\n]\n")
+	return err
+}
+
+func (p *eventPrinter) printText(event *tracer.EventData) error {
+	containerStr := ""
+	if p := event.Process; p != nil && p.Container {
+		containerStr = "[container]"
+		if c := p.ContainerInfo; c != nil && c.ID != "" {
+			containerStr = fmt.Sprintf("[container %.12s]", c.ID)
+		}
+	}
+
+	errorStr := ""
+	if event.Common.Flags == C.COMMON_EVENT_FLAG_INCOMPLETE_PROBE_READ {
+		errorStr = "[incomplete]"
+	}
+
+	// in seconds, like strace -T
+	durationStr := ""
+	if p.durations && event.Common.Duration > 0 {
+		durationStr = fmt.Sprintf(" <%.6f>", time.Duration(event.Common.Duration).Seconds())
+	}
+
+	evString := event.Event.String(event.Common.Ret)
+	_, err := fmt.Fprintf(p.w, "name %s pid %d program id %d return value %s hash %d %s%s%s%s\n",
+		event.Common.Name, event.Common.Pid, event.Common.ProgramID, tracer.DecodeRet(event.Common.Ret), event.Common.Hash, evString, containerStr, errorStr, durationStr)
+	return err
+}
+
+func (p *eventPrinter) printLogfmt(r *eventRecord) error {
+	fields := []tracer.EventArg{
+		{Name: "timestamp", Value: r.Timestamp},
+		{Name: "program_id", Value: r.ProgramID},
+		{Name: "pid", Value: r.Pid},
+		{Name: "ret", Value: r.Ret},
+	}
+	if r.Errno != "" {
+		fields = append(fields, tracer.EventArg{Name: "errno", Value: r.Errno})
+	}
+	fields = append(fields, []tracer.EventArg{
+		{Name: "name", Value: r.Name},
+		{Name: "hash", Value: r.Hash},
+		{Name: "flags", Value: r.Flags},
+		{Name: "duration_ns", Value: r.Duration},
+		{Name: "incomplete", Value: r.Incomplete},
+		{Name: "container", Value: r.Container},
+	}...)
+	if p := r.Process; p != nil {
+		fields = append(fields, []tracer.EventArg{
+			{Name: "comm", Value: p.Comm},
+			{Name: "exe", Value: p.Exe},
+			{Name: "ppid", Value: p.Ppid},
+			{Name: "uid", Value: p.UID},
+			{Name: "gid", Value: p.GID},
+			{Name: "cgroup", Value: p.Cgroup},
+		}...)
+		if c := p.ContainerInfo; c != nil {
+			fields = append(fields, []tracer.EventArg{
+				{Name: "container_runtime", Value: c.Runtime},
+				{Name: "container_id", Value: c.ID},
+				{Name: "container_name", Value: c.Name},
+				{Name: "container_image", Value: c.Image},
+				{Name: "pod_uid", Value: c.PodUID},
+				{Name: "qos_class", Value: c.QoSClass},
+			}...)
+		}
+	}
+	fields = append(fields, r.Args...)
+
+	var b strings.Builder
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(f.Name)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(f.Value))
+	}
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(p.w, b.String())
+	return err
+}
+
+func logfmtValue(v interface{}) string {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Sprintf("%v", v)
+	}
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+func openEvent(filename string, pid, ret int64) *tracer.EventData {
+	e := tracer.OpenEvent{Flags: 0x80000, Mode: 0644}
+	copy(e.Filename[:], filename)
+	return &tracer.EventData{
+		Common: tracer.CommonEvent{Name: "open", Pid: pid, Ret: ret, ProgramID: 7, Timestamp: 1000},
+		Event:  e,
+		Process: &tracer.ProcessInfo{
+			Comm: "nginx",
+			Exe:  "/usr/sbin/nginx",
+		},
+	}
+}
+
+// failingWriter fails the first fail writes
+type failingWriter struct {
+	bytes.Buffer
+	fail int
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if w.fail > 0 {
+		w.fail--
+		return 0, errors.New("disk full")
+	}
+	return w.Buffer.Write(b)
+}
+
+func printEvents(t *testing.T, format string, events ...*tracer.EventData) string {
+	var out bytes.Buffer
+	p, err := newEventPrinter(&out, format, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range events {
+		if err := p.print(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestEventPrinterFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		err    string
+	}{
+		{"text", ""},
+		{"json", ""},
+		{"ndjson", ""},
+		{"logfmt", ""},
+		{"template={{.Name}}", ""},
+		{"template={{.Name", "invalid output template"},
+		{"xml", "unknown output format"},
+	}
+	for _, tt := range tests {
+		_, err := newEventPrinter(&bytes.Buffer{}, tt.format, false)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.format, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want %q", tt.format, err, tt.err)
+		}
+	}
+}
+
+func TestLogfmtPrinter(t *testing.T) {
+	got := printEvents(t, "logfmt", openEvent("/tmp/my file", 42, -2))
+	want := `timestamp=1000 program_id=7 pid=42 ret=-2 errno=ENOENT name=open hash=0 flags=0 duration_ns=0 ` +
+		`incomplete=false container=false comm=nginx exe=/usr/sbin/nginx ppid=0 uid=0 gid=0 cgroup="" ` +
+		`Filename="/tmp/my file" Flags=O_RDONLY|O_CLOEXEC Mode=0644/rw-r--r--` + "\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJSONPrinter(t *testing.T) {
+	// no events, still an array
+	if got := printEvents(t, "json"); got != "[]\n" {
+		t.Errorf("got %q without events", got)
+	}
+
+	got := printEvents(t, "json", openEvent("/a", 1, 3), openEvent("/b", 2, 4))
+	var records []map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &records); err != nil {
+		t.Fatalf("invalid JSON %q: %v", got, err)
+	}
+	if len(records) != 2 || records[0]["pid"] != 1.0 || records[1]["pid"] != 2.0 || !strings.HasSuffix(got, "\n]\n") {
+		t.Errorf("got %s", got)
+	}
+}
+
+// TestJSONPrinterWriteError checks that the array stays valid when a write
+// fails
+func TestJSONPrinterWriteError(t *testing.T) {
+	for _, events := range []int{1, 2} {
+		w := &failingWriter{fail: 1}
+		p, err := newEventPrinter(w, "json", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.print(openEvent("/a", 1, 3)); err == nil {
+			t.Fatalf("no error from a failing write")
+		}
+		for i := 0; i < events; i++ {
+			if err := p.print(openEvent("/b", 2, 4)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := p.close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var records []map[string]interface{}
+		if err := json.Unmarshal(w.Bytes(), &records); err != nil {
+			t.Fatalf("invalid JSON %q: %v", w.Bytes(), err)
+		}
+		if len(records) != events {
+			t.Errorf("got %d records, want %d", len(records), events)
+		}
+	}
+}
+
+func TestNDJSONPrinter(t *testing.T) {
+	got := printEvents(t, "ndjson", openEvent("/a", 1, 3), openEvent("/b", 2, 4))
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %q", got)
+	}
+	for _, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Errorf("invalid JSON line %q: %v", line, err)
+		}
+	}
+}
+
+func TestTemplatePrinter(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{{.Name}} {{.Pid}}", "open 42\n"},
+		// a trailing newline isn't doubled
+		{"{{.Name}}\n", "open\n"},
+		{`{{.Arg "Filename"}} {{.Arg "Missing"}} {{.Errno}}`, "/tmp/a <no value> ENOENT\n"},
+		{"{{.Process.Comm}}", "nginx\n"},
+	}
+	for _, tt := range tests {
+		if got := printEvents(t, "template="+tt.template, openEvent("/tmp/a", 42, -2)); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	// execution errors are returned
+	p, err := newEventPrinter(&bytes.Buffer{}, "template={{.Nonexistent}}", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.print(openEvent("/tmp/a", 42, -2)); err == nil {
+		t.Errorf("no error for a missing field")
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ShiftLeftSecurity/traceleft/probe"
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+var (
+	recordCmd = &cobra.Command{
+		Use:   "record --capture <capture file> [[program_id:]<pids>|<selector>|cgroup:<path or id>:]<path elf object> ...",
+		Short: "Record raw events to a capture file",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("must pass at least one comma-separated [<pid>:]<path elf object> pair")
+			}
+			if capturePath == "" {
+				return fmt.Errorf("must pass a capture file with --capture")
+			}
+			return nil
+		},
+		Run: cmdRecord,
+	}
+
+	capturePath string
+)
+
+func init() {
+	recordCmd.Flags().IntVar(&handlerCacheSize, "handler-cache-size", 4, "size of the eBPF handler cache")
+	recordCmd.Flags().StringVar(&backendName, "backend", "kprobe", "how to capture syscalls: "+probe.Backends)
+	recordCmd.Flags().BoolVar(&followChildren, "follow", false, "also trace the children forked by traced processes")
+	recordCmd.Flags().StringVar(&capturePath, "capture", "", "path to the capture file to write")
+	recordCmd.Flags().DurationVar(&processCacheTTL, "process-cache-ttl", 10*time.Second, "how long the metadata of a process is cached before being read again from /proc")
+	recordCmd.Flags().StringVar(&containerRuntimeSocket, "container-runtime-socket", "", "unix socket of a Docker Engine API (docker or podman) to resolve the names and images of containers")
+
+	RootCmd.AddCommand(recordCmd)
+}
+
+func cmdRecord(cmd *cobra.Command, args []string) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, os.Kill)
+
+	events, err := parseEventMap(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse events to trace: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := resolveSelectors(events, false); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve processes to trace: %v\n", err)
+		os.Exit(1)
+	}
+
+	handlers := make(map[string][]byte)
+	for _, event := range events {
+		elfBPFBytes, err := ioutil.ReadFile(event.ELFPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read %q: %v\n", event.ELFPath, err)
+			os.Exit(1)
+		}
+		handlers[event.ELFPath] = elfBPFBytes
+	}
+
+	hdr, err := tracer.NewCaptureHeader(handlers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Create(capturePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create capture file: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	cw, err := tracer.NewCaptureWriter(f, hdr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	// the events are decoded while recording, so that what is read from
+	// /proc to decode them is recorded along with them
+	ctx.Processes = newProcessCache()
+	ctx.Capture = cw
+
+	var samples, lost uint64
+	recordEvent := func(data *[]byte) {
+		if _, err := decodeEvent(data); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to %v\n", err)
+		}
+		if err := cw.WriteSample(*data); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to record event: %v\n", err)
+			return
+		}
+		samples++
+	}
+	recordLostEvent := func(lostCount uint64) {
+		if err := cw.WriteLost(lostCount); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to record lost events: %v\n", err)
+			return
+		}
+		lost += lostCount
+	}
+
+	t, err := newTracer(recordEvent, recordLostEvent)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if err := t.Probe.SetFollowChildren(followChildren); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to follow children: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := registerEvents(t.Probe, events); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to register events to trace: %v\n", err)
+		os.Exit(1)
+	}
+
+	<-sig
+	t.Stop()
+	ctx.Fds.Clear()
+
+	if err := cw.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write capture file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Recorded %d events (%d lost) to %s\n", samples, lost, capturePath)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+var (
+	replayCmd = &cobra.Command{
+		Use:   "replay <capture file>",
+		Short: "Replay events from a capture file",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("must pass exactly one capture file")
+			}
+			if replaySpeed < 0 {
+				return fmt.Errorf("speed must not be negative")
+			}
+			return nil
+		},
+		Run: cmdReplay,
+	}
+
+	replaySpeed float64
+)
+
+func init() {
+	replayCmd.Flags().Float64Var(&replaySpeed, "speed", 1, "replay speed relative to the recording, 0 to replay as fast as possible")
+	replayCmd.Flags().BoolVar(&collectorWithInsecure, "collector-insecure", false, "disable transport security for collector connection")
+	replayCmd.Flags().StringVar(&aggregationSpecPath, "aggregation-spec", "", "path to the aggregation spec in json format")
+	replayCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "output format of the events without aggregation spec: "+outputFormats)
+	replayCmd.Flags().BoolVarP(&showDurations, "durations", "T", false, "show the time spent in the syscalls in the text output, like strace -T")
+
+	RootCmd.AddCommand(replayCmd)
+}
+
+func cmdReplay(cmd *cobra.Command, args []string) {
+	f, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open capture file: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	cr, err := tracer.NewCaptureReader(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read capture file: %v\n", err)
+		os.Exit(1)
+	}
+
+	hdr := cr.Header()
+	fmt.Fprintf(os.Stderr, "Replaying capture from %s (kernel %s)\n", hdr.Created.Format("2006-01-02 15:04:05"), hdr.KernelRelease)
+	for _, h := range hdr.Handlers {
+		fmt.Fprintf(os.Stderr, "  handler %s sha512:%s\n", h.Path, h.SHA512)
+	}
+
+	// everything read from /proc to decode the events comes from the capture
+	ctx.Replay = tracer.NewReplayState()
+
+	stopPipeline, err := startPipeline()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	err = tracer.Replay(cr, ctx, replaySpeed, handleEvent, handleLostEvent)
+	stopPipeline()
+	ctx.Fds.Clear()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to replay capture: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ShiftLeftSecurity/traceleft/probe"
+)
+
+var (
+	runCmd = &cobra.Command{
+		Use:   "run --handlers [program_id:]<path elf object>,... -- <command> [args]",
+		Short: "Run a command and trace it from its first syscall",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("must pass a command to run")
+			}
+			if len(runHandlers) < 1 {
+				return fmt.Errorf("must pass at least one handler with --handlers")
+			}
+			return nil
+		},
+		Run: cmdRun,
+	}
+
+	runHandlers []string
+)
+
+func init() {
+	runCmd.Flags().StringSliceVar(&runHandlers, "handlers", nil, "comma-separated list of [program_id:]<path elf object> to register for the command")
+	runCmd.Flags().BoolVarP(&followChildren, "follow", "f", false, "also trace the children forked by the command")
+	runCmd.Flags().IntVar(&handlerCacheSize, "handler-cache-size", 4, "size of the eBPF handler cache")
+	runCmd.Flags().StringVar(&backendName, "backend", "kprobe", "how to capture syscalls: "+probe.Backends)
+	runCmd.Flags().BoolVar(&collectorWithInsecure, "collector-insecure", false, "disable transport security for collector connection")
+	runCmd.Flags().StringVar(&aggregationSpecPath, "aggregation-spec", "", "path to the aggregation spec in json format")
+	runCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "output format of the events without aggregation spec: "+outputFormats)
+	runCmd.Flags().BoolVarP(&showDurations, "durations", "T", false, "show the time spent in the syscalls in the text output, like strace -T")
+	runCmd.Flags().DurationVar(&processCacheTTL, "process-cache-ttl", 10*time.Second, "how long the metadata of a process is cached before being read again from /proc")
+	runCmd.Flags().StringVar(&containerRuntimeSocket, "container-runtime-socket", "", "unix socket of a Docker Engine API (docker or podman) to resolve the names and images of containers")
+
+	RootCmd.AddCommand(runCmd)
+}
+
+type runHandler struct {
+	programID uint64
+	elf       []byte
+}
+
+func parseRunHandlers(handlers []string) ([]runHandler, error) {
+	var result []runHandler
+	for _, h := range handlers {
+		parts := strings.Split(h, ":")
+		if len(parts) > 2 {
+			return nil, fmt.Errorf("malformed handler %q", h)
+		}
+
+		var programID uint64
+		if len(parts) == 2 {
+			var err error
+			programID, err = strconv.ParseUint(parts[0], 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed program id %q in handler", parts[0])
+			}
+		}
+
+		elfPath := parts[len(parts)-1]
+		elf, err := ioutil.ReadFile(elfPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %q: %v", elfPath, err)
+		}
+
+		result = append(result, runHandler{programID: programID, elf: elf})
+	}
+	return result, nil
+}
+
+// startStopped starts the command under ptrace so that it stops right after
+// execve, before running any of its own code. The caller must resume it with
+// PtraceDetach from the same OS thread.
+func startStopped(c *exec.Cmd) error {
+	c.SysProcAttr = &syscall.SysProcAttr{Ptrace: true}
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	var ws syscall.WaitStatus
+	if _, err := syscall.Wait4(c.Process.Pid, &ws, 0, nil); err != nil {
+		return fmt.Errorf("error waiting for the command to stop: %v", err)
+	}
+	if !ws.Stopped() {
+		return fmt.Errorf("command did not stop after execve (status %v)", ws)
+	}
+
+	return nil
+}
+
+func exitCode(state *os.ProcessState) int {
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok {
+		return 1
+	}
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return ws.ExitStatus()
+}
+
+func cmdRun(cmd *cobra.Command, args []string) {
+	handlers, err := parseRunHandlers(runHandlers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse handlers: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx.Processes = newProcessCache()
+
+	stopPipeline, err := startPipeline()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	t, err := newTracer(handleEvent, handleLostEvent)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if err := t.Probe.SetFollowChildren(followChildren); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to follow children: %v\n", err)
+		t.Stop()
+		os.Exit(1)
+	}
+
+	// ptrace requests must come from the thread which started the tracee
+	runtime.LockOSThread()
+
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := startStopped(c); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start %q: %v\n", args[0], err)
+		t.Stop()
+		os.Exit(1)
+	}
+	pid := c.Process.Pid
+
+	for _, h := range handlers {
+		if err := t.Probe.RegisterHandler(h.programID, pid, h.elf); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to register handler: %v\n", err)
+			c.Process.Kill()
+			t.Stop()
+			os.Exit(1)
+		}
+	}
+	// e.g. redirections of the standard streams
+	seedFds(pid)
+
+	if err := syscall.PtraceDetach(pid); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resume %q: %v\n", args[0], err)
+		c.Process.Kill()
+		t.Stop()
+		os.Exit(1)
+	}
+	runtime.UnlockOSThread()
+
+	// the command gets terminal signals itself, forward the others
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		for s := range sig {
+			c.Process.Signal(s)
+		}
+	}()
+
+	c.Wait()
+	signal.Stop(sig)
+
+	t.Stop()
+	stopPipeline()
+	ctx.Fds.Clear()
+
+	os.Exit(exitCode(c.ProcessState))
+}
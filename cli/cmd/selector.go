@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ShiftLeftSecurity/traceleft/probe"
+)
+
+// processSelector matches processes by one of their attributes, see
+// parseProcessSelector for the syntax.
+type processSelector struct {
+	kind  string // "comm", "exe" or "cmdline"
+	value string
+	re    *regexp.Regexp
+}
+
+var selectorPrefixes = []string{"comm=", "exe=", "cmdline~="}
+
+func isProcessSelector(s string) bool {
+	for _, prefix := range selectorPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProcessSelector parses one of:
+//
+//	comm=<name>        processes whose command name (/proc/<pid>/comm) is name
+//	exe=<path>         processes whose executable is path
+//	cmdline~=<regex>   processes whose command line (arguments separated by
+//	                   spaces) matches regex, as pgrep -f
+func parseProcessSelector(s string) (*processSelector, error) {
+	switch {
+	case strings.HasPrefix(s, "comm="):
+		comm := strings.TrimPrefix(s, "comm=")
+		if comm == "" {
+			return nil, fmt.Errorf("empty comm in selector %q", s)
+		}
+		return &processSelector{kind: "comm", value: comm}, nil
+	case strings.HasPrefix(s, "exe="):
+		exe := strings.TrimPrefix(s, "exe=")
+		if exe == "" {
+			return nil, fmt.Errorf("empty exe in selector %q", s)
+		}
+		return &processSelector{kind: "exe", value: exe}, nil
+	case strings.HasPrefix(s, "cmdline~="):
+		pattern := strings.TrimPrefix(s, "cmdline~=")
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex in selector %q: %v", s, err)
+		}
+		return &processSelector{kind: "cmdline", value: pattern, re: re}, nil
+	}
+
+	return nil, fmt.Errorf("unknown selector %q", s)
+}
+
+func (s *processSelector) String() string {
+	if s.kind == "cmdline" {
+		return "cmdline~=" + s.value
+	}
+	return s.kind + "=" + s.value
+}
+
+// matches returns whether the process pid matches the selector. Processes
+// that exited or can't be inspected don't match.
+func (s *processSelector) matches(pid int) bool {
+	procDir := "/proc/" + strconv.Itoa(pid)
+
+	switch s.kind {
+	case "comm":
+		comm, err := ioutil.ReadFile(procDir + "/comm")
+		if err != nil {
+			return false
+		}
+		return strings.TrimSuffix(string(comm), "\n") == s.value
+	case "exe":
+		exe, err := os.Readlink(procDir + "/exe")
+		if err != nil {
+			return false
+		}
+		return exe == s.value
+	case "cmdline":
+		cmdline, err := ioutil.ReadFile(procDir + "/cmdline")
+		if err != nil || len(cmdline) == 0 {
+			// kernel threads have an empty command line
+			return false
+		}
+		cmdline = bytes.TrimSuffix(cmdline, []byte{0})
+		cmdline = bytes.Replace(cmdline, []byte{0}, []byte{' '}, -1)
+		return s.re.Match(cmdline)
+	}
+
+	return false
+}
+
+// listPids returns the pids of all the processes (not threads) running
+func listPids() ([]int, error) {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("error listing processes: %v", err)
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// matchingPids returns the pids of the running processes matching the
+// selector, except our own.
+func (s *processSelector) matchingPids() ([]int, error) {
+	pids, err := listPids()
+	if err != nil {
+		return nil, err
+	}
+
+	self := os.Getpid()
+	var matching []int
+	for _, pid := range pids {
+		if pid != self && s.matches(pid) {
+			matching = append(matching, pid)
+		}
+	}
+	return matching, nil
+}
+
+// selectorWatcher periodically registers the handlers of events with a
+// selector for the processes which started matching since the last scan.
+type selectorWatcher struct {
+	probe    *probe.Probe
+	events   []Event
+	elfs     map[string][]byte
+	interval time.Duration
+
+	// pids the handlers of each event were registered for
+	registered []map[int]struct{}
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newSelectorWatcher(p *probe.Probe, events []Event, interval time.Duration) (*selectorWatcher, error) {
+	w := &selectorWatcher{
+		probe:    p,
+		elfs:     make(map[string][]byte),
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+
+	for _, event := range events {
+		if event.Selector == nil {
+			continue
+		}
+		if _, ok := w.elfs[event.ELFPath]; !ok {
+			elfBPFBytes, err := ioutil.ReadFile(event.ELFPath)
+			if err != nil {
+				return nil, fmt.Errorf("error reading %q: %v", event.ELFPath, err)
+			}
+			w.elfs[event.ELFPath] = elfBPFBytes
+		}
+
+		registered := make(map[int]struct{})
+		for _, pid := range event.Pids {
+			registered[pid] = struct{}{}
+		}
+		w.events = append(w.events, event)
+		w.registered = append(w.registered, registered)
+	}
+
+	return w, nil
+}
+
+func (w *selectorWatcher) start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				if err := w.scan(); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to watch processes: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+func (w *selectorWatcher) scan() error {
+	pids, err := listPids()
+	if err != nil {
+		return err
+	}
+	running := make(map[int]struct{}, len(pids))
+	for _, pid := range pids {
+		running[pid] = struct{}{}
+	}
+
+	for i, event := range w.events {
+		registered := w.registered[i]
+
+		// forget processes which exited, their pid may be reused
+		for pid := range registered {
+			if _, ok := running[pid]; !ok {
+				delete(registered, pid)
+			}
+		}
+
+		matching, err := event.Selector.matchingPids()
+		if err != nil {
+			return err
+		}
+		for _, pid := range matching {
+			if _, ok := registered[pid]; ok {
+				continue
+			}
+			if err := w.probe.RegisterHandler(event.ProgramID, pid, w.elfs[event.ELFPath]); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to register handler for pid %d (%s): %v\n", pid, event.Selector, err)
+				continue
+			}
+			seedFds(pid)
+			registered[pid] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+func (w *selectorWatcher) close() {
+	close(w.stop)
+	w.wg.Wait()
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseProcessSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		kind     string
+		value    string
+		err      string
+	}{
+		{"comm=nginx", "comm", "nginx", ""},
+		{"comm=my prog", "comm", "my prog", ""},
+		{"exe=/usr/sbin/nginx", "exe", "/usr/sbin/nginx", ""},
+		{"cmdline~=nginx: worker", "cmdline", "nginx: worker", ""},
+		{"cmdline~=^python .*manage\\.py", "cmdline", "^python .*manage\\.py", ""},
+		// an empty regex matches everything, like pgrep -f ''
+		{"cmdline~=", "cmdline", "", ""},
+
+		{"comm=", "", "", "empty comm"},
+		{"exe=", "", "", "empty exe"},
+		{"cmdline~=(", "", "", "invalid regex"},
+		{"cmdline=nginx", "", "", "unknown selector"},
+		{"pid=42", "", "", "unknown selector"},
+		{"nginx", "", "", "unknown selector"},
+	}
+
+	for _, tt := range tests {
+		s, err := parseProcessSelector(tt.selector)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseProcessSelector(%q): got error %v, want %q", tt.selector, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseProcessSelector(%q): unexpected error: %v", tt.selector, err)
+			continue
+		}
+		if s.kind != tt.kind || s.value != tt.value {
+			t.Errorf("parseProcessSelector(%q): got %s %q, want %s %q", tt.selector, s.kind, s.value, tt.kind, tt.value)
+		}
+		if s.String() != tt.selector {
+			t.Errorf("parseProcessSelector(%q).String() = %q", tt.selector, s.String())
+		}
+		if isProcessSelector(tt.selector) != true {
+			t.Errorf("isProcessSelector(%q) = false", tt.selector)
+		}
+	}
+}
+
+func TestParseSelectorEventMap(t *testing.T) {
+	tests := []struct {
+		eventMap  string
+		programID uint64
+		selector  string
+		elf       string
+		err       string
+	}{
+		{"comm=nginx:handle_open.bpf", 0, "comm=nginx", "handle_open.bpf", ""},
+		{"7:comm=nginx:handle_open.bpf", 7, "comm=nginx", "handle_open.bpf", ""},
+		{"0x10:exe=/bin/sh:out/handle_read.bpf", 16, "exe=/bin/sh", "out/handle_read.bpf", ""},
+		// the selector may contain colons
+		{"cmdline~=nginx: worker:handle_open.bpf", 0, "cmdline~=nginx: worker", "handle_open.bpf", ""},
+		{"3:cmdline~=a:b:c:handle_open.bpf", 3, "cmdline~=a:b:c", "handle_open.bpf", ""},
+
+		{"handle_open.bpf", 0, "", "", "malformed event-map"},
+		{"nginx:handle_open.bpf", 0, "", "", "malformed event-map"},
+		{"x:comm=nginx:handle_open.bpf", 0, "", "", "malformed program id"},
+		{"1:pid=3:handle_open.bpf", 0, "", "", "unknown selector"},
+		{"comm=:handle_open.bpf", 0, "", "", "empty comm"},
+	}
+
+	for _, tt := range tests {
+		ev, err := parseSelectorEventMap(tt.eventMap)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseSelectorEventMap(%q): got error %v, want %q", tt.eventMap, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSelectorEventMap(%q): unexpected error: %v", tt.eventMap, err)
+			continue
+		}
+		if ev.ProgramID != tt.programID || ev.Selector.String() != tt.selector || ev.ELFPath != tt.elf {
+			t.Errorf("parseSelectorEventMap(%q): got %d %s %q, want %d %s %q", tt.eventMap,
+				ev.ProgramID, ev.Selector, ev.ELFPath, tt.programID, tt.selector, tt.elf)
+		}
+	}
+}
+
+// TestProcessSelectorMatches matches selectors against the test process
+func TestProcessSelectorMatches(t *testing.T) {
+	pid := os.Getpid()
+	comm, err := ioutil.ReadFile("/proc/self/comm")
+	if err != nil {
+		t.Skipf("no /proc: %v", err)
+	}
+	exe, err := os.Readlink("/proc/self/exe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		selector string
+		match    bool
+	}{
+		{"comm=" + strings.TrimSuffix(string(comm), "\n"), true},
+		{"comm=" + strings.TrimSuffix(string(comm), "\n") + "x", false},
+		{"exe=" + exe, true},
+		{"exe=/nonexistent", false},
+		// arguments are separated by spaces
+		{"cmdline~=\\.test( |$)", true},
+		{"cmdline~=^this does not match$", false},
+	}
+
+	for _, tt := range tests {
+		s, err := parseProcessSelector(tt.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.matches(pid); got != tt.match {
+			t.Errorf("%s: got %t, want %t", tt.selector, got, tt.match)
+		}
+	}
+
+	// processes that don't exist never match
+	s, _ := parseProcessSelector("cmdline~=")
+	if s.matches(-1) {
+		t.Errorf("a nonexistent process matched")
+	}
+}
@@ -9,8 +9,10 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -19,19 +21,17 @@ import (
 	"github.com/ShiftLeftSecurity/traceleft/tracer"
 )
 
-// #include <inttypes.h>
-// #include "../../bpf/events-struct.h"
-import "C"
-
 type Event struct {
 	ProgramID uint64
 	Pids      []int
+	Cgroup    string // path or id of a cgroup, instead of Pids
+	Selector  *processSelector
 	ELFPath   string
 }
 
 var (
 	traceCmd = &cobra.Command{
-		Use:   "trace [[program_id:]<pid>:]<path elf object> ...",
+		Use:   "trace [[program_id:]<pids>|<selector>|cgroup:<path or id>:]<path elf object> ...",
 		Short: "Trace processes",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
@@ -43,74 +43,103 @@ var (
 	}
 	ctx tracer.Context
 
-	handlerCacheSize      int
-	collectorWithInsecure bool
-	aggregationSpecPath   string
+	handlerCacheSize       int
+	backendName            string
+	collectorWithInsecure  bool
+	aggregationSpecPath    string
+	outputFormat           string
+	followChildren         bool
+	watchProcesses         bool
+	watchInterval          time.Duration
+	processCacheTTL        time.Duration
+	containerRuntimeSocket string
+	showDurations          bool
 )
 
 func init() {
 	traceCmd.Flags().IntVar(&handlerCacheSize, "handler-cache-size", 4, "size of the eBPF handler cache")
+	traceCmd.Flags().StringVar(&backendName, "backend", "kprobe", "how to capture syscalls: "+probe.Backends)
 	traceCmd.Flags().BoolVar(&collectorWithInsecure, "collector-insecure", false, "disable transport security for collector connection")
 	ctx.Fds = tracer.NewFdMap()
 	traceCmd.Flags().StringVar(&aggregationSpecPath, "aggregation-spec", "", "path to the aggregation spec in json format")
+	traceCmd.Flags().BoolVar(&followChildren, "follow", false, "also trace the children forked by traced processes")
+	traceCmd.Flags().BoolVar(&watchProcesses, "watch", false, "keep registering handlers for new processes matching the selectors")
+	traceCmd.Flags().DurationVar(&watchInterval, "watch-interval", time.Second, "interval between scans for new processes with --watch")
+	traceCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "output format of the events without aggregation spec: "+outputFormats)
+	traceCmd.Flags().BoolVarP(&showDurations, "durations", "T", false, "show the time spent in the syscalls in the text output, like strace -T")
+	traceCmd.Flags().DurationVar(&processCacheTTL, "process-cache-ttl", 10*time.Second, "how long the metadata of a process is cached before being read again from /proc")
+	traceCmd.Flags().StringVar(&containerRuntimeSocket, "container-runtime-socket", "", "unix socket of a Docker Engine API (docker or podman) to resolve the names and images of containers")
 }
 
 var eventChan chan *tracer.EventData
 
-func cmdTrace(cmd *cobra.Command, args []string) {
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, os.Kill)
-
+// startPipeline starts consuming events from eventChan: they are passed to
+// an aggregator if an aggregation spec was given, or printed otherwise. The
+// returned function stops the pipeline once all the events were sent.
+func startPipeline() (func(), error) {
 	eventChan = make(chan *tracer.EventData)
 
-	var spec metrics.AggregationSpec
-
 	if aggregationSpecPath != "" {
+		var spec metrics.AggregationSpec
+
 		b, err := ioutil.ReadFile(aggregationSpecPath)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to read aggregation spec: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("failed to read aggregation spec: %v", err)
 		}
 
 		err = json.Unmarshal(b, &spec)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to unmarshal aggregation spec: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("failed to unmarshal aggregation spec: %v", err)
 		}
 
 		aggregator, err := metrics.NewAggregator(metrics.AggregatorOptions{
-			collectorWithInsecure,
+			DialInsecure: collectorWithInsecure,
 		}, eventChan, spec, ctx)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to get aggregator: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("failed to get aggregator: %v", err)
 		}
-		defer aggregator.Stop()
-	} else {
-		go func() {
-			for event := range eventChan {
-				if event.Common.Name == "fd_install" {
-					continue
-				}
+		return aggregator.Stop, nil
+	}
 
-				containerStr := ""
-				if isContainer(event.Common.Pid) {
-					containerStr = "[container]"
-				}
+	printer, err := newEventPrinter(os.Stdout, outputFormat, showDurations)
+	if err != nil {
+		return nil, err
+	}
 
-				errorStr := ""
-				if event.Common.Flags == C.COMMON_EVENT_FLAG_INCOMPLETE_PROBE_READ {
-					errorStr = "[incomplete]"
-				}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range eventChan {
+			if event.Common.Name == "fd_install" {
+				continue
+			}
 
-				evString := event.Event.String(event.Common.Ret)
-				fmt.Printf("name %s pid %d program id %d return value %d hash %d %s%s%s\n",
-					event.Common.Name, event.Common.Pid, event.Common.ProgramID, event.Common.Ret, event.Common.Hash, evString, containerStr, errorStr)
+			if err := printer.print(event); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to print event: %v\n", err)
 			}
-		}()
+		}
+	}()
+
+	return func() {
+		close(eventChan)
+		<-done
+		printer.close()
+	}, nil
+}
+
+func cmdTrace(cmd *cobra.Command, args []string) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, os.Kill)
+
+	ctx.Processes = newProcessCache()
+
+	stopPipeline, err := startPipeline()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
 
-	tracer, err := tracer.New(handleEvent, handleLostEvent, handlerCacheSize)
+	tracer, err := newTracer(handleEvent, handleLostEvent)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
@@ -122,11 +151,31 @@ func cmdTrace(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	if err := resolveSelectors(events, watchProcesses); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve processes to trace: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := tracer.Probe.SetFollowChildren(followChildren); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to follow children: %v\n", err)
+		os.Exit(1)
+	}
+
 	if err := registerEvents(tracer.Probe, events); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to register events to trace: %v\n", err)
 		os.Exit(1)
 	}
 
+	var watcher *selectorWatcher
+	if watchProcesses {
+		watcher, err = newSelectorWatcher(tracer.Probe, events, watchInterval)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to watch processes: %v\n", err)
+			os.Exit(1)
+		}
+		watcher.start()
+	}
+
 	go func() {
 		if err := http.ListenAndServe("localhost:6060", nil); err != nil {
 			fmt.Fprintf(os.Stderr, "http server failed: %v\n", err)
@@ -135,7 +184,11 @@ func cmdTrace(cmd *cobra.Command, args []string) {
 	}()
 
 	<-sig
+	if watcher != nil {
+		watcher.close()
+	}
 	tracer.Stop()
+	stopPipeline()
 	ctx.Fds.Clear()
 }
 
@@ -143,35 +196,52 @@ func init() {
 	RootCmd.AddCommand(traceCmd)
 }
 
-func isContainer(pid int64) bool {
-	mntNs, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/mnt", pid))
-	if err != nil {
-		return false
+// newProcessCache returns a process cache using the options given on the
+// command line
+func newProcessCache() *tracer.ProcessCache {
+	var resolver *tracer.ContainerResolver
+	if containerRuntimeSocket != "" {
+		resolver = tracer.NewContainerResolver(containerRuntimeSocket)
 	}
-	hostMntNs, err := os.Readlink("/proc/1/ns/mnt")
+	return tracer.NewProcessCache(processCacheTTL, resolver)
+}
+
+// newTracer returns a tracer using the backend given on the command line
+func newTracer(callback func(*[]byte), callbackLost func(uint64)) (*tracer.Tracer, error) {
+	backend, err := probe.ParseBackend(backendName)
 	if err != nil {
-		return false
+		return nil, err
 	}
-
-	return mntNs != hostMntNs
+	return tracer.New(callback, callbackLost, handlerCacheSize, backend)
 }
 
 func handleEvent(data *[]byte) {
+	event, err := decodeEvent(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to %v\n", err)
+		return
+	}
+	eventChan <- event
+}
+
+// decodeEvent decodes a raw perf sample with ctx
+func decodeEvent(data *[]byte) (*tracer.EventData, error) {
 	buf := bytes.NewBuffer(*data)
 	commonEvent, err := tracer.CommonEventFromBuffer(buf)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to decode received data: %v\n", err)
-		return
+		return nil, fmt.Errorf("decode received data: %v", err)
 	}
+	// read before GetStruct, which drops the processes exiting
+	process := ctx.Process(commonEvent.Pid)
 	event, err := tracer.GetStruct(commonEvent, ctx, buf)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get event struct: %v\n", err)
-		return
-	}
-	eventChan <- &tracer.EventData{
-		*commonEvent,
-		event,
+		return nil, fmt.Errorf("get event struct: %v", err)
 	}
+	return &tracer.EventData{
+		Common:  *commonEvent,
+		Event:   event,
+		Process: process,
+	}, nil
 }
 
 func handleLostEvent(lostCount uint64) {
@@ -185,20 +255,177 @@ func registerEvents(p *probe.Probe, events []Event) error {
 			return fmt.Errorf("error reading %q: %v", event.ELFPath, err)
 		}
 
+		if event.Cgroup != "" {
+			cgroupID, err := parseCgroup(event.Cgroup)
+			if err != nil {
+				return err
+			}
+			if err := p.RegisterHandlerForCgroup(event.ProgramID, cgroupID, elfBPFBytes); err != nil {
+				return fmt.Errorf("error registering handler for cgroup %q: %v", event.Cgroup, err)
+			}
+			// processes are only known when given the path of the cgroup
+			if _, err := strconv.ParseUint(event.Cgroup, 0, 64); err != nil {
+				for _, pid := range cgroupPids(event.Cgroup) {
+					seedFds(pid)
+				}
+			}
+			continue
+		}
+
 		for _, pid := range event.Pids {
 			if err := p.RegisterHandler(event.ProgramID, pid, elfBPFBytes); err != nil {
 				return fmt.Errorf("error registering handler: %v", err)
 			}
+			seedFds(pid)
 		}
 	}
 
 	return nil
 }
 
+// seedFds adds the file descriptors already open by a newly traced process to
+// ctx.Fds, since fd_install events are only received for the ones it opens
+// later. Pid 0 is not a process but the default handler of all the processes,
+// whose file descriptors are only known from the fd_install events.
+func seedFds(pid int) {
+	if pid == 0 {
+		return
+	}
+	if err := ctx.ScanPid(uint32(pid)); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read the file descriptors of pid %d: %v\n", pid, err)
+	}
+}
+
+// cgroupPids returns the processes in a cgroup and its descendants, which
+// are traced too, given its path
+func cgroupPids(cgroup string) []int {
+	var pids []int
+	filepath.Walk(cgroup, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		data, err := ioutil.ReadFile(filepath.Join(path, "cgroup.procs"))
+		if err != nil {
+			return nil
+		}
+		for _, line := range strings.Fields(string(data)) {
+			if pid, err := strconv.Atoi(line); err == nil {
+				pids = append(pids, pid)
+			}
+		}
+		return nil
+	})
+	return pids
+}
+
+// resolveSelectors sets the pids of the events with a selector to the
+// processes currently matching it. Unless allowEmpty is set, it is an error
+// for a selector not to match any process.
+func resolveSelectors(events []Event, allowEmpty bool) error {
+	for i := range events {
+		if events[i].Selector == nil {
+			continue
+		}
+		pids, err := events[i].Selector.matchingPids()
+		if err != nil {
+			return err
+		}
+		if len(pids) == 0 && !allowEmpty {
+			return fmt.Errorf("no process matches %s", events[i].Selector)
+		}
+		events[i].Pids = pids
+	}
+	return nil
+}
+
+// parseSelectorEventMap parses an event-map of the form
+// [program_id:]<selector>:<path elf object>. The selector may contain colons.
+func parseSelectorEventMap(eventMap string) (Event, error) {
+	sep := strings.LastIndex(eventMap, ":")
+	if sep < 0 {
+		return Event{}, fmt.Errorf("malformed event-map %q", eventMap)
+	}
+	selectorStr, ebpfFile := eventMap[:sep], eventMap[sep+1:]
+
+	var programID uint64
+	if !isProcessSelector(selectorStr) {
+		parts := strings.SplitN(selectorStr, ":", 2)
+		if len(parts) != 2 {
+			return Event{}, fmt.Errorf("malformed event-map %q", eventMap)
+		}
+		var err error
+		programID, err = strconv.ParseUint(parts[0], 0, 64)
+		if err != nil {
+			return Event{}, fmt.Errorf("malformed program id %q in event-map", parts[0])
+		}
+		selectorStr = parts[1]
+	}
+
+	selector, err := parseProcessSelector(selectorStr)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ProgramID: programID,
+		Selector:  selector,
+		ELFPath:   ebpfFile,
+	}, nil
+}
+
+// parseCgroup returns the id of a cgroup given by id or by path
+func parseCgroup(cgroup string) (uint64, error) {
+	if cgroupID, err := strconv.ParseUint(cgroup, 0, 64); err == nil {
+		return cgroupID, nil
+	}
+	return probe.CgroupID(cgroup)
+}
+
+// parseCgroupEventMap parses an event-map of the form
+// [program_id:]cgroup:<path or id>:<path elf object>
+func parseCgroupEventMap(eventMap string, evParts []string) (Event, error) {
+	var programID uint64
+	if evParts[0] != "cgroup" {
+		var err error
+		programID, err = strconv.ParseUint(evParts[0], 0, 64)
+		if err != nil {
+			return Event{}, fmt.Errorf("malformed program id %q in event-map", evParts[0])
+		}
+		evParts = evParts[1:]
+	}
+	if len(evParts) != 3 || evParts[0] != "cgroup" || evParts[1] == "" {
+		return Event{}, fmt.Errorf("malformed event-map %q", eventMap)
+	}
+
+	return Event{
+		ProgramID: programID,
+		Cgroup:    evParts[1],
+		ELFPath:   evParts[2],
+	}, nil
+}
+
 func parseEventMap(eventMaps []string) ([]Event, error) {
 	var events []Event
 	for _, eventMap := range eventMaps {
+		if isProcessSelector(eventMap) || isProcessSelector(eventMap[strings.Index(eventMap, ":")+1:]) {
+			event, err := parseSelectorEventMap(eventMap)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+			continue
+		}
+
 		evParts := strings.Split(eventMap, ":")
+		if len(evParts) > 2 && (evParts[0] == "cgroup" || evParts[1] == "cgroup") {
+			event, err := parseCgroupEventMap(eventMap, evParts)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+			continue
+		}
+
 		if len(evParts) > 3 {
 			return nil, fmt.Errorf("malformed event-map %q", eventMap)
 		}
@@ -29,8 +29,9 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Event struct {
-	Name string        `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
-	Args []*Event_Args `protobuf:"bytes,2,rep,name=args" json:"args,omitempty"`
+	Name        string        `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Args        []*Event_Args `protobuf:"bytes,2,rep,name=args" json:"args,omitempty"`
+	FieldNumber uint32        `protobuf:"varint,3,opt,name=fieldNumber" json:"fieldNumber,omitempty"`
 }
 
 func (m *Event) Reset()                    { *m = Event{} }
@@ -52,12 +53,20 @@ func (m *Event) GetArgs() []*Event_Args {
 	return nil
 }
 
+func (m *Event) GetFieldNumber() uint32 {
+	if m != nil {
+		return m.FieldNumber
+	}
+	return 0
+}
+
 type Event_Args struct {
 	Position uint32 `protobuf:"varint,1,opt,name=position" json:"position,omitempty"`
 	Type     string `protobuf:"bytes,2,opt,name=type" json:"type,omitempty"`
 	Name     string `protobuf:"bytes,3,opt,name=name" json:"name,omitempty"`
 	Suffix   string `protobuf:"bytes,4,opt,name=suffix" json:"suffix,omitempty"`
 	HashFunc string `protobuf:"bytes,5,opt,name=hashFunc" json:"hashFunc,omitempty"`
+	Kind     string `protobuf:"bytes,6,opt,name=kind" json:"kind,omitempty"`
 }
 
 func (m *Event_Args) Reset()                    { *m = Event_Args{} }
@@ -100,6 +109,13 @@ func (m *Event_Args) GetHashFunc() string {
 	return ""
 }
 
+func (m *Event_Args) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
 type Config struct {
 	Event []*Event `protobuf:"bytes,1,rep,name=event" json:"event,omitempty"`
 }
@@ -125,18 +141,20 @@ func init() {
 func init() { proto.RegisterFile("config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 208 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x49, 0xce, 0xcf, 0x4b,
-	0xcb, 0x4c, 0xd7, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x4c, 0x4f, 0xcd, 0x4b, 0x2d, 0x4a,
-	0x2c, 0xc9, 0x2f, 0x52, 0x3a, 0xc6, 0xc8, 0xc5, 0xea, 0x5a, 0x96, 0x9a, 0x57, 0x22, 0x24, 0xc4,
-	0xc5, 0x92, 0x97, 0x98, 0x9b, 0x2a, 0xc1, 0xa8, 0xc0, 0xa8, 0xc1, 0x19, 0x04, 0x66, 0x0b, 0x69,
-	0x72, 0xb1, 0x24, 0x16, 0xa5, 0x17, 0x4b, 0x30, 0x29, 0x30, 0x6b, 0x70, 0x1b, 0x89, 0xea, 0xc1,
-	0xf5, 0xe9, 0x81, 0xf5, 0xe8, 0x39, 0x16, 0xa5, 0x17, 0x07, 0x81, 0x95, 0x48, 0xd5, 0x71, 0xb1,
-	0x80, 0x78, 0x42, 0x52, 0x5c, 0x1c, 0x05, 0xf9, 0xc5, 0x99, 0x25, 0x99, 0xf9, 0x79, 0x60, 0xa3,
-	0x78, 0x83, 0xe0, 0x7c, 0x90, 0x15, 0x25, 0x95, 0x05, 0xa9, 0x12, 0x4c, 0x10, 0x2b, 0x40, 0x6c,
-	0xb8, 0xb5, 0xcc, 0x48, 0xd6, 0x8a, 0x71, 0xb1, 0x15, 0x97, 0xa6, 0xa5, 0x65, 0x56, 0x48, 0xb0,
-	0x80, 0x45, 0xa1, 0x3c, 0x90, 0xd9, 0x19, 0x89, 0xc5, 0x19, 0x6e, 0xa5, 0x79, 0xc9, 0x12, 0xac,
-	0x60, 0x19, 0x38, 0x5f, 0xc9, 0x80, 0x8b, 0xcd, 0x19, 0xec, 0x47, 0x21, 0x35, 0x2e, 0xd6, 0x54,
-	0x90, 0xeb, 0x24, 0x18, 0xc1, 0xae, 0x16, 0x40, 0x77, 0x75, 0x10, 0x44, 0x3a, 0x89, 0x0d, 0x1c,
-	0x18, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0x5b, 0x84, 0x79, 0xb8, 0x1c, 0x01, 0x00, 0x00,
+	// 238 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x50, 0x4d, 0x4b, 0x03, 0x31,
+	0x10, 0x25, 0xdd, 0x0f, 0xec, 0xd4, 0x82, 0x04, 0x94, 0xa1, 0xa7, 0xa5, 0x07, 0x59, 0x2f, 0x8b,
+	0xe8, 0x2f, 0x10, 0xd1, 0xa3, 0x87, 0xfc, 0x83, 0x6d, 0x3b, 0xbb, 0x0d, 0xda, 0x64, 0x49, 0xb2,
+	0xa2, 0x7f, 0xc3, 0xff, 0x2b, 0x48, 0x66, 0x25, 0x2c, 0xbd, 0xbd, 0x37, 0x93, 0xf7, 0xf2, 0xde,
+	0xc0, 0xe5, 0xde, 0x9a, 0x4e, 0xf7, 0xcd, 0xe0, 0x6c, 0xb0, 0x72, 0xd9, 0x93, 0x21, 0xd7, 0x06,
+	0xeb, 0xb6, 0xbf, 0x02, 0x8a, 0x97, 0x4f, 0x32, 0x41, 0x4a, 0xc8, 0x4d, 0x7b, 0x22, 0x14, 0x95,
+	0xa8, 0x97, 0x8a, 0xb1, 0xbc, 0x83, 0xbc, 0x75, 0xbd, 0xc7, 0x45, 0x95, 0xd5, 0xab, 0x87, 0xeb,
+	0x26, 0xe9, 0x1a, 0xd6, 0x34, 0x4f, 0xae, 0xf7, 0x8a, 0x9f, 0xc8, 0x0a, 0x56, 0x9d, 0xa6, 0x8f,
+	0xc3, 0xdb, 0x78, 0xda, 0x91, 0xc3, 0xac, 0x12, 0xf5, 0x5a, 0xcd, 0x47, 0x9b, 0x1f, 0x01, 0x79,
+	0x14, 0xc8, 0x0d, 0x5c, 0x0c, 0xd6, 0xeb, 0xa0, 0xad, 0xe1, 0xdf, 0xd6, 0x2a, 0xf1, 0x98, 0x22,
+	0x7c, 0x0f, 0x84, 0x8b, 0x29, 0x45, 0xc4, 0x29, 0x59, 0x36, 0x4b, 0x76, 0x03, 0xa5, 0x1f, 0xbb,
+	0x4e, 0x7f, 0x61, 0xce, 0xd3, 0x7f, 0x16, 0xbd, 0x8f, 0xad, 0x3f, 0xbe, 0x8e, 0x66, 0x8f, 0x05,
+	0x6f, 0x12, 0x8f, 0x3e, 0xef, 0xda, 0x1c, 0xb0, 0x9c, 0x7c, 0x22, 0xde, 0xde, 0x43, 0xf9, 0xcc,
+	0xa7, 0x91, 0xb7, 0x50, 0x50, 0x2c, 0x85, 0x82, 0xcb, 0x5e, 0x9d, 0x97, 0x55, 0xd3, 0x7a, 0x57,
+	0xf2, 0x0d, 0x1f, 0xff, 0x06, 0x00, 0x49, 0x5e, 0x1d, 0x52, 0x53, 0x01, 0x00, 0x00,
 }
@@ -22,7 +22,8 @@ func fileExists(path string) bool {
 	return true
 }
 
-func unmarshalConfig(path string) (*Config, error) {
+// ReadConfig reads a config in JSON format
+func ReadConfig(path string) (*Config, error) {
 	p := &Config{}
 	file, err := os.Open(path)
 	if err != nil {
@@ -39,8 +40,8 @@ func unmarshalConfig(path string) (*Config, error) {
 
 func buildSource(event *Event, tpl string, destDir string) error {
 	ev := Event{
-		event.Name,
-		event.Args,
+		Name: event.Name,
+		Args: event.Args,
 	}
 
 	tplText, err := ioutil.ReadFile(tpl)
@@ -84,7 +85,7 @@ func GenerateBpfSources(configPath string, tpl string, destDir string) error {
 	}
 
 	// Uses the PB config struct directly
-	config, err := unmarshalConfig(configPath)
+	config, err := ReadConfig(configPath)
 	if err != nil {
 		return fmt.Errorf("could not read config: %v", err)
 	}
@@ -1,25 +1,139 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
+	"github.com/ShiftLeftSecurity/traceleft/generator"
 	"github.com/ShiftLeftSecurity/traceleft/metagenerator"
 )
 
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-formats DIR|TARBALL] [-layout DIR|TARBALL] CONFIG_FILE GO_OUT_FILE PROTO_OUT_FILE H_OUT_FILE BPF_OUT_FILE\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s snapshot [-tracefs DIR] OUT_DIR|OUT_TARBALL\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s diff CONFIG_FILE DIR|TARBALL DIR|TARBALL\n", os.Args[0])
+}
+
+func readFormats(src string) metagenerator.SyscallFormats {
+	formats, err := metagenerator.ReadFormats(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading format files: %v\n", err)
+		os.Exit(1)
+	}
+	return formats
+}
+
+func readConfig(path string) *generator.Config {
+	config, err := generator.ReadConfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading config %q: %v\n", path, err)
+		os.Exit(1)
+	}
+	return config
+}
+
+// snapshot copies the format files of the syscalls of the running kernel, to
+// check the config against them later with -formats
+func snapshot(args []string) {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	tracefs := flags.String("tracefs", metagenerator.SyscallsPath, "directory of the syscall tracepoints")
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if err := metagenerator.Snapshot(*tracefs, flags.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "error taking snapshot: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// diff prints the differences between the generated structs of the events
+// with the layouts of two kernels, and exits with 1 if there are any
+func diff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 3 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	config := readConfig(flags.Arg(0))
+	a, b := flags.Arg(1), flags.Arg(2)
+	d, err := metagenerator.DiffLayouts(config, a, readFormats(a), b, readFormats(b))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	if d != "" {
+		fmt.Print(d)
+		os.Exit(1)
+	}
+}
+
 func main() {
-	if len(os.Args) != 4 {
-		fmt.Fprintf(os.Stderr, "usage: %s GO_OUT_FILE PROTO_OUT_FILE H_OUT_FILE\n", os.Args[0])
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "snapshot":
+			snapshot(os.Args[2:])
+			return
+		case "diff":
+			diff(os.Args[2:])
+			return
+		}
+	}
+
+	formats := flag.String("formats", "", "check the config against the format files of a kernel, in a directory (e.g. "+metagenerator.SyscallsPath+") or a tarball written by the snapshot command")
+	layout := flag.String("layout", "", "like -formats, and generate the structs with the types of the arguments in the format files instead of the ones of the config")
+	flag.Usage = func() {
+		usage()
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) != 5 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	goSyscalls, cSyscalls, protoSyscalls, err := metagenerator.GatherSyscalls()
+	config := readConfig(args[0])
+
+	if *formats != "" {
+		if err := metagenerator.CheckConfig(config, readFormats(*formats)); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *layout != "" {
+		var err error
+		config, err = metagenerator.ApplyFormats(config, readFormats(*layout))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	goSyscalls, cSyscalls, protoSyscalls, err := metagenerator.GatherSyscalls(config)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error gathering syscalls: %v\n", err)
 		os.Exit(1)
 	}
 
-	f, err := os.Create(os.Args[1])
+	f, err := os.Create(args[1])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error creating output file: %v\n", err)
 		os.Exit(1)
@@ -33,11 +147,11 @@ func main() {
 	}
 
 	if _, err := f.WriteString(goStructs); err != nil {
-		fmt.Fprintf(os.Stderr, "error writing to file %q: %v\n", os.Args[1], err)
+		fmt.Fprintf(os.Stderr, "error writing to file %q: %v\n", args[1], err)
 		os.Exit(1)
 	}
 
-	protof, err := os.Create(os.Args[2])
+	protof, err := os.Create(args[2])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error creating output file: %v\n", err)
 		os.Exit(1)
@@ -51,11 +165,11 @@ func main() {
 	}
 
 	if _, err := protof.WriteString(protoStructs); err != nil {
-		fmt.Fprintf(os.Stderr, "error writing to file %q: %v\n", os.Args[2], err)
+		fmt.Fprintf(os.Stderr, "error writing to file %q: %v\n", args[2], err)
 		os.Exit(1)
 	}
 
-	cf, err := os.Create(os.Args[3])
+	cf, err := os.Create(args[3])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error creating output file: %v\n", err)
 		os.Exit(1)
@@ -69,7 +183,25 @@ func main() {
 	}
 
 	if _, err := cf.WriteString(cStructs); err != nil {
-		fmt.Fprintf(os.Stderr, "error writing to file %q: %v\n", os.Args[2], err)
+		fmt.Fprintf(os.Stderr, "error writing to file %q: %v\n", args[3], err)
+		os.Exit(1)
+	}
+
+	bpff, err := os.Create(args[4])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+	defer bpff.Close()
+
+	bpfDispatch, err := metagenerator.GenerateBpfDispatch(cSyscalls)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error generating BPF dispatch: %v\n", err)
+		os.Exit(1)
+	}
+
+	if _, err := bpff.WriteString(bpfDispatch); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing to file %q: %v\n", args[4], err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,492 @@
+package metagenerator
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ShiftLeftSecurity/traceleft/generator"
+)
+
+// SyscallsPath is the tracefs directory of the syscall tracepoints, whose
+// sys_enter_*/format files describe the arguments of the syscalls of the
+// running kernel.
+const SyscallsPath = "/sys/kernel/debug/tracing/events/syscalls/"
+
+// SyscallArg is an argument of a syscall as described by its format file
+type SyscallArg struct {
+	Name string
+	Type string
+}
+
+// SyscallFormats maps the syscall names (e.g. "open") to their arguments
+type SyscallFormats map[string][]SyscallArg
+
+func isTarball(p string) bool {
+	return strings.HasSuffix(p, ".tar") || strings.HasSuffix(p, ".tar.gz") || strings.HasSuffix(p, ".tgz")
+}
+
+func isGzip(p string) bool {
+	return strings.HasSuffix(p, ".tar.gz") || strings.HasSuffix(p, ".tgz")
+}
+
+// formatSyscall returns the syscall described by the format file at path p,
+// which must end with sys_enter_NAME/format.
+func formatSyscall(p string) (string, bool) {
+	if path.Base(p) != "format" {
+		return "", false
+	}
+	dir := path.Base(path.Dir(p))
+	if !strings.HasPrefix(dir, "sys_enter_") {
+		return "", false
+	}
+	return strings.TrimPrefix(dir, "sys_enter_"), true
+}
+
+// ReadFormats reads the sys_enter_* format files from src, which is either a
+// directory (the tracefs one or a snapshot of it) or a tarball written by
+// Snapshot.
+func ReadFormats(src string) (SyscallFormats, error) {
+	if isTarball(src) {
+		return readFormatsTarball(src)
+	}
+	return readFormatsDir(src)
+}
+
+func readFormatsDir(dir string) (SyscallFormats, error) {
+	formatFiles, err := filepath.Glob(filepath.Join(dir, "sys_enter_*", "format"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing format files: %v", err)
+	}
+	if len(formatFiles) == 0 {
+		return nil, fmt.Errorf("no format files in %q", dir)
+	}
+
+	formats := make(SyscallFormats)
+	for _, formatFile := range formatFiles {
+		name, _ := formatSyscall(filepath.ToSlash(formatFile))
+		f, err := os.Open(formatFile)
+		if err != nil {
+			return nil, fmt.Errorf("error opening %q: %v", formatFile, err)
+		}
+		args, err := parseFormat(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %q: %v", formatFile, err)
+		}
+		formats[name] = args
+	}
+
+	return formats, nil
+}
+
+func readFormatsTarball(tarball string) (SyscallFormats, error) {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %q: %v", tarball, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if isGzip(tarball) {
+		gzr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %q: %v", tarball, err)
+		}
+		defer gzr.Close()
+		r = gzr
+	}
+
+	formats := make(SyscallFormats)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %q: %v", tarball, err)
+		}
+		name, ok := formatSyscall(hdr.Name)
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		args, err := parseFormat(tr)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %q in %q: %v", hdr.Name, tarball, err)
+		}
+		formats[name] = args
+	}
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("no format files in %q", tarball)
+	}
+
+	return formats, nil
+}
+
+// parseFormat returns the arguments of a syscall from its format file, whose
+// fields look like:
+//
+//	field:const char * filename;	offset:16;	size:8;	signed:0;
+//
+// The common_* fields and __syscall_nr are not arguments of the syscall.
+func parseFormat(r io.Reader) ([]SyscallArg, error) {
+	var args []SyscallArg
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "field:") {
+			continue
+		}
+		decl := strings.TrimPrefix(line, "field:")
+		if i := strings.Index(decl, ";"); i >= 0 {
+			decl = decl[:i]
+		}
+		i := strings.LastIndexAny(decl, " *")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid field %q", line)
+		}
+		arg := SyscallArg{
+			Name: decl[i+1:],
+			Type: strings.TrimSpace(decl[:i+1]),
+		}
+		if strings.HasPrefix(arg.Name, "common_") || arg.Name == "__syscall_nr" {
+			continue
+		}
+		args = append(args, arg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return args, nil
+}
+
+// CheckConfig checks the events of the config against the format files of a
+// kernel: the syscalls must exist, and the arguments must exist at their
+// position, with a pointer type for buffers.
+func CheckConfig(config *generator.Config, formats SyscallFormats) error {
+	var problems []string
+
+	for _, event := range config.Event {
+		args, ok := formats[event.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: no such syscall", event.Name))
+			continue
+		}
+		for _, arg := range event.Args {
+			if arg.Position < 1 || int(arg.Position) > len(args) {
+				problems = append(problems, fmt.Sprintf("%s: argument %q at position %d but the syscall has %d arguments",
+					event.Name, arg.Name, arg.Position, len(args)))
+				continue
+			}
+			kernelArg := args[arg.Position-1]
+			if kernelArg.Name != arg.Name {
+				problems = append(problems, fmt.Sprintf("%s: argument %d is %q, not %q",
+					event.Name, arg.Position, kernelArg.Name, arg.Name))
+			}
+			isPointer := strings.HasSuffix(kernelArg.Type, "*")
+			if arg.Suffix != "" && !isPointer {
+				problems = append(problems, fmt.Sprintf("%s: argument %q is a buffer but has type %q",
+					event.Name, arg.Name, kernelArg.Type))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("config doesn't match the format files:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+
+	return nil
+}
+
+// Snapshot copies the sys_enter_* and sys_exit_* format files of src (usually
+// SyscallsPath) to dst, a directory or a tarball depending on its name, so
+// that CheckConfig can be run without access to the kernel.
+func Snapshot(src, dst string) error {
+	formatFiles, err := filepath.Glob(filepath.Join(src, "sys_e*_*", "format"))
+	if err != nil {
+		return fmt.Errorf("error listing format files: %v", err)
+	}
+	if len(formatFiles) == 0 {
+		return fmt.Errorf("no format files in %q", src)
+	}
+	sort.Strings(formatFiles)
+
+	if isTarball(dst) {
+		return snapshotTarball(src, formatFiles, dst)
+	}
+	return snapshotDir(src, formatFiles, dst)
+}
+
+func snapshotDir(src string, formatFiles []string, dst string) error {
+	for _, formatFile := range formatFiles {
+		rel, err := filepath.Rel(src, formatFile)
+		if err != nil {
+			return err
+		}
+		// tracefs reports a size of 0, read the whole file instead of
+		// copying st_size bytes
+		data, err := ioutil.ReadFile(formatFile)
+		if err != nil {
+			return fmt.Errorf("error reading %q: %v", formatFile, err)
+		}
+		out := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return fmt.Errorf("error creating directory: %v", err)
+		}
+		if err := ioutil.WriteFile(out, data, 0644); err != nil {
+			return fmt.Errorf("error writing %q: %v", out, err)
+		}
+	}
+
+	return nil
+}
+
+func snapshotTarball(src string, formatFiles []string, dst string) error {
+	buf := new(bytes.Buffer)
+
+	var w io.Writer = buf
+	var gzw *gzip.Writer
+	if isGzip(dst) {
+		gzw = gzip.NewWriter(buf)
+		w = gzw
+	}
+
+	tw := tar.NewWriter(w)
+	for _, formatFile := range formatFiles {
+		rel, err := filepath.Rel(src, formatFile)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(formatFile)
+		if err != nil {
+			return fmt.Errorf("error reading %q: %v", formatFile, err)
+		}
+		hdr := &tar.Header{
+			Name:     filepath.ToSlash(rel),
+			Mode:     0644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("error writing tarball: %v", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("error writing tarball: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error writing tarball: %v", err)
+	}
+	if gzw != nil {
+		if err := gzw.Close(); err != nil {
+			return fmt.Errorf("error writing tarball: %v", err)
+		}
+	}
+
+	if err := ioutil.WriteFile(dst, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing %q: %v", dst, err)
+	}
+
+	return nil
+}
+
+// kernelTypes maps the types of the format files that aren't in
+// goTypeConversions to ones that are. The BPF programs copy the whole
+// registers of the arguments, so types narrower than 32 bits are widened.
+var kernelTypes = map[string]string{
+	"short":             "s32",
+	"unsigned short":    "u32",
+	"umode_t":           "u32",
+	"qid_t":             "u32",
+	"clockid_t":         "int",
+	"key_serial_t":      "int",
+	"key_t":             "int",
+	"mqd_t":             "int",
+	"rwf_t":             "int",
+	"timer_t":           "int",
+	"off_t":             "long",
+	"aio_context_t":     "unsigned long",
+	"cap_user_data_t":   "unsigned long",
+	"cap_user_header_t": "unsigned long",
+	"unsigned":          "unsigned int",
+}
+
+// kernelType returns the config type of an argument of the given type in a
+// format file. Pointers are addresses.
+func kernelType(t string) (string, bool) {
+	if strings.HasSuffix(t, "*") {
+		return "unsigned long", true
+	}
+	t = strings.TrimPrefix(t, "const ")
+	if _, ok := goTypeConversions[t]; ok {
+		return t, true
+	}
+	t, ok := kernelTypes[t]
+	return t, ok
+}
+
+// ApplyFormats returns a copy of config whose arguments have the types they
+// have in the format files of a kernel, for the generated structs to have the
+// layout of this kernel instead of the one of the config. The config must
+// match the format files (see CheckConfig). Buffers keep the type and size of
+// the config, the format files only say they are pointers.
+func ApplyFormats(config *generator.Config, formats SyscallFormats) (*generator.Config, error) {
+	if err := CheckConfig(config, formats); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+
+	layout := &generator.Config{}
+	for _, event := range config.Event {
+		e := *event
+		e.Args = nil
+		for _, arg := range event.Args {
+			a := *arg
+			kernelArg := formats[event.Name][arg.Position-1]
+			if arg.Type != "char" {
+				t, ok := kernelType(kernelArg.Type)
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s: argument %q has unsupported type %q",
+						event.Name, arg.Name, kernelArg.Type))
+				}
+				a.Type = t
+			}
+			e.Args = append(e.Args, &a)
+		}
+		layout.Event = append(layout.Event, &e)
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("unsupported types in the format files:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+
+	return layout, nil
+}
+
+// goStructs returns the fields of the generated Go structs of the events, one
+// per line, by event name. The padding the C compiler adds before the fields,
+// skipped when decoding, is shown as _ fields.
+func goStructs(config *generator.Config) (map[string][]string, error) {
+	goSyscalls, _, _, err := GatherSyscalls(config)
+	if err != nil {
+		return nil, err
+	}
+
+	structs := make(map[string][]string)
+	for _, sc := range goSyscalls {
+		var fields []string
+		for _, p := range sc.Params {
+			if p.Padding != 0 {
+				fields = append(fields, fmt.Sprintf("_ [%d]byte", p.Padding))
+			}
+			fields = append(fields, fmt.Sprintf("%s %s", p.Name, p.Type))
+		}
+		structs[sc.Name] = fields
+	}
+	return structs, nil
+}
+
+// DiffLayouts returns the differences between the generated Go structs of the
+// events of config with the layouts of two kernels (see ApplyFormats), in the
+// format of a unified diff without line numbers. It's empty if the structs
+// are the same.
+func DiffLayouts(config *generator.Config, nameA string, a SyscallFormats, nameB string, b SyscallFormats) (string, error) {
+	layoutA, err := ApplyFormats(config, a)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", nameA, err)
+	}
+	layoutB, err := ApplyFormats(config, b)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", nameB, err)
+	}
+	structsA, err := goStructs(layoutA)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", nameA, err)
+	}
+	structsB, err := goStructs(layoutB)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", nameB, err)
+	}
+
+	names := make([]string, 0, len(structsA))
+	for name := range structsA {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		lines := diffLines(structsA[name], structsB[name])
+		if lines == nil {
+			continue
+		}
+		if buf.Len() == 0 {
+			fmt.Fprintf(buf, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		fmt.Fprintf(buf, " type %s struct {\n", name)
+		for _, l := range lines {
+			fmt.Fprintf(buf, "%s\n", l)
+		}
+		fmt.Fprintf(buf, " }\n")
+	}
+	return buf.String(), nil
+}
+
+// diffLines returns the lines of a and b prefixed with " " if they're in
+// both, "-" if they're only in a and "+" if they're only in b, following
+// their longest common subsequence, or nil if a and b are the same
+func diffLines(a, b []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	if lcs[0][0] == len(a) && len(a) == len(b) {
+		return nil
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " \t"+a[i])
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "-\t"+a[i])
+			i++
+		default:
+			lines = append(lines, "+\t"+b[j])
+			j++
+		}
+	}
+	return lines
+}
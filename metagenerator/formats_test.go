@@ -0,0 +1,216 @@
+package metagenerator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ShiftLeftSecurity/traceleft/generator"
+)
+
+// writeFormats writes the format files of syscalls, given as the declarations
+// of their arguments, in a tracefs-like directory
+func writeFormats(t *testing.T, dir string, syscalls map[string][]string) {
+	for name, args := range syscalls {
+		var b strings.Builder
+		fmt.Fprintf(&b, "name: sys_enter_%s\nID: 1\nformat:\n", name)
+		b.WriteString("\tfield:unsigned short common_type;\toffset:0;\tsize:2;\tsigned:0;\n")
+		b.WriteString("\tfield:int common_pid;\toffset:4;\tsize:4;\tsigned:1;\n\n")
+		b.WriteString("\tfield:int __syscall_nr;\toffset:8;\tsize:4;\tsigned:1;\n")
+		for i, arg := range args {
+			fmt.Fprintf(&b, "\tfield:%s;\toffset:%d;\tsize:8;\tsigned:0;\n", arg, 16+8*i)
+		}
+		b.WriteString("\nprint fmt: \"\"\n")
+
+		d := filepath.Join(dir, "sys_enter_"+name)
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(d, "format"), []byte(b.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testConfig() *generator.Config {
+	return &generator.Config{Event: []*generator.Event{
+		{Name: "open", FieldNumber: 14, Args: []*generator.Event_Args{
+			{Position: 1, Type: "char", Name: "filename", Suffix: "[256]"},
+			{Position: 2, Type: "s64", Name: "flags", Kind: "open_flags"},
+			{Position: 3, Type: "u64", Name: "mode", Kind: "mode"},
+		}},
+		{Name: "close", FieldNumber: 7, Args: []*generator.Event_Args{
+			{Position: 1, Type: "u64", Name: "fd", Kind: "fd"},
+		}},
+	}}
+}
+
+func TestReadFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "formats-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tracefs := filepath.Join(dir, "tracefs")
+	writeFormats(t, tracefs, map[string][]string{
+		"open":  {"const char * filename", "int flags", "umode_t mode"},
+		"close": {"unsigned int fd"},
+	})
+	want := SyscallFormats{
+		"open":  {{"filename", "const char *"}, {"flags", "int"}, {"mode", "umode_t"}},
+		"close": {{"fd", "unsigned int"}},
+	}
+
+	for _, snapshot := range []string{"", "snapshot", "snapshot.tar", "snapshot.tar.gz"} {
+		src := tracefs
+		if snapshot != "" {
+			src = filepath.Join(dir, snapshot)
+			if err := Snapshot(tracefs, src); err != nil {
+				t.Fatalf("%s: %v", snapshot, err)
+			}
+		}
+		formats, err := ReadFormats(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if !reflect.DeepEqual(formats, want) {
+			t.Errorf("%s: got %v, want %v", src, formats, want)
+		}
+	}
+
+	if _, err := ReadFormats(filepath.Join(dir, "nonexistent")); err == nil {
+		t.Errorf("expected an error reading formats from a nonexistent directory")
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	formats := SyscallFormats{
+		"open":  {{"filename", "const char *"}, {"flags", "int"}, {"mode", "umode_t"}},
+		"close": {{"fd", "unsigned int"}},
+	}
+	if err := CheckConfig(testConfig(), formats); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	formats = SyscallFormats{
+		"open": {{"filename", "int"}, {"mode", "umode_t"}},
+	}
+	err := CheckConfig(testConfig(), formats)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, problem := range []string{
+		"close: no such syscall",
+		`open: argument "filename" is a buffer but has type "int"`,
+		`open: argument 2 is "mode", not "flags"`,
+		`open: argument "mode" at position 3 but the syscall has 2 arguments`,
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("error %q doesn't contain %q", err, problem)
+		}
+	}
+}
+
+func TestApplyFormats(t *testing.T) {
+	formats := SyscallFormats{
+		"open":  {{"filename", "const char *"}, {"flags", "int"}, {"mode", "umode_t"}},
+		"close": {{"fd", "unsigned int"}},
+	}
+	config := testConfig()
+	layout, err := ApplyFormats(config, formats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, event := range layout.Event {
+		for _, arg := range event.Args {
+			types = append(types, event.Name+"."+arg.Name+":"+arg.Type+arg.Suffix)
+		}
+	}
+	want := []string{"open.filename:char[256]", "open.flags:int", "open.mode:u32", "close.fd:unsigned int"}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("got types %v, want %v", types, want)
+	}
+	if config.Event[0].Args[1].Type != "s64" {
+		t.Errorf("the config was modified")
+	}
+	if _, _, _, err := GatherSyscalls(layout); err != nil {
+		t.Errorf("error generating from the layout: %v", err)
+	}
+
+	formats["close"] = []SyscallArg{{"fd", "struct file_handle"}}
+	if _, err := ApplyFormats(config, formats); err == nil || !strings.Contains(err.Error(), `close: argument "fd" has unsupported type "struct file_handle"`) {
+		t.Errorf("got error %v, want an unsupported type", err)
+	}
+	formats["close"] = []SyscallArg{{"fildes", "unsigned int"}}
+	if _, err := ApplyFormats(config, formats); err == nil || !strings.Contains(err.Error(), "doesn't match") {
+		t.Errorf("got error %v, want a mismatch", err)
+	}
+}
+
+func TestDiffLayouts(t *testing.T) {
+	a := SyscallFormats{
+		"open":  {{"filename", "const char *"}, {"flags", "int"}, {"mode", "umode_t"}},
+		"close": {{"fd", "unsigned int"}},
+	}
+	b := SyscallFormats{
+		"open":  {{"filename", "const char *"}, {"flags", "int"}, {"mode", "unsigned long"}},
+		"close": {{"fd", "unsigned int"}},
+	}
+
+	d, err := DiffLayouts(testConfig(), "a", a, "a", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != "" {
+		t.Errorf("got differences between the same layouts:\n%s", d)
+	}
+
+	d, err = DiffLayouts(testConfig(), "a", a, "b", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the wider mode is aligned on 8 bytes
+	want := "--- a\n+++ b\n" +
+		" type OpenEvent struct {\n" +
+		" \tFilename [256]byte\n" +
+		" \tFlags int32\n" +
+		"-\tMode uint32\n" +
+		"+\t_ [4]byte\n" +
+		"+\tMode uint64\n" +
+		" }\n"
+	if d != want {
+		t.Errorf("got diff:\n%s\nwant:\n%s", d, want)
+	}
+
+	delete(b, "close")
+	if _, err := DiffLayouts(testConfig(), "a", a, "b", b); err == nil || !strings.HasPrefix(err.Error(), "b: ") {
+		t.Errorf("got error %v, want an error about b", err)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want []string
+	}{
+		{nil, nil, nil},
+		{[]string{"x", "y"}, []string{"x", "y"}, nil},
+		{[]string{"x"}, nil, []string{"-\tx"}},
+		{nil, []string{"x"}, []string{"+\tx"}},
+		{[]string{"x", "y", "z"}, []string{"x", "z"}, []string{" \tx", "-\ty", " \tz"}},
+		{[]string{"x", "z"}, []string{"x", "y", "z"}, []string{" \tx", "+\ty", " \tz"}},
+	}
+	for _, tt := range tests {
+		if got := diffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("diffLines(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -6,15 +6,13 @@ package metagenerator
 import (
 	"bytes"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
 	"text/template"
-)
 
-const syscallsPath = `/sys/kernel/debug/tracing/events/syscalls/`
+	"github.com/ShiftLeftSecurity/traceleft/generator"
+)
 
 const headers = `
 // Generated file, do not edit.
@@ -33,7 +31,9 @@ package tracer
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"path/filepath"
@@ -51,6 +51,13 @@ type FdInfo struct {
 	Ino   uint64
 	Major uint64
 	Minor uint64
+
+	// protocol and addresses of a socket, once resolved (see ResolveSocket)
+	Socket string
+	// if not 0, Socket may still change (e.g. the socket isn't connected
+	// yet, or wasn't found) and is looked up again after this time, in
+	// nanoseconds since the epoch
+	SocketRetry int64
 }
 
 // Pid -> Fd -> FdInfo
@@ -105,6 +112,87 @@ func (f *FdMap) DeletePid(pid uint32) {
 	delete(f.items, pid)
 }
 
+// CopyPid copies the entries of a process to another one, e.g. to a child
+// inheriting the file descriptors of its parent
+func (f *FdMap) CopyPid(from, to uint32) {
+	f.Lock()
+	defer f.Unlock()
+
+	inner, ok := f.items[from]
+	if !ok {
+		return
+	}
+
+	copied := make(map[uint32]FdInfo, len(inner))
+	for fd, info := range inner {
+		copied[fd] = info
+	}
+	f.items[to] = copied
+}
+
+// Copy copies the entry of a file descriptor to another one of the same
+// process, e.g. on dup2(2)
+func (f *FdMap) Copy(pid, from, to uint32) {
+	f.Lock()
+	defer f.Unlock()
+
+	inner, ok := f.items[pid]
+	if !ok {
+		return
+	}
+
+	info, ok := inner[from]
+	if !ok {
+		// whatever was open at to was closed
+		delete(inner, to)
+		return
+	}
+	inner[to] = info
+}
+
+// ScanPid adds the file descriptors currently open by a process, read from
+// /proc, e.g. the ones it inherited or opened before being traced
+func (f *FdMap) ScanPid(pid uint32) error {
+	fdDir := fmt.Sprintf("/proc/%d/fd", pid)
+	d, err := os.Open(fdDir)
+	if err != nil {
+		return err
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		fd, err := strconv.ParseUint(name, 10, 32)
+		if err != nil {
+			continue
+		}
+		path, err := procLookupPath(pid, uint32(fd))
+		// ignore entries not backed by files or sockets, like anonymous inodes
+		if err != nil || !strings.HasPrefix(path, "/") && !isSocketPath(path) {
+			continue
+		}
+		var stat syscall.Stat_t
+		if err := syscall.Stat(filepath.Join(fdDir, name), &stat); err != nil {
+			continue
+		}
+		major, minor := devNumbers(uint64(stat.Dev))
+		f.Put(pid, uint32(fd), FdInfo{Path: path, Ino: stat.Ino, Major: major, Minor: minor})
+	}
+
+	return nil
+}
+
+// devNumbers returns the major and minor numbers of a device, as the kernel
+// reports them in fd_install events
+func devNumbers(dev uint64) (major, minor uint64) {
+	major = (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor = dev&0xff | (dev>>12)&^0xff
+	return major, minor
+}
+
 func (f *FdMap) Clear() {
 	f.Lock()
 	defer f.Unlock()
@@ -113,7 +201,14 @@ func (f *FdMap) Clear() {
 }
 
 type Context struct {
-	Fds *FdMap
+	Fds       *FdMap
+	Processes *ProcessCache
+
+	// when recording a capture, what is read from /proc to decode events is
+	// also written to Capture; when replaying one, it is read from Replay
+	// instead of /proc (see capture-state.go)
+	Capture *CaptureWriter
+	Replay  *ReplayState
 }
 
 // kernel structures
@@ -265,6 +360,23 @@ func (e FileEvent) GetArgN(n int, ret int64) (string, error) {
 	return "", fmt.Errorf("FileEvent.GetArgN not implemented")
 }
 
+func (e FileEvent) GetArg(name string, ret int64) (string, error) {
+	return getArg("FileEvent", e.Args(ret), name)
+}
+
+func (e FileEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Fd", Value: e.Fd},
+		{Name: "Ino", Value: e.Ino},
+		{Name: "Major", Value: e.Major},
+		{Name: "Minor", Value: e.Minor},
+	}
+}
+
+func (e FileEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 // FileEvent is not meant to be seen by the users
 func (e FileEvent) Metric() *Metric {
 	return nil
@@ -276,266 +388,33 @@ func (e FileEvent) Metric() *Metric {
 const maxBufferSize = 256
 
 var (
-	protoTypeConversions = map[string]string{
-		"BpfAttr":            "todo",
-		"CapUserData":        "todo",
-		"CapUserHeader":      "todo",
-		"FileHandle":         "todo",
-		"GetCPUCache":        "todo",
-		"IoCb":               "todo",
-		"IoEvent":            "todo",
-		"Itimerspec":         "todo",
-		"KexecSegment":       "todo",
-		"MqAttr":             "todo",
-		"Msgbuf":             "todo",
-		"Pollfd":             "todo",
-		"RobustListHead":     "todo",
-		"SigSet":             "todo",
-		"Sigaction":          "todo",
-		"Sigevent":           "todo",
-		"Stack":              "todo",
-		"SysctlArgs":         "todo",
-		"Timezone":           "todo",
-		"[256]byte":          "bytes",
-		"[]IoCb":             "todo",
-		"[]RobustListHead":   "todo",
-		"int16":              "int32",
-		"int32":              "int32",
-		"int64":              "int64",
-		"syscall.Dirent":     "todo",
-		"syscall.EpollEvent": "todo",
-		"syscall.FdSet":      "todo",
-		"syscall.Iovec":      "todo",
-		"syscall.Msghdr":     "todo",
-		"syscall.Rlimit":     "todo",
-		"syscall.Rusage":     "todo",
-		"syscall.Sockaddr":   "todo",
-		"syscall.Stat_t":     "todo",
-		"syscall.Statfs_t":   "todo",
-		"syscall.Sysinfo_t":  "todo",
-		"syscall.Timespec":   "todo",
-		"syscall.Timeval":    "todo",
-		"syscall.Timex":      "todo",
-		"syscall.Tms":        "todo",
-		"syscall.Ustat_t":    "todo",
-		"syscall.Utimbuf":    "todo",
-		"syscall.Utsname":    "todo",
-		"uint16":             "uint32",
-		"uint32":             "uint32",
-		"uint64":             "uint64",
-		"unsafe.Pointer":     "todo",
+	// Go types of the C types of the syscall arguments in the config.
+	// Buffers ("char" with a "[size]" suffix) are handled separately.
+	goTypeConversions = map[string]string{
+		"int":           "int32",
+		"int32_t":       "int32",
+		"pid_t":         "int32",
+		"s32":           "int32",
+		"gid_t":         "uint32",
+		"u32":           "uint32",
+		"uid_t":         "uint32",
+		"uint32_t":      "uint32",
+		"unsigned int":  "uint32",
+		"int64_t":       "int64",
+		"loff_t":        "int64",
+		"long":          "int64",
+		"s64":           "int64",
+		"size_t":        "uint64",
+		"u64":           "uint64",
+		"uint64_t":      "uint64",
+		"unsigned long": "uint64",
 	}
 
-	goTypeConversions = map[string]string{
-		"aio_context_t *":             "uint64",
-		"aio_context_t":               "uint64",
-		"cap_user_data_t":             "CapUserData",
-		"cap_user_header_t":           "CapUserHeader",
-		"char *":                      fmt.Sprintf("[%d]byte", maxBufferSize),
-		"const cap_user_data_t":       "CapUserData",
-		"const char *":                fmt.Sprintf("[%d]byte", maxBufferSize),
-		"const clockid_t":             "uint32",
-		"const int *":                 "int64",
-		"const sigset_t *":            "SigSet",
-		"const stack_t *":             "Stack",
-		"const struct iovec *":        "syscall.Iovec",
-		"const struct itimerspec *":   "Itimerspec",
-		"const struct mq_attr *":      "MqAttr",
-		"const struct rlimit64 *":     "syscall.Rlimit",
-		"const struct sigaction *":    "Sigaction",
-		"const struct sigevent *":     "Sigevent",
-		"const struct timespec *":     "syscall.Timespec",
-		"const unsigned long *":       "uint64",
-		"const void * *":              "unsafe.Pointer",
-		"const void *":                "unsafe.Pointer",
-		"fd_set *":                    "syscall.FdSet",
-		"gid_t *":                     "uint64",
-		"gid_t":                       "uint32",
-		"int *":                       "int64",
-		"int":                         "int64",
-		"key_serial_t":                "int32",
-		"key_t":                       "int64",
-		"loff_t *":                    "int64",
-		"loff_t":                      "int64",
-		"long":                        "int64",
-		"mqd_t":                       "int64",
-		"off_t":                       "int64",
-		"pid_t":                       "int64",
-		"qid_t":                       "uint32",
-		"__s32":                       "uint32",
-		"siginfo_t *":                 "unsafe.Pointer", // unknown
-		"sigset_t *":                  "SigSet",
-		"size_t *":                    "int64",
-		"size_t":                      "int64",
-		"stack_t *":                   "Stack",
-		"struct epoll_event *":        "syscall.EpollEvent",
-		"struct file_handle *":        "FileHandle",
-		"struct getcpu_cache *":       "GetCPUCache",
-		"struct iocb * *":             "IoCb",
-		"struct iocb *":               "[]IoCb",
-		"struct io_event *":           "IoEvent",
-		"struct itimerspec *":         "Itimerspec",
-		"struct itimerval *":          "Itimerspec",
-		"struct kexec_segment *":      "KexecSegment",
-		"struct linux_dirent64 *":     "syscall.Dirent",
-		"struct linux_dirent *":       "syscall.Dirent",
-		"struct mmsghdr *":            "syscall.Msghdr",
-		"struct mq_attr *":            "MqAttr",
-		"struct msgbuf *":             "Msgbuf",
-		"struct msqid_ds *":           "unsafe.Pointer", // obsolete
-		"struct new_utsname *":        "syscall.Utsname",
-		"struct perf_event_attr *":    "unsafe.Pointer", // too big
-		"struct pollfd *":             "Pollfd",
-		"struct rlimit64 *":           "syscall.Rlimit",
-		"struct rlimit *":             "syscall.Rlimit",
-		"struct robust_list_head * *": "[]RobustListHead",
-		"struct robust_list_head *":   "RobustListHead",
-		"struct rusage *":             "syscall.Rusage",
-		"struct sched_attr *":         "unsafe.Pointer", // unknown
-		"struct sched_param *":        "unsafe.Pointer", // unknown
-		"struct sembuf *":             "unsafe.Pointer", // unknown
-		"struct shmid_ds *":           "unsafe.Pointer", // unknown
-		"struct sigaction *":          "unsafe.Pointer", // unknown
-		"struct sigevent *":           "unsafe.Pointer", // unknown
-		"struct siginfo *":            "unsafe.Pointer", // unknown
-		"struct sockaddr *":           "syscall.Sockaddr",
-		"struct stat *":               "syscall.Stat_t",
-		"struct statfs *":             "syscall.Statfs_t",
-		"struct __sysctl_args *":      "SysctlArgs",
-		"struct sysinfo *":            "syscall.Sysinfo_t",
-		"struct timespec *":           "syscall.Timespec",
-		"struct timeval *":            "syscall.Timeval",
-		"struct timex *":              "syscall.Timex",
-		"struct timezone *":           "Timezone",
-		"struct tms *":                "syscall.Tms",
-		"struct user_msghdr *":        "unsafe.Pointer", // unknown
-		"struct ustat *":              "syscall.Ustat_t",
-		"struct utimbuf *":            "syscall.Utimbuf",
-		"timer_t *":                   "int64",
-		"timer_t":                     "int64",
-		"time_t *":                    "int64",
-		"u32 *":                       "uint32",
-		"u32":                         "uint32",
-		"u64":                         "uint64",
-		"__u64":                       "uint64",
-		"uid_t *":                     "uint64",
-		"uid_t":                       "uint32",
-		"umode_t":                     "uint64",
-		"union bpf_attr *":            "BpfAttr",
-		"unsigned char *":             fmt.Sprintf("[%d]byte", maxBufferSize),
-		"unsigned *":                  "uint64",
-		"unsigned":                    "uint64",
-		"unsigned int *":              "uint64",
-		"unsigned int":                "uint64",
-		"unsigned long *":             "uint64",
-		"unsigned long":               "uint64",
-		"void *":                      "unsafe.Pointer",
-	}
-
-	cTypeConversions = map[string]string{
-		"aio_context_t *":             "u64",
-		"aio_context_t":               "aio_context_t",
-		"cap_user_data_t":             "cap_user_data_t",
-		"cap_user_header_t":           "cap_user_header_t",
-		"char *":                      "char",
-		"const cap_user_data_t":       "cap_user_data_t",
-		"const char *":                "char",
-		"const clockid_t":             "u32",
-		"const int *":                 "s64",
-		"const sigset_t *":            "u64",
-		"const stack_t *":             "u64",
-		"const struct iovec *":        "u64",
-		"const struct itimerspec *":   "u64",
-		"const struct mq_attr *":      "u64",
-		"const struct rlimit64 *":     "u64",
-		"const struct sigaction *":    "u64",
-		"const struct sigevent *":     "u64",
-		"const struct timespec *":     "u64",
-		"const unsigned long *":       "u64",
-		"const void * *":              "u64",
-		"const void *":                "u64",
-		"fd_set *":                    "u64",
-		"gid_t *":                     "u64",
-		"gid_t":                       "gid_t",
-		"int *":                       "u64",
-		"int":                         "s64",
-		"key_serial_t":                "key_serial_t",
-		"key_t":                       "key_t",
-		"loff_t *":                    "s64",
-		"loff_t":                      "loff_t",
-		"long":                        "s64",
-		"mqd_t":                       "mdq_t",
-		"off_t":                       "off_t",
-		"pid_t":                       "pid_t",
-		"qid_t":                       "qid_t",
-		"__s32":                       "s32",
-		"siginfo_t *":                 "u64",
-		"sigset_t *":                  "u64",
-		"size_t *":                    "u64",
-		"size_t":                      "int64_t", // varies in kernel
-		"stack_t *":                   "u64",
-		"struct epoll_event *":        "u64",
-		"struct file_handle *":        "u64",
-		"struct getcpu_cache *":       "u64",
-		"struct iocb * *":             "u64",
-		"struct iocb *":               "u64",
-		"struct io_event *":           "u64",
-		"struct itimerspec *":         "u64",
-		"struct itimerval *":          "u64",
-		"struct kexec_segment *":      "u64",
-		"struct linux_dirent64 *":     "u64",
-		"struct linux_dirent *":       "u64",
-		"struct mmsghdr *":            "u64",
-		"struct mq_attr *":            "u64",
-		"struct msgbuf *":             "u64",
-		"struct msqid_ds *":           "u64",
-		"struct new_utsname *":        "u64",
-		"struct perf_event_attr *":    "u64",
-		"struct pollfd *":             "u64",
-		"struct rlimit64 *":           "u64",
-		"struct rlimit *":             "u64",
-		"struct robust_list_head * *": "u64",
-		"struct robust_list_head *":   "u64",
-		"struct rusage *":             "u64",
-		"struct sched_attr *":         "u64",
-		"struct sched_param *":        "u64",
-		"struct sembuf *":             "u64",
-		"struct shmid_ds *":           "u64",
-		"struct sigaction *":          "u64",
-		"struct sigevent *":           "u64",
-		"struct siginfo *":            "u64",
-		"struct sockaddr *":           "u64",
-		"struct stat *":               "u64",
-		"struct statfs *":             "u64",
-		"struct __sysctl_args *":      "u64",
-		"struct sysinfo *":            "u64",
-		"struct timespec *":           "u64",
-		"struct timeval *":            "u64",
-		"struct timex *":              "u64",
-		"struct timezone *":           "u64",
-		"struct tms *":                "u64",
-		"struct user_msghdr *":        "u64",
-		"struct ustat *":              "u64",
-		"struct utimbuf *":            "u64",
-		"timer_t *":                   "u64",
-		"timer_t":                     "timer_t",
-		"time_t *":                    "u64",
-		"u32 *":                       "s64",
-		"u32":                         "u32",
-		"u64":                         "u64",
-		"__u64":                       "u64",
-		"uid_t *":                     "u64",
-		"uid_t":                       "uid_t",
-		"umode_t":                     "u64",
-		"union bpf_attr *":            "u64",
-		"unsigned char *":             "char",
-		"unsigned *":                  "u64",
-		"unsigned":                    "unsigned",
-		"unsigned int *":              "u64",
-		"unsigned int":                "u64",
-		"unsigned long *":             "u64",
-		"unsigned long":               "unsigned long",
-		"void *":                      "u64",
+	protoTypeConversions = map[string]string{
+		"int32":  "int32",
+		"int64":  "int64",
+		"uint32": "uint32",
+		"uint64": "uint64",
 	}
 )
 
@@ -574,10 +453,46 @@ func (e ConnectV4Event) GetArgN(n int, ret int64) (string, error) {
 	return "", fmt.Errorf("ConnectV4Event.GetArgN not implemented")
 }
 
+func (e ConnectV4Event) GetArg(name string, ret int64) (string, error) {
+	return getArg("ConnectV4Event", e.Args(ret), name)
+}
+
 func (e ConnectV6Event) GetArgN(n int, ret int64) (string, error) {
 	return "", fmt.Errorf("ConnectV6Event.GetArgN not implemented")
 }
 
+func (e ConnectV6Event) GetArg(name string, ret int64) (string, error) {
+	return getArg("ConnectV6Event", e.Args(ret), name)
+}
+
+func (e ConnectV4Event) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Saddr", Value: inet_ntoa(e.Saddr)},
+		{Name: "Daddr", Value: inet_ntoa(e.Daddr)},
+		{Name: "Sport", Value: e.Sport},
+		{Name: "Dport", Value: e.Dport},
+		{Name: "Netns", Value: e.Netns},
+	}
+}
+
+func (e ConnectV6Event) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Saddr", Value: inet_ntoa6(e.Saddr)},
+		{Name: "Daddr", Value: inet_ntoa6(e.Daddr)},
+		{Name: "Sport", Value: e.Sport},
+		{Name: "Dport", Value: e.Dport},
+		{Name: "Netns", Value: e.Netns},
+	}
+}
+
+func (e ConnectV4Event) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+func (e ConnectV6Event) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 
 func (e ConnectV4Event) Metric() *Metric {
 	return &Metric{
@@ -625,12 +540,92 @@ type FileEvent struct {
 }
 `
 
+const processTemplate = `
+// process events struct
+
+type ForkEvent struct {
+	ChildPid uint64
+}
+
+func (e ForkEvent) String(ret int64) string {
+	return fmt.Sprintf("ChildPid %d ", e.ChildPid)
+}
+
+func (e ForkEvent) GetArgN(n int, ret int64) (string, error) {
+	switch n {
+	case 0: // ChildPid: type uint64
+		return fmt.Sprintf("%v", e.ChildPid), nil
+	default:
+		return "", fmt.Errorf("Event ForkEvent does not have argument %d", n)
+	}
+}
+
+func (e ForkEvent) GetArg(name string, ret int64) (string, error) {
+	return getArg("ForkEvent", e.Args(ret), name)
+}
+
+func (e ForkEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "ChildPid", Value: e.ChildPid},
+	}
+}
+
+func (e ForkEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+// ForkEvent is not sent to collectors
+func (e ForkEvent) Metric() *Metric {
+	return nil
+}
+
+type ExitEvent struct {
+	ExitCode int64
+	Signal   int64
+}
+
+func (e ExitEvent) String(ret int64) string {
+	return fmt.Sprintf("ExitCode %d Signal %d ", e.ExitCode, e.Signal)
+}
+
+func (e ExitEvent) GetArgN(n int, ret int64) (string, error) {
+	switch n {
+	case 0: // ExitCode: type int64
+		return fmt.Sprintf("%v", e.ExitCode), nil
+	case 1: // Signal: type int64
+		return fmt.Sprintf("%v", e.Signal), nil
+	default:
+		return "", fmt.Errorf("Event ExitEvent does not have argument %d", n)
+	}
+}
+
+func (e ExitEvent) GetArg(name string, ret int64) (string, error) {
+	return getArg("ExitEvent", e.Args(ret), name)
+}
+
+func (e ExitEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "ExitCode", Value: e.ExitCode},
+		{Name: "Signal", Value: e.Signal},
+	}
+}
+
+func (e ExitEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+// ExitEvent is not sent to collectors
+func (e ExitEvent) Metric() *Metric {
+	return nil
+}
+`
+
 const goStructTemplate = `
 type {{ .Name }} struct {
 	{{- range $index, $param := .Params }}
 	{{ $param.Name }} {{ $param.Type }}
-	{{- if (eq $param.NeedsPath true) }}
-	{{ $param.Name }}Path string
+	{{- if $param.Extra }}
+	{{ $param.Name }}{{ $param.Extra }} string
 	{{- end }}
 	{{- end }}
 }
@@ -648,6 +643,71 @@ typedef struct {
 } {{ .Name }}_event_t;
 `
 
+const bpfDispatchHeader = `
+// Dispatch of the syscalls to their handlers, included by
+// bpf/trace_events.c. See dispatch() there.
+`
+
+// The kprobes are named after the SyS_* functions of older kernels, the probe
+// attaches them to the function actually implementing the syscall.
+const bpfDispatchTemplate = `
+struct bpf_map_def SEC("maps/handle_{{ .Name }}_progs") handle_{{ .Name }}_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_{{ .Name }}_progs_ret") handle_{{ .Name }}_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_{{ .Name }}_tp_progs") handle_{{ .Name }}_tp_progs = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+struct bpf_map_def SEC("maps/handle_{{ .Name }}_tp_progs_ret") handle_{{ .Name }}_tp_progs_ret = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = sizeof(__u32),
+	.value_size = sizeof(__u32),
+	.max_entries = PROG_ARRAY_ENTRIES,
+	.map_flags = 0,
+};
+
+SEC("kprobe/SyS_{{ .Name }}")
+int kprobe__sys_{{ .Name }}(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_{{ .Name }}_progs);
+}
+
+SEC("kretprobe/SyS_{{ .Name }}")
+int kretprobe__sys_{{ .Name }}(struct pt_regs *ctx)
+{
+	return dispatch(ctx, &handle_{{ .Name }}_progs_ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_{{ .Name }}")
+int tracepoint__sys_enter_{{ .Name }}(struct syscall_enter_args *ctx)
+{
+	return dispatch(ctx, &handle_{{ .Name }}_tp_progs);
+}
+
+SEC("tracepoint/syscalls/sys_exit_{{ .Name }}")
+int tracepoint__sys_exit_{{ .Name }}(struct syscall_exit_args *ctx)
+{
+	return dispatch(ctx, &handle_{{ .Name }}_tp_progs_ret);
+}
+`
+
 const helpers = `
 // helpers for events
 
@@ -659,7 +719,7 @@ func min(x, y int) int {
 }
 
 // Assume buffer truncates at 0
-func bufLen(buf [256]byte) int {
+func bufLen(buf []byte) int {
 	for idx := 0; idx < len(buf); idx++ {
 		if buf[idx] == 0 {
 			return idx
@@ -668,12 +728,79 @@ func bufLen(buf [256]byte) int {
 	return len(buf)
 }
 
+// cString returns the NUL terminated string in buf
+func cString(buf []byte) string {
+	return string(buf[:bufLen(buf)])
+}
+
+// retBuffer returns the part of buf filled by a syscall returning the length
+// it read or wrote, e.g. read(2). If ret is unknown, buf is assumed to be NUL
+// terminated.
+func retBuffer(buf []byte, ret int64) string {
+	if ret == retUnknown {
+		return cString(buf)
+	}
+	if ret <= 0 {
+		return ""
+	}
+	return string(buf[:min(int(ret), len(buf))])
+}
+
+// getArg returns the value of the argument name of event
+func getArg(event string, args []EventArg, name string) (string, error) {
+	for _, arg := range args {
+		if arg.Name == name {
+			return fmt.Sprintf("%v", arg.Value), nil
+		}
+	}
+	return "", fmt.Errorf("Event %s does not have argument %q", event, name)
+}
+
 type Event interface {
 	String(ret int64) string
 	GetArgN(n int, ret int64) (string, error)
+	GetArg(name string, ret int64) (string, error)
+	Args(ret int64) []EventArg
 	Metric() *Metric
 }
 
+// EventArg is a decoded argument of an event
+type EventArg struct {
+	Name  string
+	Value interface{}
+}
+
+type EventArgs []EventArg
+
+// MarshalJSON encodes the arguments as a JSON object, keeping their order
+func (a EventArgs) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, arg := range a {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(arg.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// retUnknown is passed to Args when the return value of the syscall is not
+// known, e.g. when marshalling an event on its own. Buffers are then assumed
+// to be NUL terminated.
+const retUnknown = math.MinInt64
+
 func procLookupPath(pid, fd uint32) (string, error) {
 	return os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", pid, fd))
 }
@@ -688,73 +815,80 @@ func (e DefaultEvent) GetArgN(n int, ret int64) (string, error) {
 	return "", fmt.Errorf("DefaultEvent.GetArgN not implemented")
 }
 
+func (e DefaultEvent) GetArg(name string, ret int64) (string, error) {
+	return "", fmt.Errorf("DefaultEvent.GetArg not implemented")
+}
+
+func (e DefaultEvent) Args(ret int64) []EventArg {
+	return nil
+}
+
+func (e DefaultEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(nil).MarshalJSON()
+}
+
 func (w DefaultEvent) Metric() *Metric {
 	return nil
 }
 `
 
-// TODO: Use template functions for parameter types & names, make buffer len decision generic for other syscalls
+// How arguments are decoded depends on their kind, see the Param methods
 const eventStringsTemplate = `
 func (e {{ .Name }}) String(ret int64) string {
-	{{- $name := .Name }}
-	{{- range $index, $param := .Params }}
-		{{- if or (eq $param.Name "Buf") (eq $param.Name "Filename") (eq $param.Name "Pathname") }}
-	buffer := (*C.char)(unsafe.Pointer(&e.{{ $param.Name }}))
-	length := C.int(0)
-			{{- if or (eq $name "ReadEvent") (eq $name "WriteEvent") }}
-	if ret > 0 {
-		length = C.int(min(int(ret), len(e.{{ $param.Name }})))
-	}
-			{{- else}}
-	length = C.int(bufLen(e.{{ $param.Name }}))
-			{{- end}}
-	bufferGo := C.GoStringN(buffer, length)
-		{{- end }}
-	{{- end }}
 	return fmt.Sprintf("{{- range $index, $param := .Params -}}
-	{{ $param.Name }}
-	{{- if or (eq $param.Type "uint64") (eq $param.Type "int64") (eq $param.Type "uint32") }} %d{{else}} %q{{ end -}}
-	{{- if or (eq $param.Name "Fd") (eq $param.Name "Dfd") -}}
-	<%s> {{/* space */}}
-	{{- else }} {{ end -}}
+	{{ $param.Name }} {{ $param.Verb }}{{ if $param.Extra }}<%s>{{ end }} {{/* space */}}
 	{{- end }}", {{/* space */}}
 	{{- range $index, $param := .Params -}}
-		{{- if $index }}, {{ end }}
-		{{- if or (eq $param.Name "Buf") (eq $param.Name "Filename") (eq $param.Name "Pathname") -}}
-			 bufferGo
-			{{- else -}}
-			 e.{{ $param.Name }}
-		{{- end -}}
-		{{- if or (eq $param.Name "Fd") (eq $param.Name "Dfd") -}}
-			, e.{{ $param.Name }}Path
-		{{- end -}}
+		{{- if $index }}, {{ end -}}
+		{{ $param.Value }}
+		{{- if $param.Extra }}, e.{{ $param.Name }}{{ $param.Extra }}{{ end -}}
 	{{- end -}})
 }
 
 func (e {{ .Name }}) GetArgN(n int, ret int64) (string, error) {
 	switch n {
 	{{- range $index, $param := .Params }}
-	case {{ $index }}: // {{ $param.Name }}: type {{ $param.Type }}
-	{{- if or (eq $param.Type "uint64") (eq $param.Type "int64") (eq $param.Type "uint32") }}
-		bufferGo := fmt.Sprintf("%v", e.{{ $param.Name }})
-	{{- else }}
-		buffer := (*C.char)(unsafe.Pointer(&e.{{ $param.Name }}))
-		length := C.int(0)
-			{{- if or (eq $name "ReadEvent") (eq $name "WriteEvent") }}
-		if ret > 0 {
-			length = C.int(min(int(ret), len(e.{{ $param.Name }})))
-		}
-			{{- else}}
-		length = C.int(bufLen(e.{{ $param.Name }}))
-			{{- end}}
-		bufferGo := C.GoStringN(buffer, length)
-	{{- end }}
-		return bufferGo, nil
+	case {{ $index }}: // {{ $param.Name }}: {{ $param.Kind }} of type {{ $param.Type }}
+		{{- if $param.Size }}
+		return {{ $param.Value }}, nil
+		{{- else }}
+		return fmt.Sprintf("%v", e.{{ $param.Name }}), nil
+		{{- end }}
 	{{- end }}
 	default:
 		return "", fmt.Errorf("Event {{ .Name }} does not have argument %d", n)
 	}
 }
+
+func (e {{ .Name }}) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	{{- range $index, $param := .Params }}
+	case "{{ $param.Name }}":
+		return e.GetArgN({{ $index }}, ret)
+		{{- if $param.Extra }}
+	case "{{ $param.Name }}{{ $param.Extra }}":
+		return e.{{ $param.Name }}{{ $param.Extra }}, nil
+		{{- end }}
+	{{- end }}
+	default:
+		return "", fmt.Errorf("Event {{ .Name }} does not have argument %q", name)
+	}
+}
+
+func (e {{ .Name }}) Args(ret int64) []EventArg {
+	return []EventArg{
+	{{- range $index, $param := .Params }}
+		{Name: "{{ $param.Name }}", Value: {{ $param.Value }}},
+		{{- if $param.Extra }}
+		{Name: "{{ $param.Name }}{{ $param.Extra }}", Value: e.{{ $param.Name }}{{ $param.Extra }}},
+		{{- end }}
+	{{- end }}
+	}
+}
+
+func (e {{ .Name }}) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
 `
 
 const eventProtobufTemplate = `
@@ -762,7 +896,7 @@ func (e {{ .Syscall.Name }}) Metric() *Metric {
 	return &Metric{
 		{{ .Syscall.Name }}: &Protobuf{{ .Syscall.Name }}{
 		{{- range $index, $param := .Syscall.Params }}
-			{{- if or (eq $param.Name "Buf") (eq $param.Name "Filename") (eq $param.Name "Pathname") }}
+			{{- if $param.Size }}
 				{{ $param.Name }}: e.{{ $param.Name }}[:],
 			{{- else }}
 				{{ $param.Name }}: e.{{ $param.Name }},
@@ -782,39 +916,39 @@ const getStructTemplate = `
 	case "{{ .RawName }}":
 		ev := {{ .Name }}{}
 		{{- range $index, $param := .Params }}
-			{{- if eq $param.Type "[256]byte" }}
-		copy(ev.{{ $param.Name }}[:], buf.Next(256))
+			{{- if $param.Padding }}
+		buf.Next({{ $param.Padding }}) // padding
+			{{- end }}
+			{{- if $param.Size }}
+		copy(ev.{{ $param.Name }}[:], buf.Next({{ $param.Size }}))
 			{{- else if or (eq $param.Type "uint32") (eq $param.Type "int32") }}
 		ev.{{ $param.Name }} = {{ $param.Type }}(binary.LittleEndian.Uint32(buf.Next(4)))
 			{{- else if or (eq $param.Type "uint64") (eq $param.Type "int64") }}
 		ev.{{ $param.Name }} = {{ $param.Type }}(binary.LittleEndian.Uint64(buf.Next(8)))
 			{{- end }}
 			{{- if (eq $param.NeedsPath true) }}
-		fileName := "unknown"
-		info, ok := ctx.Fds.Get(uint32(ce.Pid), uint32(ev.{{ $param.Name }}))
-		if ok {
-			var stat syscall.Stat_t
-			path := filepath.Join("/proc", strconv.FormatInt(int64(ce.Pid), 10), "root", info.Path)
-			err := syscall.Stat(path, &stat)
-			if err != nil {
-				if err == syscall.ENOENT {
-					// the file doesn't exist anymore, it's probably "info.Path"
-					// but we're not sure
-					fileName = fmt.Sprintf("[deleted] (%q)?", info.Path)
-				}
-			}
-			if info.Ino == stat.Ino &&
-				info.Major == stat.Dev>>8 &&
-				info.Minor == stat.Dev&0xff {
-				fileName = info.Path
-			}
-		}
-		ev.{{ $param.Name }}Path = fileName
+		ev.{{ $param.Name }}Path = ctx.fdPath(uint32(ce.Pid), uint32(ev.{{ $param.Name }}))
+			{{- else if (eq $param.Kind "uid") }}
+		ev.{{ $param.Name }}Name = ctx.lookupUser(ce.Pid, uint32(ev.{{ $param.Name }}))
+			{{- else if (eq $param.Kind "gid") }}
+		ev.{{ $param.Name }}Name = ctx.lookupGroup(ce.Pid, uint32(ev.{{ $param.Name }}))
 		{{- end }}
 		{{- end }}
 
 	{{- if (eq .Name "CloseEvent") }}
 		ctx.Fds.Delete(uint32(ce.Pid), uint32(ev.Fd))
+	{{- else if (eq .Name "DupEvent") }}
+		if ce.Ret >= 0 {
+			ctx.Fds.Copy(uint32(ce.Pid), uint32(ev.Fildes), uint32(ce.Ret))
+		}
+	{{- else if or (eq .Name "Dup2Event") (eq .Name "Dup3Event") }}
+		if ce.Ret >= 0 {
+			ctx.Fds.Copy(uint32(ce.Pid), uint32(ev.Oldfd), uint32(ce.Ret))
+		}
+	{{- else if (eq .Name "FcntlEvent") }}
+		if (ev.Cmd == syscall.F_DUPFD || ev.Cmd == syscall.F_DUPFD_CLOEXEC) && ce.Ret >= 0 {
+			ctx.Fds.Copy(uint32(ce.Pid), uint32(ev.Fd), uint32(ce.Ret))
+		}
 	{{- end }}
 
 		return ev, nil
@@ -827,18 +961,20 @@ const getStructEpilogue = `
 		if err := binary.Read(buf, binary.LittleEndian, &ev); err != nil {
 			return nil, err
 		}
-		name, err := procLookupPath(uint32(ce.Pid), uint32(ev.Fd))
-		if err != nil {
-			name = "unknown"
-		}
-
-		fdInfo := FdInfo{Path: name, Ino: ev.Ino, Major: ev.Major, Minor: ev.Minor}
-
-		// ignore entries not backed by files, like sockets or anonymous inodes
-		if strings.HasPrefix(fdInfo.Path, "/") {
-			ctx.Fds.Put(uint32(ce.Pid), uint32(ev.Fd), fdInfo)
-		}
-
+		ctx.installFd(uint32(ce.Pid), ev)
+		return ev, nil
+	// process events
+	case "fork":
+		ev := ForkEvent{}
+		ev.ChildPid = binary.LittleEndian.Uint64(buf.Next(8))
+		ctx.Fds.CopyPid(uint32(ce.Pid), uint32(ev.ChildPid))
+		return ev, nil
+	case "exit":
+		ev := ExitEvent{}
+		ev.ExitCode = int64(binary.LittleEndian.Uint64(buf.Next(8)))
+		ev.Signal = int64(binary.LittleEndian.Uint64(buf.Next(8)))
+		ctx.Fds.DeletePid(uint32(ce.Pid))
+		ctx.deleteProcess(ce.Pid)
 		return ev, nil
 	// network events
 	case "close_v4":
@@ -882,6 +1018,7 @@ message ProtobufCommonEvent {
 	string Name = 4;
 	uint64 Hash = 5;
 	uint64 Flags = 6;
+	uint64 Duration = 7;
 }
 
 message ProtobufConnectV4Event {
@@ -930,8 +1067,73 @@ message Metric {
 	ProtobufConnectV4Event ConnectV4Event = 3;
 	ProtobufConnectV6Event ConnectV6Event = 4;
 	{{- range $index, $syscall := . }}
-	Protobuf{{ $syscall.Name }} {{ $syscall.Name }} = {{ incn $index 5 }};
+	Protobuf{{ $syscall.Name }} {{ $syscall.Name }} = {{ $syscall.FieldNumber }};
 	{{- end }}
+
+	// far from the events of the syscalls, numbered from 5 (see
+	// GatherSyscalls)
+	ProtobufProcess Process = 1000;
+
+	// the event encoded in the format of the output, unless it is
+	// collector_spec_pb
+	string Format = 1001;
+	bytes Encoded = 1002;
+
+	// summary of the events of a group in a window, for the window output
+	// functions
+	ProtobufWindowSummary Window = 1003;
+}
+
+message ProtobufContainer {
+	string Runtime = 1;
+	string Id = 2;
+	string Name = 3;
+	string Image = 4;
+	string PodUid = 5;
+	string QosClass = 6;
+}
+
+message ProtobufProcess {
+	string Comm = 1;
+	string Exe = 2;
+	int64 Ppid = 3;
+	uint32 Uid = 4;
+	uint32 Gid = 5;
+	string Cgroup = 6;
+	bool InContainer = 7;
+	ProtobufContainer Container = 8;
+	repeated string Cmdline = 9;
+	// in nanoseconds since the epoch, 0 if unknown
+	int64 StartTime = 10;
+	// inodes of the mount, pid and network namespaces
+	uint64 MntNs = 11;
+	uint64 PidNs = 12;
+	uint64 NetNs = 13;
+}
+
+message ProtobufWindowKey {
+	string Name = 1;
+	string Value = 2;
+}
+
+message ProtobufPercentile {
+	double Percentile = 1;
+	double Value = 2;
+}
+
+message ProtobufWindowSummary {
+	// bounds of the window, in nanoseconds since the epoch
+	int64 Start = 1;
+	int64 End = 2;
+	// values of the group-by fields of the group
+	repeated ProtobufWindowKey Keys = 3;
+	uint64 Count = 4;
+	// summary of the numeric values of Field in the events of the group
+	string Field = 5;
+	double Min = 6;
+	double Max = 7;
+	double Sum = 8;
+	repeated ProtobufPercentile Percentiles = 9;
 }
 `
 
@@ -941,28 +1143,91 @@ type Param struct {
 	Type      string
 	Suffix    string
 	HashFunc  string
-	NeedsPath bool `json:"needsPath"`
+	Kind      string // how to decode the argument, see argKinds
+	NeedsPath bool   `json:"needsPath"`
+	Size      int    // size of buffers, 0 otherwise
+	Padding   int    // bytes to skip before the field when decoding
+}
+
+// Kinds of the arguments, set by "kind" in the config
+const (
+	kindString    = "string"     // NUL terminated string, the default for buffers
+	kindBuffer    = "buffer"     // buffer whose length is the return value of the syscall
+	kindInt       = "int"        // the default for integers
+	kindFd        = "fd"         // file descriptor, also decoded as the path of its file
+	kindMode      = "mode"       // file mode, e.g. 0644/rw-r--r--
+	kindFlags     = "flags"      // bit flags, shown in hexadecimal
+	kindOpenFlags = "open_flags" // flags of open(2), e.g. O_RDONLY|O_CLOEXEC
+	kindAtFlags   = "at_flags"   // AT_* flags of the *at(2) syscalls
+	kindUID       = "uid"        // user id, also decoded as the user name
+	kindGID       = "gid"        // group id, also decoded as the group name
+)
+
+// argKinds tells whether each kind is one of buffers (char arrays) or of
+// integers
+var argKinds = map[string]bool{
+	kindString:    true,
+	kindBuffer:    true,
+	kindInt:       false,
+	kindFd:        false,
+	kindMode:      false,
+	kindFlags:     false,
+	kindOpenFlags: false,
+	kindAtFlags:   false,
+	kindUID:       false,
+	kindGID:       false,
+}
+
+// Verb returns the fmt verb to print the argument in String
+func (p Param) Verb() string {
+	switch p.Kind {
+	case kindString, kindBuffer:
+		return "%q"
+	case kindMode, kindOpenFlags, kindAtFlags:
+		return "%s"
+	case kindFlags:
+		return "%#x"
+	}
+	return "%d"
+}
+
+// Extra returns the suffix of the name of the field holding what the argument
+// refers to, if any: the path of the file of fds and the name of uids and gids
+func (p Param) Extra() string {
+	switch p.Kind {
+	case kindFd:
+		return "Path"
+	case kindUID, kindGID:
+		return "Name"
+	}
+	return ""
+}
+
+// Value returns the Go expression of the decoded value of the argument in the
+// event e, given the return value ret of the syscall
+func (p Param) Value() string {
+	switch p.Kind {
+	case kindString:
+		return fmt.Sprintf("cString(e.%s[:])", p.Name)
+	case kindBuffer:
+		return fmt.Sprintf("retBuffer(e.%s[:], ret)", p.Name)
+	case kindMode:
+		return fmt.Sprintf("DecodeMode(uint64(e.%s))", p.Name)
+	case kindOpenFlags:
+		return fmt.Sprintf("DecodeOpenFlags(uint64(e.%s))", p.Name)
+	case kindAtFlags:
+		return fmt.Sprintf("DecodeAtFlags(uint64(e.%s))", p.Name)
+	}
+	return "e." + p.Name
 }
 
 type Syscall struct {
 	Name    string
 	RawName string
 	Params  []Param
-}
 
-var consideredSyscalls = map[string]struct{}{
-	"open":     {},
-	"close":    {},
-	"read":     {},
-	"write":    {},
-	"mkdir":    {},
-	"mkdirat":  {},
-	"chmod":    {},
-	"fchmod":   {},
-	"fchmodat": {},
-	"chown":    {},
-	"fchown":   {},
-	"fchownat": {},
+	// number of the field of the event in the Metric message
+	FieldNumber uint32
 }
 
 // Converts a string to CamelCase
@@ -990,169 +1255,180 @@ func ToCamel(s string) string {
 	return n
 }
 
-var re = regexp.MustCompile(`\s+field:(?P<type>.*?) (?P<name>[a-z_0-9]+);.*`)
-
-func parseLine(l string, idx int) (*Param, *Param, *Param, error) {
-	n1 := re.SubexpNames()
-
-	r := re.FindAllStringSubmatch(l, -1)
-	if len(r) == 0 {
-		return nil, nil, nil, nil
+// alignment returns the alignment of a field of the given C type, 1 for
+// buffers
+func (p Param) alignment() int {
+	if p.Size != 0 {
+		return 1
 	}
-	res := r[0]
-
-	mp := map[string]string{}
-	for i, n := range res {
-		mp[n1[i]] = n
+	switch goTypeConversions[p.Type] {
+	case "int32", "uint32":
+		return 4
 	}
+	return 8
+}
 
-	if _, ok := mp["type"]; !ok {
-		return nil, nil, nil, nil
-	}
-	if _, ok := mp["name"]; !ok {
-		return nil, nil, nil, nil
+// size returns the size of a field of the given C type
+func (p Param) size() int {
+	if p.Size != 0 {
+		return p.Size
 	}
+	return p.alignment()
+}
 
-	// ignore
-	if mp["name"] == "__syscall_nr" {
-		return nil, nil, nil, nil
-	}
+func parseArg(arg *generator.Event_Args) (*Param, *Param, *Param, error) {
+	var cParam Param
+	cParam.Name = arg.Name
+	cParam.Type = arg.Type
+	cParam.Suffix = arg.Suffix
+	cParam.HashFunc = arg.HashFunc
+	cParam.Position = int(arg.Position)
 
 	var goParam Param
-	goParam.Name = ToCamel(mp["name"])
-	goParam.Type = goTypeConversions[mp["type"]]
-	goParam.Suffix = ""
-	goParam.Position = 0
-	if goParam.Name == "Fd" || goParam.Name == "Dfd" {
-		goParam.NeedsPath = true
-	}
+	goParam.Name = ToCamel(arg.Name)
+	goParam.Position = cParam.Position
+	goParam.Kind = arg.Kind
 
-	var cParam Param
-	cParam.Name = mp["name"]
-	cParam.Type = cTypeConversions[mp["type"]]
+	isBuffer, ok := argKinds[arg.Kind]
+	if arg.Kind != "" && !ok {
+		return nil, nil, nil, fmt.Errorf("unknown kind %q for argument %q", arg.Kind, arg.Name)
+	}
+	if arg.Kind != "" && isBuffer != (arg.Type == "char") {
+		return nil, nil, nil, fmt.Errorf("kind %q doesn't match type %q of argument %q", arg.Kind, arg.Type, arg.Name)
+	}
+	if arg.Kind == "" {
+		goParam.Kind = kindInt
+		if arg.Type == "char" {
+			goParam.Kind = kindString
+		}
+	}
+	goParam.NeedsPath = goParam.Kind == kindFd
 
 	var protoParam Param
-	protoParam.Name = mp["name"]
-	protoParam.Type = protoTypeConversions[goParam.Type]
+	protoParam.Name = arg.Name
 
-	// TODO: Separate this function when types to check start increasing
-	// Build suffix here for expected char pointer. Consider all chars need suffix
-	if cTypeConversions[mp["type"]] == "char" {
-		cParam.Suffix = fmt.Sprintf("[%d]", maxBufferSize)
+	if arg.Type == "char" {
+		var size int
+		if _, err := fmt.Sscanf(arg.Suffix, "[%d]", &size); err != nil || size <= 0 {
+			return nil, nil, nil, fmt.Errorf("buffer %q needs a suffix with its size like %q, got %q", arg.Name, fmt.Sprintf("[%d]", maxBufferSize), arg.Suffix)
+		}
+		cParam.Size = size
+		goParam.Size = size
+		goParam.Type = fmt.Sprintf("[%d]byte", size)
+		protoParam.Type = "bytes"
 	} else {
-		cParam.Suffix = ""
+		goType, ok := goTypeConversions[arg.Type]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("unsupported type %q for argument %q", arg.Type, arg.Name)
+		}
+		if arg.Suffix != "" {
+			return nil, nil, nil, fmt.Errorf("unexpected suffix %q for argument %q of type %q", arg.Suffix, arg.Name, arg.Type)
+		}
+		goParam.Type = goType
+		protoParam.Type = protoTypeConversions[goType]
 	}
-	// The position is calculated based on the event format. The actual parameters
-	// start from 8th index, hence we subtract that from idx to get position
-	// of the parameter to the syscall
-	cParam.Position = idx - 8
-	// TODO: Add position info here and use the Param struct to populate parameter reading in kretprobe handler
 
 	return &goParam, &cParam, &protoParam, nil
 }
 
-func parseSyscall(name, format string) (*Syscall, *Syscall, *Syscall, error) {
-	syscallParts := strings.Split(format, "\n")
-	var skipped bool
-
+func parseEvent(event *generator.Event) (*Syscall, *Syscall, *Syscall, error) {
 	var cParams []Param
 	var goParams []Param
 	var protoParams []Param
-	for idx, line := range syscallParts {
-		if !skipped {
-			if len(line) != 0 {
-				continue
-			} else {
-				skipped = true
-			}
-		}
-		gp, cp, protop, err := parseLine(line, idx)
+
+	// offset of the next field after common_event_t, to decode the padding
+	// the C compiler adds between fields
+	offset := 0
+	for _, arg := range event.Args {
+		gp, cp, protop, err := parseArg(arg)
 		if err != nil {
-			return nil, nil, nil, err
-		}
-		if gp != nil {
-			goParams = append(goParams, *gp)
+			return nil, nil, nil, fmt.Errorf("event %q: %v", event.Name, err)
 		}
-		if cp != nil {
-			cParams = append(cParams, *cp)
-		}
-		if protop != nil {
-			protoParams = append(protoParams, *protop)
+
+		if rem := offset % cp.alignment(); rem != 0 {
+			gp.Padding = cp.alignment() - rem
+			offset += gp.Padding
 		}
+		offset += cp.size()
+
+		goParams = append(goParams, *gp)
+		cParams = append(cParams, *cp)
+		protoParams = append(protoParams, *protop)
 	}
 
 	return &Syscall{
-			Name:    fmt.Sprintf("%s%s", ToCamel(name), "Event"),
-			RawName: name,
-			Params:  goParams,
+			Name:        fmt.Sprintf("%s%s", ToCamel(event.Name), "Event"),
+			RawName:     event.Name,
+			Params:      goParams,
+			FieldNumber: event.FieldNumber,
 		},
 		&Syscall{
-			Name:    fmt.Sprintf("%s", name),
-			RawName: name,
-			Params:  cParams,
+			Name:        fmt.Sprintf("%s", event.Name),
+			RawName:     event.Name,
+			Params:      cParams,
+			FieldNumber: event.FieldNumber,
 		},
 		&Syscall{
-			Name:    fmt.Sprintf("%s%s%s", "Protobuf", ToCamel(name), "Event"),
-			RawName: name,
-			Params:  protoParams,
+			Name:        fmt.Sprintf("%s%s%s", "Protobuf", ToCamel(event.Name), "Event"),
+			RawName:     event.Name,
+			Params:      protoParams,
+			FieldNumber: event.FieldNumber,
 		}, nil
 }
 
-func GatherSyscalls() ([]Syscall, []Syscall, []Syscall, error) {
+// Field numbers of the events of the syscalls in the Metric message, the
+// others are used by its other fields
+const (
+	minEventFieldNumber = 5
+	maxEventFieldNumber = 999
+)
+
+// GatherSyscalls returns the Go, C and protobuf descriptions of the syscalls
+// in config, sorted by name so that the generated files don't depend on the
+// order of the config. Each event must have its own field number in the
+// Metric message (fieldNumber in the config), which must never change once
+// released, for the messages to stay compatible.
+func GatherSyscalls(config *generator.Config) ([]Syscall, []Syscall, []Syscall, error) {
 	var goSyscalls []Syscall
 	var cSyscalls []Syscall
 	var protoSyscalls []Syscall
 
-	err := filepath.Walk(syscallsPath, func(path string, f os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if path == "syscalls" {
-			return nil
-		}
-
-		if !f.IsDir() {
-			return nil
-		}
+	events := make([]*generator.Event, len(config.Event))
+	copy(events, config.Event)
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Name < events[j].Name
+	})
 
-		eventName := f.Name()
-		if strings.HasPrefix(eventName, "sys_exit") {
-			return nil
+	seen := make(map[string]struct{})
+	fieldNumbers := make(map[uint32]string)
+	for _, event := range events {
+		if _, ok := seen[event.Name]; ok {
+			return nil, nil, nil, fmt.Errorf("event %q is defined more than once", event.Name)
 		}
+		seen[event.Name] = struct{}{}
 
-		syscallName := strings.TrimPrefix(eventName, "sys_enter_")
-
-		if _, ok := consideredSyscalls[syscallName]; !ok {
-			return nil
+		n := event.FieldNumber
+		if n == 0 {
+			return nil, nil, nil, fmt.Errorf("event %q has no fieldNumber", event.Name)
 		}
-
-		formatFilePath := filepath.Join(syscallsPath, eventName, "format")
-		formatFile, err := os.Open(formatFilePath)
-		if err != nil {
-			return nil
+		if n < minEventFieldNumber || n > maxEventFieldNumber {
+			return nil, nil, nil, fmt.Errorf("event %q: fieldNumber %d out of range [%d, %d]", event.Name, n, minEventFieldNumber, maxEventFieldNumber)
 		}
-		defer formatFile.Close()
-
-		formatBytes, err := ioutil.ReadAll(formatFile)
-		if err != nil {
-			return err
+		if other, ok := fieldNumbers[n]; ok {
+			return nil, nil, nil, fmt.Errorf("events %q and %q have the same fieldNumber %d", other, event.Name, n)
 		}
+		fieldNumbers[n] = event.Name
 
-		goSyscall, cSyscall, protoSyscall, err := parseSyscall(syscallName, string(formatBytes))
+		goSyscall, cSyscall, protoSyscall, err := parseEvent(event)
 		if err != nil {
-			return err
+			return nil, nil, nil, err
 		}
 
 		goSyscalls = append(goSyscalls, *goSyscall)
 		cSyscalls = append(cSyscalls, *cSyscall)
 		protoSyscalls = append(protoSyscalls, *protoSyscall)
-
-		return nil
-	})
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("error walking %q: %v", err)
 	}
+
 	return goSyscalls, cSyscalls, protoSyscalls, nil
 }
 
@@ -1223,6 +1499,10 @@ func GenerateGoStructs(goSyscalls []Syscall) (string, error) {
 		return "", fmt.Errorf("error writing to buffer: %v", err)
 	}
 
+	if _, err := buf.WriteString(processTemplate); err != nil {
+		return "", fmt.Errorf("error writing to buffer: %v", err)
+	}
+
 	return buf.String(), nil
 }
 
@@ -1244,6 +1524,28 @@ func GenerateCStructs(cSyscalls []Syscall) (string, error) {
 	return buf.String(), nil
 }
 
+// GenerateBpfDispatch returns the prog arrays and the kprobes and tracepoint
+// programs of the global BPF program for the syscalls
+func GenerateBpfDispatch(cSyscalls []Syscall) (string, error) {
+	buf := new(bytes.Buffer)
+
+	if _, err := buf.WriteString(headers + bpfDispatchHeader); err != nil {
+		return "", fmt.Errorf("error writing to buffer: %v", err)
+	}
+
+	tmpl, err := template.New("bpf_dispatch").Parse(bpfDispatchTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error templating: %v", err)
+	}
+	for _, sc := range cSyscalls {
+		if err := tmpl.Execute(buf, sc); err != nil {
+			return "", fmt.Errorf("error templating BPF dispatch of %q: %v", sc.Name, err)
+		}
+	}
+
+	return buf.String(), nil
+}
+
 func GenerateProtoStructs(protoSyscalls []Syscall, goSyscalls []Syscall) (string, error) {
 	buf := new(bytes.Buffer)
 
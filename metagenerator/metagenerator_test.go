@@ -0,0 +1,91 @@
+package metagenerator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ShiftLeftSecurity/traceleft/generator"
+)
+
+func event(name string, fieldNumber uint32) *generator.Event {
+	return &generator.Event{
+		Name:        name,
+		FieldNumber: fieldNumber,
+		Args: []*generator.Event_Args{
+			{Position: 1, Type: "u64", Name: "fd", Kind: "fd"},
+		},
+	}
+}
+
+func TestGatherSyscallsFieldNumbers(t *testing.T) {
+	tests := []struct {
+		events []*generator.Event
+		err    string
+	}{
+		{[]*generator.Event{event("close", 7), event("chmod", 5)}, ""},
+		{[]*generator.Event{event("close", 7), event("chmod", 0)}, `event "chmod" has no fieldNumber`},
+		{[]*generator.Event{event("close", 4)}, "out of range"},
+		{[]*generator.Event{event("close", 1000)}, "out of range"},
+		{[]*generator.Event{event("close", 7), event("chmod", 7)}, `events "chmod" and "close" have the same fieldNumber 7`},
+		{[]*generator.Event{event("close", 7), event("close", 8)}, `event "close" is defined more than once`},
+	}
+
+	for _, tt := range tests {
+		_, _, _, err := GatherSyscalls(&generator.Config{Event: tt.events})
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("got error %v, want %q", err, tt.err)
+		}
+	}
+}
+
+// TestProtoFieldNumbers checks that the field numbers of the events in the
+// Metric message don't depend on the other events
+func TestProtoFieldNumbers(t *testing.T) {
+	generate := func(events ...*generator.Event) string {
+		goSyscalls, _, protoSyscalls, err := GatherSyscalls(&generator.Config{Event: events})
+		if err != nil {
+			t.Fatal(err)
+		}
+		proto, err := GenerateProtoStructs(protoSyscalls, goSyscalls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return proto
+	}
+
+	want := "ProtobufCloseEvent CloseEvent = 7;"
+	if proto := generate(event("close", 7)); !strings.Contains(proto, want) {
+		t.Errorf("generated proto doesn't contain %q:\n%s", want, proto)
+	}
+	// an event sorted before close doesn't move it
+	if proto := generate(event("close", 7), event("accept", 20)); !strings.Contains(proto, want) {
+		t.Errorf("generated proto doesn't contain %q:\n%s", want, proto)
+	}
+}
+
+// TestReleasedFieldNumbers checks that the events of the example config keep
+// the field numbers they were released with
+func TestReleasedFieldNumbers(t *testing.T) {
+	config, err := generator.ReadConfig("../examples/config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	released := map[string]uint32{
+		"chmod": 5, "chown": 6, "close": 7,
+		"fchmod": 8, "fchmodat": 9, "fchown": 10, "fchownat": 11,
+		"mkdir": 12, "mkdirat": 13, "open": 14, "read": 15, "write": 16,
+		"dup": 17, "dup2": 18, "dup3": 19, "fcntl": 20,
+	}
+	for _, event := range config.Event {
+		if n, ok := released[event.Name]; ok && event.FieldNumber != n {
+			t.Errorf("event %q: fieldNumber %d, released as %d", event.Name, event.FieldNumber, n)
+		}
+	}
+}
@@ -1,23 +1,21 @@
 package metrics
 
 import (
-	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"strings"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 
 	"github.com/ShiftLeftSecurity/traceleft/tracer"
 )
 
 var (
-	/* factories initialized in output.go's and processing-functions.go's init() */
-	processingFuncBuilder map[string]func(*Aggregator, int) processingFunc = make(map[string]func(*Aggregator, int) processingFunc)
-	outputFuncBuilder     map[string]func(*Aggregator, int) outputFunc     = make(map[string]func(*Aggregator, int) outputFunc)
+	/* factories initialized in output.go's, processing-functions.go's and encoder.go's init() */
+	processingFuncBuilder map[string]func(*Aggregator, int) (processingFunc, error) = make(map[string]func(*Aggregator, int) (processingFunc, error))
+	outputFuncBuilder     map[string]func(*Aggregator, int) (outputFunc, error)     = make(map[string]func(*Aggregator, int) (outputFunc, error))
+	encoderBuilder        map[string]func() encoder                                 = make(map[string]func() encoder)
 )
 
 type Aggregator struct {
@@ -38,10 +36,21 @@ type SendEvent struct {
 	data    *tracer.EventData
 	spec    *EventSpec
 	counter int
+
+	// set by the window output functions
+	window *windowSummary
 }
 
 func (e SendEvent) String(tracerCtx tracer.Context) string {
-	return fmt.Sprintf("%+v %s", e.data.Common, e.data.Event.String(e.data.Common.Ret))
+	s := fmt.Sprintf("%+v %s", e.data.Common, e.data.Event.String(e.data.Common.Ret))
+	if p := e.data.Process; p != nil {
+		s += fmt.Sprintf(" process {comm %s exe %s ppid %d uid %d gid %d cgroup %s container %t}",
+			p.Comm, p.Exe, p.Ppid, p.UID, p.GID, p.Cgroup, p.Container)
+		if c := p.ContainerInfo; c != nil {
+			s += fmt.Sprintf(" container {%+v}", *c)
+		}
+	}
+	return s
 }
 
 type ChannelKind int
@@ -57,16 +66,55 @@ type aggregationChannel struct {
 	Handler interface{}
 }
 
-type GrpcHandler struct {
-	Conn   *grpc.ClientConn
-	Client tracer.MetricCollectorClient
-}
-
 type AggregatorOptions struct {
 	DialInsecure bool
 }
 
 func NewAggregator(opts AggregatorOptions, incoming <-chan *tracer.EventData, spec AggregationSpec, tracerCtx tracer.Context) (*Aggregator, error) {
+	aggregator := &Aggregator{
+		stop:            make(chan bool),
+		aggregationSpec: spec,
+	}
+
+	for i := range spec.Events {
+		r, err := compileRule(spec.Events[i].Rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule for event %q: %v", spec.Events[i].Name, err)
+		}
+		spec.Events[i].rule = r
+
+		newProcessingFunc, ok := processingFuncBuilder[spec.Events[i].F.Id]
+		if !ok {
+			return nil, fmt.Errorf("unknown processing function %q for event %q", spec.Events[i].F.Id, spec.Events[i].Name)
+		}
+		// the parameters are checked before opening the channels
+		f, err := newProcessingFunc(aggregator, i)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters of processing function %q for event %q: %v", spec.Events[i].F.Id, spec.Events[i].Name, err)
+		}
+		spec.Events[i].F.state = f
+
+		newOutputFunc, ok := outputFuncBuilder[spec.Events[i].O.Metrics]
+		if !ok {
+			return nil, fmt.Errorf("unknown output function %q for event %q", spec.Events[i].O.Metrics, spec.Events[i].Name)
+		}
+		o, err := newOutputFunc(aggregator, i)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters of output function %q for event %q: %v", spec.Events[i].O.Metrics, spec.Events[i].Name, err)
+		}
+		spec.Events[i].O.state = o
+
+		format := spec.Events[i].O.Format
+		if format == "" {
+			format = defaultFormat
+		}
+		newEncoder, ok := encoderBuilder[format]
+		if !ok {
+			return nil, fmt.Errorf("unknown output format %q for event %q", format, spec.Events[i].Name)
+		}
+		spec.Events[i].O.encoder = newEncoder()
+	}
+
 	channels := make(map[string]aggregationChannel)
 	for _, c := range spec.Channels {
 		switch c.Type {
@@ -82,35 +130,26 @@ func NewAggregator(opts AggregatorOptions, incoming <-chan *tracer.EventData, sp
 			if opts.DialInsecure {
 				dialOptions = append(dialOptions, grpc.WithInsecure())
 			} else {
-				serverName := strings.Split(c.Path, ":")[0]
-				creds := credentials.NewClientTLSFromCert(nil, serverName)
+				creds, err := c.transportCredentials()
+				if err != nil {
+					return nil, fmt.Errorf("invalid TLS settings for channel %q: %v", c.Id, err)
+				}
 				dialOptions = append(dialOptions, grpc.WithTransportCredentials(creds))
 			}
 
-			conn, err := grpc.Dial(c.Path, dialOptions...)
+			h, err := newGrpcChannel(c, dialOptions)
 			if err != nil {
-				return nil, err
-			}
-			client := tracer.NewMetricCollectorClient(conn)
-
-			h := GrpcHandler{
-				Conn:   conn,
-				Client: client,
+				return nil, fmt.Errorf("error opening grpc channel %q: %v", c.Id, err)
 			}
 
 			channels[c.Id] = aggregationChannel{Kind: Grpc, Id: c.Id, Handler: h}
 		}
 	}
 
-	aggregator := &Aggregator{
-		channels:        channels,
-		stop:            make(chan bool),
-		aggregationSpec: spec,
-	}
+	aggregator.channels = channels
 
 	for i := range spec.Events {
-		spec.Events[i].F.state = processingFuncBuilder[spec.Events[i].F.Id](aggregator, i)
-		spec.Events[i].O.state = outputFuncBuilder[spec.Events[i].O.Metrics](aggregator, i)
+		spec.Events[i].O.state.run(aggregator)
 	}
 
 	go func() {
@@ -130,22 +169,9 @@ func NewAggregator(opts AggregatorOptions, incoming <-chan *tracer.EventData, sp
 	return aggregator, nil
 }
 
-func passesRule(event *tracer.EventData, rule string) bool {
-	// TODO: don't hardcode parameter #0
-	arg, err := event.Event.GetArgN(0, event.Common.Ret)
-	if err != nil {
-		return false
-	}
-	// TODO: don't hardcode filename
-	if strings.Contains(arg, "/tmp/a.txt") {
-		return true
-	}
-	return false
-}
-
 func considerEvent(event *tracer.EventData, spec AggregationSpec) (*EventSpec, bool) {
 	for _, e := range spec.Events {
-		if event.Common.Name == e.Name && passesRule(event, e.Rule) {
+		if event.Common.Name == e.Name && e.rule.match(event) {
 			return &e, true
 		}
 	}
@@ -159,7 +185,7 @@ func (a *Aggregator) add(event *tracer.EventData) {
 		return
 	}
 
-	processedEvent := eventSpec.F.state.process(event, eventSpec.F.Parameters)
+	processedEvent := eventSpec.F.state.process(event)
 	if processedEvent == nil {
 		return
 	}
@@ -172,22 +198,27 @@ func (a *Aggregator) add(event *tracer.EventData) {
 	eventSpec.O.state.channel() <- se
 }
 
-func writeEventToFile(w io.Writer, event *SendEvent, tracerCtx tracer.Context) error {
-	evString := fmt.Sprintf("%s", event.String(tracerCtx))
-
-	outString := fmt.Sprintf("COUNT: %d\nEVENT: %s\n\n", event.counter, evString)
-
-	if _, err := io.WriteString(w, outString); err != nil {
-		return fmt.Errorf("error writing to output file: %v", err)
+// metric returns the Metric message of the event, as sent to the collectors
+func (se *SendEvent) metric() *tracer.Metric {
+	event := se.data
+	metric := event.Event.Metric()
+	if metric == nil {
+		metric = &tracer.Metric{}
+	}
+	metric.Count++
+	metric.CommonEvent = event.Common.Proto()
+	metric.Process = event.Process.Proto()
+	if se.window != nil {
+		metric.Count = se.window.Count
+		metric.Window = se.window.Proto()
 	}
 
-	return nil
+	return metric
 }
 
 func (a *Aggregator) send(se *SendEvent) error {
 	log.Printf("sending event %v...\n", se.String(a.tracerCtx))
 
-	event := se.data
 	var ch aggregationChannel
 	// TODO refactor
 	for _, v := range a.channels {
@@ -205,27 +236,27 @@ func (a *Aggregator) send(se *SendEvent) error {
 
 	switch ch.Kind {
 	case File:
-		if err := writeEventToFile(h.(io.Writer), se, a.tracerCtx); err != nil {
-			return err
-		}
-	case Grpc:
-		grpcHandler := h.(GrpcHandler)
-		stream, err := grpcHandler.Client.Process(context.Background())
+		b, err := se.spec.O.encoder.encode(se, a.tracerCtx)
 		if err != nil {
 			return err
 		}
-		metric := event.Event.Metric()
-		metric.Count++
-		metric.CommonEvent = event.Common.Proto()
-
-		if err := stream.Send(metric); err != nil {
-			return err
+		// a single write, as several outputs may share the file
+		if _, err := h.(io.Writer).Write(b); err != nil {
+			return fmt.Errorf("error writing to output file: %v", err)
 		}
-
-		_, err = stream.CloseAndRecv()
-		if err != nil {
-			return err
+	case Grpc:
+		metric := se.metric()
+		if format := se.spec.O.Format; format != "" && format != protoFormat {
+			b, err := se.spec.O.encoder.encode(se, a.tracerCtx)
+			if err != nil {
+				return err
+			}
+			metric.Format = format
+			metric.Encoded = b
 		}
+
+		// sent in a batch on the stream of the channel
+		h.(*grpcChannel).send(metric)
 	}
 
 	log.Printf("sending event ... finished\n")
@@ -235,13 +266,30 @@ func (a *Aggregator) send(se *SendEvent) error {
 
 func (a *Aggregator) Stop() {
 	a.stop <- true
+	// the output functions send their pending events, e.g. the summaries of
+	// the current windows, before the channels are closed
+	for _, e := range a.aggregationSpec.Events {
+		e.O.state.stop()
+	}
 	for _, c := range a.channels {
 		switch c.Kind {
 		case File:
 			c.Handler.(*os.File).Close()
 		case Grpc:
-			grpcHandler := c.Handler.(GrpcHandler)
-			grpcHandler.Conn.Close()
+			grpcChannel := c.Handler.(*grpcChannel)
+			grpcChannel.close()
+			log.Printf("grpc channel %q: %v\n", c.Id, grpcChannel.Stats())
+		}
+	}
+}
+
+// Stats returns the delivery counters of the grpc channels, by channel id
+func (a *Aggregator) Stats() map[string]ChannelStats {
+	stats := make(map[string]ChannelStats)
+	for _, c := range a.channels {
+		if c.Kind == Grpc {
+			stats[c.Id] = c.Handler.(*grpcChannel).Stats()
 		}
 	}
+	return stats
 }
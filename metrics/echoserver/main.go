@@ -1,9 +1,12 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"os"
 	"sort"
@@ -13,6 +16,7 @@ import (
 	ui "github.com/gizak/termui"
 	"golang.org/x/crypto/acme/autocert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/ShiftLeftSecurity/traceleft/tracer"
 )
@@ -22,8 +26,39 @@ var (
 	tlsDomain   = flag.String("tls-domain", "", "domain to use for certificate")
 	tlsCacheDir = flag.String("tls-cache-dir", "./.acme", "directory to cache obtained certificates")
 	tlsEnable   = flag.Bool("tls", false, "obtain and use lets encrypt certificate (requires -domain option)")
+	tlsCert     = flag.String("tls-cert", "", "use the certificate of this file (requires -tls-key and -tls-client-ca options)")
+	tlsKey      = flag.String("tls-key", "", "key of the -tls-cert certificate")
+	tlsClientCA = flag.String("tls-client-ca", "", "require client certificates signed by the CAs of this file")
 )
 
+// localTLSCredentials returns the credentials of a server using the
+// certificate of local files, and requiring client certificates
+func localTLSCredentials() (credentials.TransportCredentials, error) {
+	if *tlsKey == "" || *tlsClientCA == "" {
+		return nil, fmt.Errorf("-tls-key and -tls-client-ca are required with -tls-cert")
+	}
+
+	cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("error loading certificate: %v", err)
+	}
+
+	pem, err := ioutil.ReadFile(*tlsClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client CA bundle: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in client CA bundle %q", *tlsClientCA)
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}), nil
+}
+
 type server struct{}
 
 var (
@@ -36,8 +71,6 @@ func init() {
 }
 
 func (s *server) Process(stream tracer.MetricCollector_ProcessServer) error {
-	syscallCountMutex.Lock()
-	defer syscallCountMutex.Unlock()
 	for {
 		metric, err := stream.Recv()
 		if err == io.EOF {
@@ -46,9 +79,11 @@ func (s *server) Process(stream tracer.MetricCollector_ProcessServer) error {
 		if err != nil {
 			return err
 		}
+		// streams are long-lived: don't hold the lock between metrics
+		syscallCountMutex.Lock()
 		syscallCount[string(metric.CommonEvent.Name)] += metric.Count
+		syscallCountMutex.Unlock()
 	}
-	return nil
 }
 
 const sizeGaugeList = 10
@@ -166,7 +201,21 @@ func initUI(quitChan chan bool) {
 func main() {
 	flag.Parse()
 
-	grpcServer := grpc.NewServer()
+	var serverOptions []grpc.ServerOption
+	if *tlsCert != "" {
+		if *tlsEnable {
+			fmt.Fprintf(os.Stderr, "-tls and -tls-cert are mutually exclusive\n")
+			os.Exit(1)
+		}
+		creds, err := localTLSCredentials()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to set up TLS: %v\n", err)
+			os.Exit(1)
+		}
+		serverOptions = append(serverOptions, grpc.Creds(creds))
+	}
+
+	grpcServer := grpc.NewServer(serverOptions...)
 
 	tracer.RegisterMetricCollectorServer(grpcServer, &server{})
 
@@ -0,0 +1,207 @@
+// encoders of the events sent to the channels
+
+package metrics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+// encoder encodes the events of an output in its format. Encoders don't
+// depend on the kind of the channel: the encoded events are written as they
+// are to file channels, and sent in the Encoded field of the metrics of grpc
+// channels (except for collector_spec_pb, the native format of grpc
+// channels).
+type encoder interface {
+	encode(*SendEvent, tracer.Context) ([]byte, error)
+}
+
+const (
+	defaultFormat = "text"
+	protoFormat   = "collector_spec_pb"
+)
+
+/* text */
+
+func init() {
+	encoderBuilder["text"] = func() encoder { return textEncoder{} }
+}
+
+type textEncoder struct{}
+
+func (textEncoder) encode(se *SendEvent, tracerCtx tracer.Context) ([]byte, error) {
+	if se.window != nil {
+		return []byte(fmt.Sprintf("COUNT: %d\nWINDOW: %s\nEVENT: %s\n\n", se.counter, se.window, se.String(tracerCtx))), nil
+	}
+	return []byte(fmt.Sprintf("COUNT: %d\nEVENT: %s\n\n", se.counter, se.String(tracerCtx))), nil
+}
+
+/* json and ndjson */
+
+func init() {
+	encoderBuilder["json"] = func() encoder { return jsonEncoder{indent: true} }
+	encoderBuilder["ndjson"] = func() encoder { return jsonEncoder{} }
+}
+
+// eventRecord is what is encoded for each event by the structured formats
+type eventRecord struct {
+	Count     int              `json:"count"`
+	Timestamp uint64           `json:"timestamp"`
+	Name      string           `json:"name"`
+	Pid       int64            `json:"pid"`
+	Ret       int64            `json:"ret"`
+	Hash      uint64           `json:"hash"`
+	Duration  uint64           `json:"duration_ns"`
+	Args      tracer.EventArgs `json:"args"`
+
+	Process *tracer.ProcessInfo `json:"process,omitempty"`
+
+	// summary of the window of the event, for the window output functions
+	Window *windowSummary `json:"window,omitempty"`
+}
+
+func newEventRecord(se *SendEvent) *eventRecord {
+	event := se.data
+	return &eventRecord{
+		Count:     se.counter,
+		Timestamp: event.Common.Timestamp,
+		Name:      event.Common.Name,
+		Pid:       event.Common.Pid,
+		Ret:       event.Common.Ret,
+		Hash:      event.Common.Hash,
+		Duration:  event.Common.Duration,
+		Args:      event.Event.Args(event.Common.Ret),
+		Process:   event.Process,
+		Window:    se.window,
+	}
+}
+
+// jsonEncoder encodes each event as a JSON object, indented for json and on
+// a single line for ndjson
+type jsonEncoder struct {
+	indent bool
+}
+
+func (e jsonEncoder) encode(se *SendEvent, _ tracer.Context) ([]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if e.indent {
+		b, err = json.MarshalIndent(newEventRecord(se), "", "    ")
+	} else {
+		b, err = json.Marshal(newEventRecord(se))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error encoding event: %v", err)
+	}
+
+	return append(b, '\n'), nil
+}
+
+/* collector_spec_pb */
+
+func init() {
+	encoderBuilder[protoFormat] = func() encoder { return protoEncoder{} }
+}
+
+// protoEncoder encodes each event as a Metric message, prefixed with its
+// length as a varint (like the writeDelimitedTo of the other protobuf
+// implementations)
+type protoEncoder struct{}
+
+func (protoEncoder) encode(se *SendEvent, _ tracer.Context) ([]byte, error) {
+	b, err := proto.Marshal(se.metric())
+	if err != nil {
+		return nil, fmt.Errorf("error encoding event: %v", err)
+	}
+
+	return append(proto.EncodeVarint(uint64(len(b))), b...), nil
+}
+
+/* csv */
+
+func init() {
+	encoderBuilder["csv"] = func() encoder { return &csvEncoder{} }
+}
+
+var csvHeader = []string{"count", "timestamp", "name", "pid", "ret", "hash", "duration_ns",
+	"comm", "exe", "container_id", "args",
+	"window_start", "window_end", "window_keys", "window_field", "window_min", "window_max", "window_sum", "window_percentiles"}
+
+// csvEncoder encodes each event as a CSV record, after a header for the
+// first one. The arguments, which depend on the event, are in a single column
+// as name=value pairs separated by spaces, like the keys and percentiles of
+// the window summaries. The window columns are empty without a summary.
+type csvEncoder struct {
+	sync.Mutex
+	headerDone bool
+}
+
+func (e *csvEncoder) encode(se *SendEvent, _ tracer.Context) ([]byte, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if !e.headerDone {
+		w.Write(csvHeader)
+		e.headerDone = true
+	}
+
+	r := newEventRecord(se)
+	var comm, exe, containerID string
+	if p := r.Process; p != nil {
+		comm, exe = p.Comm, p.Exe
+		if p.ContainerInfo != nil {
+			containerID = p.ContainerInfo.ID
+		}
+	}
+	args := make([]string, len(r.Args))
+	for i, arg := range r.Args {
+		args[i] = fmt.Sprintf("%s=%v", arg.Name, arg.Value)
+	}
+
+	window := make([]string, 8)
+	if ws := r.Window; ws != nil {
+		window = []string{
+			strconv.FormatInt(ws.Start.UnixNano(), 10),
+			strconv.FormatInt(ws.End.UnixNano(), 10),
+			ws.keysString(),
+			ws.Field,
+			strconv.FormatFloat(ws.Min, 'g', -1, 64),
+			strconv.FormatFloat(ws.Max, 'g', -1, 64),
+			strconv.FormatFloat(ws.Sum, 'g', -1, 64),
+			ws.percentilesString(),
+		}
+	}
+
+	w.Write(append([]string{
+		strconv.Itoa(r.Count),
+		strconv.FormatUint(r.Timestamp, 10),
+		r.Name,
+		strconv.FormatInt(r.Pid, 10),
+		strconv.FormatInt(r.Ret, 10),
+		strconv.FormatUint(r.Hash, 10),
+		strconv.FormatUint(r.Duration, 10),
+		comm,
+		exe,
+		containerID,
+		strings.Join(args, " "),
+	}, window...))
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("error encoding event: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
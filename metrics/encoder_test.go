@@ -0,0 +1,290 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+func testSendEvents() (*SendEvent, *SendEvent) {
+	// a comma and quotes, to be escaped in CSV
+	data := openEvent(`/tmp/a,"b".txt`, 42, -2, 0x80000)
+	data.Process.ContainerInfo = &tracer.ContainerInfo{Runtime: "docker", ID: "abc"}
+
+	start := time.Unix(100, 0)
+	windowed := &SendEvent{
+		data:    openEvent("/tmp/c", 43, 3, 0),
+		counter: 7,
+		window: &windowSummary{
+			Start:       start,
+			End:         start.Add(10 * time.Second),
+			Keys:        []windowKey{{"comm", "nginx"}, {"Filename", "/tmp/c"}},
+			Count:       7,
+			Field:       "duration",
+			Min:         1,
+			Max:         9.5,
+			Sum:         30,
+			Percentiles: []percentileValue{{50, 4}, {99, 9.5}},
+		},
+	}
+	return &SendEvent{data: data, counter: 3}, windowed
+}
+
+func TestEncoderFormats(t *testing.T) {
+	for _, format := range []string{"text", "json", "ndjson", "collector_spec_pb", "csv"} {
+		if _, ok := encoderBuilder[format]; !ok {
+			t.Errorf("no encoder for format %q", format)
+		}
+	}
+	if defaultFormat != "text" {
+		t.Errorf("default format %q, want text", defaultFormat)
+	}
+}
+
+func TestTextEncoder(t *testing.T) {
+	plain, windowed := testSendEvents()
+	e := encoderBuilder["text"]()
+
+	b, err := e.encode(plain, tracer.Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); !strings.HasPrefix(s, "COUNT: 3\nEVENT: ") || !strings.HasSuffix(s, "\n\n") ||
+		!strings.Contains(s, "Flags O_RDONLY|O_CLOEXEC") || strings.Contains(s, "WINDOW") {
+		t.Errorf("unexpected text encoding %q", s)
+	}
+
+	b, err = e.encode(windowed, tracer.Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); !strings.HasPrefix(s, "COUNT: 7\nWINDOW: ") || !strings.Contains(s, "{comm=nginx Filename=/tmp/c} count 7 duration") {
+		t.Errorf("unexpected text encoding %q", s)
+	}
+}
+
+func TestJSONEncoders(t *testing.T) {
+	plain, windowed := testSendEvents()
+
+	for _, format := range []string{"json", "ndjson"} {
+		e := encoderBuilder[format]()
+
+		b, err := e.encode(plain, tracer.Context{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Count(string(b), "\n")
+		if format == "ndjson" && lines != 1 || format == "json" && lines <= 1 || !bytes.HasSuffix(b, []byte("\n")) {
+			t.Errorf("%s: unexpected layout %q", format, b)
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal(b, &record); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		want := map[string]interface{}{
+			"count":       3.0,
+			"timestamp":   0.0,
+			"name":        "open",
+			"pid":         42.0,
+			"ret":         -2.0,
+			"hash":        0.0,
+			"duration_ns": 0.0,
+			"args": map[string]interface{}{
+				"Filename": `/tmp/a,"b".txt`,
+				"Flags":    "O_RDONLY|O_CLOEXEC",
+				"Mode":     "0644/rw-r--r--",
+			},
+		}
+		process, _ := record["process"].(map[string]interface{})
+		delete(record, "process")
+		if !reflect.DeepEqual(record, want) {
+			t.Errorf("%s: got %v, want %v", format, record, want)
+		}
+		if process["comm"] != "nginx" {
+			t.Errorf("%s: unexpected process %v", format, process)
+		}
+
+		b, err = e.encode(windowed, tracer.Context{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var withWindow struct {
+			Count  int            `json:"count"`
+			Window *windowSummary `json:"window"`
+		}
+		if err := json.Unmarshal(b, &withWindow); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if withWindow.Count != 7 || withWindow.Window == nil {
+			t.Fatalf("%s: got count %d and window %v", format, withWindow.Count, withWindow.Window)
+		}
+		// the times are decoded in UTC
+		gotWindow, wantWindow := *withWindow.Window, *windowed.window
+		if !gotWindow.Start.Equal(wantWindow.Start) || !gotWindow.End.Equal(wantWindow.End) {
+			t.Errorf("%s: got window %v - %v, want %v - %v", format, gotWindow.Start, gotWindow.End, wantWindow.Start, wantWindow.End)
+		}
+		gotWindow.Start, gotWindow.End, wantWindow.Start, wantWindow.End = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+		if !reflect.DeepEqual(gotWindow, wantWindow) {
+			t.Errorf("%s: got window %+v, want %+v", format, gotWindow, wantWindow)
+		}
+	}
+}
+
+func TestProtoEncoder(t *testing.T) {
+	plain, windowed := testSendEvents()
+	e := encoderBuilder[protoFormat]()
+
+	// the messages are length-delimited, so that they can be concatenated
+	var stream []byte
+	for _, se := range []*SendEvent{plain, windowed} {
+		b, err := e.encode(se, tracer.Context{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream = append(stream, b...)
+	}
+
+	var metrics []*tracer.Metric
+	for len(stream) > 0 {
+		length, n := proto.DecodeVarint(stream)
+		if n == 0 || uint64(len(stream)-n) < length {
+			t.Fatalf("truncated message")
+		}
+		m := &tracer.Metric{}
+		if err := proto.Unmarshal(stream[n:n+int(length)], m); err != nil {
+			t.Fatal(err)
+		}
+		metrics = append(metrics, m)
+		stream = stream[n+int(length):]
+	}
+
+	if len(metrics) != 2 {
+		t.Fatalf("decoded %d messages, want 2", len(metrics))
+	}
+	m := metrics[0]
+	if m.Count != 1 || m.CommonEvent.GetName() != "open" || m.CommonEvent.GetPid() != 42 ||
+		m.OpenEvent == nil || m.OpenEvent.Flags != 0x80000 || m.Window != nil {
+		t.Errorf("unexpected metric %v", m)
+	}
+	if !bytes.HasPrefix(m.OpenEvent.Filename, []byte(`/tmp/a,"b".txt`)) {
+		t.Errorf("unexpected filename %q", m.OpenEvent.Filename)
+	}
+	m = metrics[1]
+	if m.Count != 7 || m.Window == nil || m.Window.Field != "duration" || len(m.Window.Percentiles) != 2 {
+		t.Errorf("unexpected window metric %v", m)
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	plain, windowed := testSendEvents()
+	if len(csvHeader) != 19 {
+		t.Errorf("%d columns in the header, want 19", len(csvHeader))
+	}
+
+	e := encoderBuilder["csv"]()
+	var out bytes.Buffer
+	for _, se := range []*SendEvent{plain, windowed, plain} {
+		b, err := e.encode(se, tracer.Context{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Write(b)
+	}
+
+	r := csv.NewReader(&out)
+	// every record must have as many columns as the header
+	r.FieldsPerRecord = len(csvHeader)
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	// the header is only written once
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want the header and 3 events: %q", len(records), records)
+	}
+	if !reflect.DeepEqual(records[0], csvHeader) {
+		t.Errorf("got header %q, want %q", records[0], csvHeader)
+	}
+
+	column := func(record []string, name string) string {
+		for i, h := range csvHeader {
+			if h == name {
+				return record[i]
+			}
+		}
+		t.Fatalf("no column %q", name)
+		return ""
+	}
+	want := map[string]string{
+		"count":        "3",
+		"timestamp":    "0",
+		"name":         "open",
+		"pid":          "42",
+		"ret":          "-2",
+		"hash":         "0",
+		"duration_ns":  "0",
+		"comm":         "nginx",
+		"exe":          "/usr/sbin/nginx",
+		"container_id": "abc",
+		"args":         `Filename=/tmp/a,"b".txt Flags=O_RDONLY|O_CLOEXEC Mode=0644/rw-r--r--`,
+	}
+	for _, h := range csvHeader {
+		got := column(records[1], h)
+		if strings.HasPrefix(h, "window_") {
+			if got != "" {
+				t.Errorf("column %s: got %q without a window", h, got)
+			}
+			continue
+		}
+		if got != want[h] {
+			t.Errorf("column %s: got %q, want %q", h, got, want[h])
+		}
+	}
+	if !reflect.DeepEqual(records[3], records[1]) {
+		t.Errorf("the same event was encoded differently: %q and %q", records[1], records[3])
+	}
+
+	wantWindow := map[string]string{
+		"count":              "7",
+		"window_start":       "100000000000",
+		"window_end":         "110000000000",
+		"window_keys":        "comm=nginx Filename=/tmp/c",
+		"window_field":       "duration",
+		"window_min":         "1",
+		"window_max":         "9.5",
+		"window_sum":         "30",
+		"window_percentiles": "p50=4 p99=9.5",
+	}
+	for h, w := range wantWindow {
+		if got := column(records[2], h); got != w {
+			t.Errorf("window column %s: got %q, want %q", h, got, w)
+		}
+	}
+
+	// each output has its own encoder, and header
+	b, err := encoderBuilder["csv"]().encode(plain, tracer.Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, []byte(strings.Join(csvHeader, ",")+"\n")) {
+		t.Errorf("no header for a new encoder: %q", b)
+	}
+}
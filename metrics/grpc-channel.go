@@ -0,0 +1,392 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	defaultAckTimeout    = 10 * time.Second
+	defaultStreamBatches = 10
+	defaultSpoolMaxBytes = 64 << 20
+
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// ChannelStats are the delivery counters of a grpc channel
+type ChannelStats struct {
+	// metrics acknowledged by the collector
+	Sent uint64
+	// spooled metrics acknowledged by the collector once it was back
+	Replayed uint64
+	// metrics written to the spool while the collector was unreachable
+	Spooled uint64
+	// metrics lost because the queue or the spool was full, or because
+	// there is no spool
+	Dropped uint64
+	// streams opened to the collector, and failures to open one
+	Connects, ConnectFailures uint64
+}
+
+func (s ChannelStats) String() string {
+	return fmt.Sprintf("sent %d replayed %d spooled %d dropped %d connects %d connect failures %d",
+		s.Sent, s.Replayed, s.Spooled, s.Dropped, s.Connects, s.ConnectFailures)
+}
+
+// grpcChannel sends the metrics of a channel to a collector on
+// MetricCollector.Process streams, in batches. Sending a metric only means it
+// was buffered: the collector acknowledges the metrics of a stream when it is
+// closed (CloseAndRecv). So the stream is closed, and a new one opened, after
+// streamBatches batches or when no batch was sent for a flush interval, and
+// the metrics sent on it are kept until they are acknowledged. A stream that
+// isn't acknowledged within the ack timeout fails, so that a stalled collector
+// doesn't block the channel.
+//
+// When a stream fails, the metrics it didn't acknowledge are written to the
+// spool (if any), as well as the next ones while the stream is reopened with
+// an exponential backoff. Once the collector is back, the spool is replayed on
+// the new stream, and only emptied once this stream is acknowledged. With a
+// spool, delivery is thus at least once, as long as the spool isn't full: the
+// collector may receive metrics again if a stream fails before being
+// acknowledged.
+type grpcChannel struct {
+	id     string
+	conn   *grpc.ClientConn
+	client tracer.MetricCollectorClient
+
+	batchSize     int
+	flushInterval time.Duration
+	ackTimeout    time.Duration
+	streamBatches int
+	spool         *spool
+
+	queue chan *tracer.Metric
+	stop  chan struct{}
+	done  chan struct{}
+
+	// only used by the goroutine running run()
+	stream      tracer.MetricCollector_ProcessClient
+	cancel      context.CancelFunc
+	backoff     time.Duration
+	nextConnect time.Time
+	// metrics sent on the stream, besides the replayed ones, and batches
+	unacked []*tracer.Metric
+	batches int
+	// spooled metrics sent on the stream, the spool is only replayed once per
+	// stream
+	replayed   int
+	isReplayed bool
+
+	statsMu sync.Mutex
+	stats   ChannelStats
+}
+
+func newGrpcChannel(c Channel, dialOptions []grpc.DialOption) (*grpcChannel, error) {
+	conn, err := grpc.Dial(c.Path, dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := &grpcChannel{
+		id:            c.Id,
+		conn:          conn,
+		client:        tracer.NewMetricCollectorClient(conn),
+		batchSize:     c.BatchSize,
+		flushInterval: defaultFlushInterval,
+		ackTimeout:    defaultAckTimeout,
+		streamBatches: c.StreamBatches,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if ch.batchSize <= 0 {
+		ch.batchSize = defaultBatchSize
+	}
+	if ch.streamBatches <= 0 {
+		ch.streamBatches = defaultStreamBatches
+	}
+	if c.FlushInterval != "" {
+		ch.flushInterval, err = time.ParseDuration(c.FlushInterval)
+		if err != nil || ch.flushInterval <= 0 {
+			conn.Close()
+			return nil, fmt.Errorf("invalid flush interval %q for channel %q", c.FlushInterval, c.Id)
+		}
+	}
+	if c.AckTimeout != "" {
+		ch.ackTimeout, err = time.ParseDuration(c.AckTimeout)
+		if err != nil || ch.ackTimeout <= 0 {
+			conn.Close()
+			return nil, fmt.Errorf("invalid ack timeout %q for channel %q", c.AckTimeout, c.Id)
+		}
+	}
+	if c.SpoolDir != "" {
+		maxBytes := c.SpoolMaxBytes
+		if maxBytes <= 0 {
+			maxBytes = defaultSpoolMaxBytes
+		}
+		ch.spool, err = openSpool(c.SpoolDir, c.Id, maxBytes)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	ch.queue = make(chan *tracer.Metric, 4*ch.batchSize)
+
+	go ch.run()
+
+	return ch, nil
+}
+
+// send queues a metric, without blocking the aggregator
+func (c *grpcChannel) send(m *tracer.Metric) {
+	select {
+	case c.queue <- m:
+	default:
+		c.count(func(s *ChannelStats) { s.Dropped++ })
+	}
+}
+
+// Stats returns the delivery counters of the channel
+func (c *grpcChannel) Stats() ChannelStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	return c.stats
+}
+
+func (c *grpcChannel) count(f func(*ChannelStats)) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	f(&c.stats)
+}
+
+// close sends or spools the queued metrics and closes the connection
+func (c *grpcChannel) close() error {
+	close(c.stop)
+	<-c.done
+	return c.conn.Close()
+}
+
+func (c *grpcChannel) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
+	var batch []*tracer.Metric
+	for {
+		select {
+		case m := <-c.queue:
+			batch = append(batch, m)
+			if len(batch) < c.batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				// get the metrics of an idle stream acknowledged,
+				// or retry replaying the spool
+				if c.stream != nil {
+					c.closeStream()
+				} else if c.spool != nil && !c.spool.empty() {
+					c.flush(nil)
+				}
+				continue
+			}
+		case <-c.stop:
+			for len(c.queue) > 0 {
+				batch = append(batch, <-c.queue)
+			}
+			c.flush(batch)
+			c.closeStream()
+			return
+		}
+		c.flush(batch)
+		batch = nil
+	}
+}
+
+// flush sends a batch, after the spooled metrics if the collector is back
+func (c *grpcChannel) flush(batch []*tracer.Metric) {
+	if c.stream == nil && !c.connect() {
+		c.spoolMetrics(batch)
+		return
+	}
+
+	if c.spool != nil && !c.spool.empty() && !c.isReplayed {
+		if !c.replay() {
+			c.spoolMetrics(batch)
+			return
+		}
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	for i, m := range batch {
+		if err := c.stream.Send(m); err != nil {
+			c.fail(err)
+			c.spoolMetrics(batch[i:])
+			return
+		}
+		c.unacked = append(c.unacked, m)
+	}
+
+	c.batches++
+	if c.batches >= c.streamBatches {
+		c.closeStream()
+	}
+}
+
+// connect opens a stream, unless the last attempt failed less than the
+// backoff ago. It returns whether the stream is open.
+func (c *grpcChannel) connect() bool {
+	if time.Now().Before(c.nextConnect) {
+		return false
+	}
+
+	// don't wait for a connection known to be failing
+	if c.conn.GetState() == connectivity.TransientFailure {
+		c.fail(fmt.Errorf("connection in transient failure"))
+		c.count(func(s *ChannelStats) { s.ConnectFailures++ })
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.client.Process(ctx)
+	if err != nil {
+		cancel()
+		c.fail(err)
+		c.count(func(s *ChannelStats) { s.ConnectFailures++ })
+		return false
+	}
+
+	c.stream = stream
+	c.cancel = cancel
+	c.backoff = 0
+	c.count(func(s *ChannelStats) { s.Connects++ })
+
+	return true
+}
+
+// fail drops the stream, spools the metrics it didn't acknowledge and
+// schedules the next connection attempt
+func (c *grpcChannel) fail(err error) {
+	if c.stream != nil {
+		log.Printf("stream of channel %q failed: %v\n", c.id, err)
+		c.cancel()
+		c.dropStream()
+		// the replayed metrics are still in the spool
+		unacked := c.unacked
+		c.unacked = nil
+		c.spoolMetrics(unacked)
+	}
+
+	if c.backoff == 0 {
+		c.backoff = minBackoff
+	} else if c.backoff *= 2; c.backoff > maxBackoff {
+		c.backoff = maxBackoff
+	}
+	c.nextConnect = time.Now().Add(c.backoff)
+}
+
+// replay sends the spooled metrics on the stream. The spool is emptied once
+// the stream is acknowledged.
+func (c *grpcChannel) replay() bool {
+	metrics, err := c.spool.load()
+	if err != nil || len(metrics) == 0 {
+		if err != nil {
+			log.Printf("failed to load the spool of channel %q, dropping it: %v\n", c.id, err)
+		}
+		c.clearSpool()
+		return true
+	}
+
+	c.isReplayed = true
+	for _, m := range metrics {
+		if err := c.stream.Send(m); err != nil {
+			c.fail(err)
+			return false
+		}
+		c.replayed++
+	}
+
+	return true
+}
+
+func (c *grpcChannel) clearSpool() {
+	if err := c.spool.clear(); err != nil {
+		log.Printf("failed to clear the spool of channel %q: %v\n", c.id, err)
+	}
+}
+
+func (c *grpcChannel) spoolMetrics(metrics []*tracer.Metric) {
+	if len(metrics) == 0 {
+		return
+	}
+
+	spooled := 0
+	if c.spool != nil {
+		var err error
+		spooled, err = c.spool.push(metrics)
+		if err != nil {
+			log.Printf("failed to spool metrics of channel %q: %v\n", c.id, err)
+		}
+	}
+
+	c.count(func(s *ChannelStats) {
+		s.Spooled += uint64(spooled)
+		s.Dropped += uint64(len(metrics) - spooled)
+	})
+}
+
+// closeStream closes the stream, waiting up to the ack timeout for the
+// collector to acknowledge the metrics sent on it. The next batch opens a new
+// stream.
+func (c *grpcChannel) closeStream() {
+	if c.stream == nil {
+		return
+	}
+
+	// canceling the stream unblocks CloseAndRecv
+	timer := time.AfterFunc(c.ackTimeout, c.cancel)
+	_, err := c.stream.CloseAndRecv()
+	if !timer.Stop() {
+		err = fmt.Errorf("not acknowledged within %v", c.ackTimeout)
+	}
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	c.cancel()
+
+	sent, replayed := len(c.unacked), c.replayed
+	if c.isReplayed {
+		c.clearSpool()
+	}
+	c.unacked = nil
+	c.dropStream()
+	c.count(func(s *ChannelStats) {
+		s.Sent += uint64(sent)
+		s.Replayed += uint64(replayed)
+	})
+}
+
+// dropStream forgets the stream and the replay on it, c.unacked is left to
+// the caller
+func (c *grpcChannel) dropStream() {
+	c.stream = nil
+	c.batches = 0
+	c.replayed = 0
+	c.isReplayed = false
+}
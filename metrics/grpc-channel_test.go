@@ -0,0 +1,403 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+// testCollector is a MetricCollector like the echoserver, recording the
+// metrics it receives by their Count
+type testCollector struct {
+	mu       sync.Mutex
+	received []uint64
+	// streams acknowledged
+	acked int
+	// number of the next streams to fail instead of acknowledging them
+	failAcks int
+	// whether the streams are never acknowledged, until canceled
+	stallAcks bool
+}
+
+func (c *testCollector) Process(stream tracer.MetricCollector_ProcessServer) error {
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF {
+			c.mu.Lock()
+			stall := c.stallAcks
+			c.mu.Unlock()
+			if stall {
+				<-stream.Context().Done()
+				return stream.Context().Err()
+			}
+
+			c.mu.Lock()
+			fail := c.failAcks > 0
+			if fail {
+				c.failAcks--
+			} else {
+				c.acked++
+			}
+			c.mu.Unlock()
+			if fail {
+				return errors.New("collector restarting")
+			}
+			return stream.SendAndClose(&tracer.Empty{})
+		}
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.received = append(c.received, m.Count)
+		c.mu.Unlock()
+	}
+}
+
+func (c *testCollector) state() ([]uint64, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]uint64(nil), c.received...), c.acked
+}
+
+// startCollector serves a testCollector on addr, or a free port of localhost
+// if addr is empty
+func startCollector(t *testing.T, addr string) (*testCollector, *grpc.Server, string) {
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := &testCollector{}
+	s := grpc.NewServer()
+	tracer.RegisterMetricCollectorServer(s, collector)
+	go s.Serve(l)
+
+	return collector, s, l.Addr().String()
+}
+
+// unusedAddr returns an address of localhost nobody listens on, for now
+func unusedAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func newTestChannel(t *testing.T, c Channel) *grpcChannel {
+	c.Type = "grpc"
+	ch, err := newGrpcChannel(c, []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithBackoffMaxDelay(50 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch
+}
+
+func sendMetrics(ch *grpcChannel, from, to uint64) {
+	for i := from; i < to; i++ {
+		ch.send(&tracer.Metric{Count: i})
+	}
+}
+
+// waitFor waits until cond is true
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(15 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func tempSpoolDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "grpc-channel-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestGrpcChannelBatches(t *testing.T) {
+	collector, s, addr := startCollector(t, "")
+	defer s.Stop()
+
+	ch := newTestChannel(t, Channel{Id: "1", Path: addr, BatchSize: 3, StreamBatches: 2, FlushInterval: "1h"})
+
+	// the stream is acknowledged after 2 batches
+	sendMetrics(ch, 0, 6)
+	waitFor(t, "6 metrics sent", func() bool { return ch.Stats().Sent == 6 })
+	received, acked := collector.state()
+	if len(received) != 6 || acked != 1 {
+		t.Errorf("got %v in %d streams, want 6 metrics in 1 stream", received, acked)
+	}
+	for i, count := range received {
+		if count != uint64(i) {
+			t.Errorf("metrics received out of order: %v", received)
+			break
+		}
+	}
+
+	// a partial batch waits for the flush interval, or the close
+	sendMetrics(ch, 6, 8)
+	time.Sleep(50 * time.Millisecond)
+	if received, _ := collector.state(); len(received) != 6 {
+		t.Errorf("partial batch sent before the flush interval: %v", received)
+	}
+	if err := ch.close(); err != nil {
+		t.Fatal(err)
+	}
+	received, acked = collector.state()
+	if len(received) != 8 || acked != 2 {
+		t.Errorf("got %v in %d streams, want 8 metrics in 2 streams", received, acked)
+	}
+	if stats := ch.Stats(); stats.Sent != 8 || stats.Connects != 2 || stats.Spooled != 0 || stats.Dropped != 0 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+}
+
+func TestGrpcChannelIdleStream(t *testing.T) {
+	collector, s, addr := startCollector(t, "")
+	defer s.Stop()
+
+	ch := newTestChannel(t, Channel{Id: "1", Path: addr, FlushInterval: "20ms"})
+	defer ch.close()
+
+	// a lone metric is flushed, then acknowledged when the stream is idle
+	sendMetrics(ch, 0, 1)
+	waitFor(t, "the metric acknowledged", func() bool { return ch.Stats().Sent == 1 })
+	if received, acked := collector.state(); len(received) != 1 || acked != 1 {
+		t.Errorf("got %v in %d streams", received, acked)
+	}
+}
+
+func TestGrpcChannelReconnect(t *testing.T) {
+	addr := unusedAddr(t)
+	dir := tempSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	ch := newTestChannel(t, Channel{Id: "1", Path: addr, BatchSize: 2, FlushInterval: "10ms", SpoolDir: dir})
+	defer ch.close()
+
+	// the collector is down
+	sendMetrics(ch, 0, 4)
+	waitFor(t, "4 metrics spooled", func() bool { return ch.Stats().Spooled == 4 })
+
+	// failing connections are retried with a backoff, not every flush
+	time.Sleep(300 * time.Millisecond)
+	if stats := ch.Stats(); stats.ConnectFailures == 0 || stats.ConnectFailures > 10 {
+		t.Errorf("%d connect failures in 300ms, with a flush every 10ms", stats.ConnectFailures)
+	}
+
+	// the spool is replayed once the collector is back
+	collector, s, _ := startCollector(t, addr)
+	defer s.Stop()
+	waitFor(t, "4 metrics replayed", func() bool { return ch.Stats().Replayed == 4 })
+	sendMetrics(ch, 4, 6)
+	waitFor(t, "2 metrics sent", func() bool { return ch.Stats().Sent == 2 })
+
+	received, _ := collector.state()
+	if len(received) != 6 {
+		t.Errorf("got %v, want the 6 metrics", received)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "channel-1.spool")); !os.IsNotExist(err) {
+		t.Errorf("the spool wasn't removed once replayed: %v", err)
+	}
+	if stats := ch.Stats(); stats.Dropped != 0 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+}
+
+// TestGrpcChannelUnacknowledged checks that the metrics of a stream that
+// fails before being acknowledged are spooled and replayed
+func TestGrpcChannelUnacknowledged(t *testing.T) {
+	collector, s, addr := startCollector(t, "")
+	defer s.Stop()
+	collector.failAcks = 1
+
+	dir := tempSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	ch := newTestChannel(t, Channel{Id: "1", Path: addr, BatchSize: 2, StreamBatches: 1, FlushInterval: "10ms", SpoolDir: dir})
+	defer ch.close()
+
+	sendMetrics(ch, 0, 2)
+	waitFor(t, "2 metrics replayed", func() bool { return ch.Stats().Replayed == 2 })
+
+	stats := ch.Stats()
+	if stats.Sent != 0 || stats.Spooled != 2 || stats.Dropped != 0 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+	// at least once: the collector got them twice
+	received, acked := collector.state()
+	if len(received) != 4 || acked != 1 {
+		t.Errorf("got %v in %d acknowledged streams, want the 2 metrics twice in 1 stream", received, acked)
+	}
+}
+
+// TestGrpcChannelAckTimeout checks that a collector which doesn't
+// acknowledge the streams doesn't block the channel
+func TestGrpcChannelAckTimeout(t *testing.T) {
+	collector, s, addr := startCollector(t, "")
+	defer s.Stop()
+	collector.stallAcks = true
+
+	dir := tempSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	ch := newTestChannel(t, Channel{Id: "1", Path: addr, BatchSize: 2, StreamBatches: 1, FlushInterval: "1h", AckTimeout: "50ms", SpoolDir: dir})
+	sendMetrics(ch, 0, 2)
+	waitFor(t, "2 metrics spooled", func() bool { return ch.Stats().Spooled == 2 })
+
+	closed := make(chan error)
+	go func() { closed <- ch.close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("close blocked by the stalled collector")
+	}
+	if stats := ch.Stats(); stats.Sent != 0 || stats.Dropped != 0 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+
+	if _, err := newGrpcChannel(Channel{Id: "2", Path: addr, AckTimeout: "0s"}, []grpc.DialOption{grpc.WithInsecure()}); err == nil {
+		t.Errorf("no error for a zero ack timeout")
+	}
+}
+
+func TestGrpcChannelSpoolBounds(t *testing.T) {
+	addr := unusedAddr(t)
+	dir := tempSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	// room for 3 metrics of the same size
+	size := int64(4 + proto.Size(&tracer.Metric{Count: 1}))
+	ch := newTestChannel(t, Channel{Id: "1", Path: addr, BatchSize: 5, FlushInterval: "1h", SpoolDir: dir, SpoolMaxBytes: 3 * size})
+	sendMetrics(ch, 1, 6)
+	if err := ch.close(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := ch.Stats(); stats.Spooled != 3 || stats.Dropped != 2 {
+		t.Errorf("got %v, want 3 metrics spooled and 2 dropped", stats)
+	}
+
+	// without a spool, metrics are dropped
+	noSpool := newTestChannel(t, Channel{Id: "2", Path: addr, BatchSize: 5, FlushInterval: "1h"})
+	sendMetrics(noSpool, 1, 6)
+	if err := noSpool.close(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := noSpool.Stats(); stats.Spooled != 0 || stats.Dropped != 5 {
+		t.Errorf("got %v, want 5 metrics dropped", stats)
+	}
+
+	// the spool is kept across restarts, and replayed by the next channel
+	collector, s, _ := startCollector(t, addr)
+	defer s.Stop()
+	ch = newTestChannel(t, Channel{Id: "1", Path: addr, FlushInterval: "10ms", SpoolDir: dir, SpoolMaxBytes: 3 * size})
+	defer ch.close()
+	waitFor(t, "3 metrics replayed", func() bool { return ch.Stats().Replayed == 3 })
+	if received, _ := collector.state(); len(received) != 3 || received[0] != 1 || received[2] != 3 {
+		t.Errorf("got %v, want the 3 oldest metrics", received)
+	}
+}
+
+func TestSpool(t *testing.T) {
+	dir := tempSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	size := int64(4 + proto.Size(&tracer.Metric{Count: 1}))
+	s, err := openSpool(dir, "x", 2*size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.empty() {
+		t.Errorf("new spool not empty")
+	}
+	if n, err := s.push([]*tracer.Metric{{Count: 1}}); n != 1 || err != nil {
+		t.Errorf("push: %d, %v", n, err)
+	}
+	if n, err := s.push([]*tracer.Metric{{Count: 2}, {Count: 3}}); n != 1 || err != nil {
+		t.Errorf("push to a full spool: %d, %v", n, err)
+	}
+
+	// reopened, e.g. after a restart
+	s, err = openSpool(dir, "x", 2*size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, err := s.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 2 || metrics[0].Count != 1 || metrics[1].Count != 2 {
+		t.Errorf("loaded %v", metrics)
+	}
+
+	// a record truncated by a crash is ignored
+	f, err := os.OpenFile(filepath.Join(dir, "channel-x.spool"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{10, 0, 0, 0, 1})
+	f.Close()
+	if metrics, err := s.load(); err != nil || len(metrics) != 2 {
+		t.Errorf("loaded %v, %v from a truncated spool", metrics, err)
+	}
+
+	// and so is the rest of the file after an implausible length
+	if err := s.clear(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.push([]*tracer.Metric{{Count: 1}}); n != 1 || err != nil {
+		t.Errorf("push: %d, %v", n, err)
+	}
+	f, err = os.OpenFile(filepath.Join(dir, "channel-x.spool"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0xff, 0xff, 0xff, 0xff, 1, 2, 3})
+	f.Close()
+	if metrics, err := s.load(); err != nil || len(metrics) != 1 {
+		t.Errorf("loaded %v, %v from a corrupt spool", metrics, err)
+	}
+
+	if err := s.clear(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.empty() {
+		t.Errorf("spool not empty once cleared")
+	}
+	if metrics, err := s.load(); err != nil || len(metrics) != 0 {
+		t.Errorf("loaded %v, %v from a cleared spool", metrics, err)
+	}
+
+	// the channel id can't escape the spool directory
+	s, err = openSpool(dir, "../a/b", size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(s.path) != dir {
+		t.Errorf("spool %s outside of %s", s.path, dir)
+	}
+}
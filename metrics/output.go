@@ -3,35 +3,62 @@
 package metrics
 
 import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
 )
 
 type outputFunc interface {
 	channel() chan *SendEvent
 	process(d time.Duration, aggregator *Aggregator)
+	// run starts process, once the channels of the aggregator are open
+	run(aggregator *Aggregator)
+	// stop sends the pending events and stops process
+	stop()
 }
 
 /* alerts_per_sec */
 
 func init() {
-	outputFuncBuilder["alerts_per_sec"] = func(a *Aggregator, i int) outputFunc {
-		o := &eventsPerS{
+	outputFuncBuilder["alerts_per_sec"] = func(a *Aggregator, i int) (outputFunc, error) {
+		// no parameters
+		if err := newParamParser(a.aggregationSpec.Events[i].O.Parameters).done(); err != nil {
+			return nil, err
+		}
+		return &eventsPerS{
 			ch:      make(chan *SendEvent),
+			done:    make(chan chan bool),
 			counter: 0,
-		}
-		go o.process(time.Second, a)
-		return o
+		}, nil
 	}
 }
 
 type eventsPerS struct {
 	ch      chan *SendEvent
+	done    chan chan bool
 	counter int
 }
 
 func (e eventsPerS) process(d time.Duration, aggregator *Aggregator) {
-	ticker := time.NewTicker(d).C
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
 	var savedEv *SendEvent
+	send := func() {
+		if savedEv != nil {
+			savedEv.counter = e.counter
+			if err := aggregator.send(savedEv); err != nil {
+				log.Printf("error sending event: %v\n", err)
+			}
+			savedEv = nil
+		}
+		e.counter = 0
+	}
 	for {
 		select {
 		case event, ok := <-e.ch:
@@ -40,13 +67,12 @@ func (e eventsPerS) process(d time.Duration, aggregator *Aggregator) {
 			}
 			savedEv = event
 			e.counter++
-		case <-ticker:
-			if savedEv != nil {
-				savedEv.counter = e.counter
-				aggregator.send(savedEv)
-				savedEv = nil
-			}
-			e.counter = 0
+		case <-ticker.C:
+			send()
+		case done := <-e.done:
+			send()
+			close(done)
+			return
 		}
 	}
 }
@@ -54,3 +80,398 @@ func (e eventsPerS) process(d time.Duration, aggregator *Aggregator) {
 func (e eventsPerS) channel() chan *SendEvent {
 	return e.ch
 }
+
+func (e eventsPerS) run(aggregator *Aggregator) {
+	go e.process(time.Second, aggregator)
+}
+
+func (e eventsPerS) stop() {
+	stopOutput(e.done)
+}
+
+// stopOutput asks the process loop of an output function to send its pending
+// events and return, and waits for it
+func stopOutput(stop chan chan bool) {
+	done := make(chan bool)
+	stop <- done
+	<-done
+}
+
+/* tumbling_window and sliding_window */
+
+func init() {
+	outputFuncBuilder["tumbling_window"] = func(a *Aggregator, i int) (outputFunc, error) {
+		return newWindowOutput(a.aggregationSpec.Events[i], false)
+	}
+	outputFuncBuilder["sliding_window"] = func(a *Aggregator, i int) (outputFunc, error) {
+		return newWindowOutput(a.aggregationSpec.Events[i], true)
+	}
+}
+
+const defaultMaxSamples = 1024
+
+// windowOutput groups the events by the values of the group_by fields, and
+// sends a summary of each group at the end of every window: the number of
+// events, and the min, max, sum and percentiles of the numeric values of
+// field. Tumbling windows follow each other, while sliding windows of window
+// are sent every slide. Percentiles are computed from at most max_samples
+// values per group and per slide, weighted by the number of values of the
+// slide they stand for.
+type windowOutput struct {
+	ch   chan *SendEvent
+	done chan chan bool
+
+	window, slide time.Duration
+	groupBy       []string
+	field         string
+	percentiles   []float64
+	maxSamples    int
+
+	// values of the stream and group fields of the group-by keys
+	stream, group string
+
+	// one bucket of groups per slide, the current one last
+	buckets []map[string]*windowGroup
+	start   []time.Time
+}
+
+type windowGroup struct {
+	keys    []windowKey
+	last    *SendEvent
+	count   uint64
+	values  uint64
+	min     float64
+	max     float64
+	sum     float64
+	samples []float64
+}
+
+func newWindowOutput(spec EventSpec, sliding bool) (*windowOutput, error) {
+	p := newParamParser(spec.O.Parameters)
+
+	o := &windowOutput{
+		ch:         make(chan *SendEvent),
+		done:       make(chan chan bool),
+		window:     p.duration("window", time.Second),
+		groupBy:    p.fields("group_by", ""),
+		field:      p.str("field", "duration"),
+		maxSamples: p.integer("max_samples", defaultMaxSamples, 1),
+		stream:     spec.Stream,
+		group:      spec.Group,
+	}
+
+	o.slide = o.window
+	if sliding {
+		def := time.Second
+		if def > o.window {
+			def = o.window
+		}
+		o.slide = p.duration("slide", def)
+		if o.slide > o.window {
+			p.errorf("parameter slide: %v is longer than the window %v", o.slide, o.window)
+		}
+	}
+
+	for _, s := range p.fields("percentiles", "50,90,99") {
+		pct, err := strconv.ParseFloat(s, 64)
+		if err != nil || pct <= 0 || pct > 100 {
+			p.errorf("parameter percentiles: %q is not a number in ]0, 100]", s)
+			continue
+		}
+		o.percentiles = append(o.percentiles, pct)
+	}
+
+	if err := p.done(); err != nil {
+		return nil, err
+	}
+
+	buckets := int((o.window + o.slide - 1) / o.slide)
+	o.buckets = make([]map[string]*windowGroup, buckets)
+	o.start = make([]time.Time, buckets)
+	now := time.Now()
+	for i := range o.buckets {
+		o.buckets[i] = make(map[string]*windowGroup)
+		o.start[i] = now
+	}
+
+	return o, nil
+}
+
+func (o *windowOutput) process(d time.Duration, aggregator *Aggregator) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	send := func(now time.Time) {
+		for _, se := range o.summaries(now) {
+			if err := aggregator.send(se); err != nil {
+				log.Printf("error sending event: %v\n", err)
+			}
+		}
+	}
+	for {
+		select {
+		case event, ok := <-o.ch:
+			if !ok {
+				continue
+			}
+			o.add(event)
+		case now := <-ticker.C:
+			send(now)
+			o.slideWindow(now)
+		case done := <-o.done:
+			// the window ends early, unless its last slide was already sent
+			if len(o.buckets[len(o.buckets)-1]) > 0 {
+				send(time.Now())
+			}
+			close(done)
+			return
+		}
+	}
+}
+
+// slideWindow starts a new slide at now, the oldest one leaving the window
+func (o *windowOutput) slideWindow(now time.Time) {
+	copy(o.buckets, o.buckets[1:])
+	copy(o.start, o.start[1:])
+	o.buckets[len(o.buckets)-1] = make(map[string]*windowGroup)
+	o.start[len(o.start)-1] = now
+}
+
+func (o *windowOutput) run(aggregator *Aggregator) {
+	go o.process(o.slide, aggregator)
+}
+
+func (o *windowOutput) channel() chan *SendEvent {
+	return o.ch
+}
+
+func (o *windowOutput) stop() {
+	stopOutput(o.done)
+}
+
+// keys returns the values of the group-by fields of an event
+func (o *windowOutput) keys(se *SendEvent) []windowKey {
+	keys := make([]windowKey, len(o.groupBy))
+	for i, field := range o.groupBy {
+		keys[i].Name = field
+		switch field {
+		case "stream", "Stream":
+			keys[i].Value = o.stream
+		case "group", "Group":
+			keys[i].Value = o.group
+		default:
+			keys[i].Value, _ = fieldValue(se.data, field)
+		}
+	}
+	return keys
+}
+
+func (o *windowOutput) add(se *SendEvent) {
+	keys := o.keys(se)
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = k.Value
+	}
+	id := strings.Join(values, "\x00")
+
+	bucket := o.buckets[len(o.buckets)-1]
+	g, ok := bucket[id]
+	if !ok {
+		g = &windowGroup{keys: keys}
+		bucket[id] = g
+	}
+	g.last = se
+	g.count++
+
+	s, ok := fieldValue(se.data, o.field)
+	if !ok {
+		return
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return
+	}
+	if g.values == 0 || v < g.min {
+		g.min = v
+	}
+	if g.values == 0 || v > g.max {
+		g.max = v
+	}
+	g.sum += v
+	g.values++
+
+	// reservoir sampling
+	if len(g.samples) < o.maxSamples {
+		g.samples = append(g.samples, v)
+	} else if j := rand.Int63n(int64(g.values)); j < int64(o.maxSamples) {
+		g.samples[j] = v
+	}
+}
+
+// weightedSample is a sampled value, standing for weight values of a slide
+type weightedSample struct {
+	value  float64
+	weight float64
+}
+
+// summaries returns an event per group of the window ending now, carrying
+// the summary of the group and its last event
+func (o *windowOutput) summaries(now time.Time) []*SendEvent {
+	merged := make(map[string]*windowGroup)
+	samples := make(map[string][]weightedSample)
+	var ids []string
+	for _, bucket := range o.buckets {
+		for id, g := range bucket {
+			m, ok := merged[id]
+			if !ok {
+				m = &windowGroup{keys: g.keys}
+				merged[id] = m
+				ids = append(ids, id)
+			}
+			// the reservoirs of the slides are samples of different sizes
+			for _, v := range g.samples {
+				samples[id] = append(samples[id], weightedSample{
+					value:  v,
+					weight: float64(g.values) / float64(len(g.samples)),
+				})
+			}
+			m.last = g.last
+			m.count += g.count
+			if g.values > 0 {
+				if m.values == 0 || g.min < m.min {
+					m.min = g.min
+				}
+				if m.values == 0 || g.max > m.max {
+					m.max = g.max
+				}
+			}
+			m.values += g.values
+			m.sum += g.sum
+		}
+	}
+	sort.Strings(ids)
+
+	var events []*SendEvent
+	for _, id := range ids {
+		g := merged[id]
+		summary := &windowSummary{
+			Start: o.start[0],
+			End:   now,
+			Keys:  g.keys,
+			Count: g.count,
+			Field: o.field,
+			Min:   g.min,
+			Max:   g.max,
+			Sum:   g.sum,
+		}
+		for _, pct := range o.percentiles {
+			if len(samples[id]) == 0 {
+				break
+			}
+			summary.Percentiles = append(summary.Percentiles, percentileValue{
+				Percentile: pct,
+				Value:      weightedPercentile(samples[id], pct),
+			})
+		}
+		events = append(events, &SendEvent{
+			data:    g.last.data,
+			spec:    g.last.spec,
+			counter: int(g.count),
+			window:  summary,
+		})
+	}
+
+	return events
+}
+
+// weightedPercentile returns the nearest rank percentile of the samples,
+// counting each one weight times. It sorts the samples.
+func weightedPercentile(samples []weightedSample, pct float64) float64 {
+	sort.Slice(samples, func(i, j int) bool { return samples[i].value < samples[j].value })
+
+	var total float64
+	for _, s := range samples {
+		total += s.weight
+	}
+	// the weights are rounded, e.g. 1000 values in 3 samples
+	rank := pct / 100 * total * (1 - 1e-9)
+	var cumulative float64
+	for _, s := range samples {
+		cumulative += s.weight
+		if cumulative >= rank {
+			return s.value
+		}
+	}
+	return samples[len(samples)-1].value
+}
+
+// windowSummary summarizes the events of a group in a window
+type windowSummary struct {
+	Start time.Time   `json:"start"`
+	End   time.Time   `json:"end"`
+	Keys  []windowKey `json:"keys"`
+	Count uint64      `json:"count"`
+
+	// summary of the numeric values of Field, zero if there were none
+	Field       string            `json:"field"`
+	Min         float64           `json:"min"`
+	Max         float64           `json:"max"`
+	Sum         float64           `json:"sum"`
+	Percentiles []percentileValue `json:"percentiles"`
+}
+
+type windowKey struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type percentileValue struct {
+	Percentile float64 `json:"percentile"`
+	Value      float64 `json:"value"`
+}
+
+func (w *windowSummary) String() string {
+	return fmt.Sprintf("%s - %s {%s} count %d %s min %g max %g sum %g %s",
+		w.Start.Format(time.RFC3339Nano), w.End.Format(time.RFC3339Nano), w.keysString(),
+		w.Count, w.Field, w.Min, w.Max, w.Sum, w.percentilesString())
+}
+
+// keysString returns the keys as name=value pairs separated by spaces
+func (w *windowSummary) keysString() string {
+	keys := make([]string, len(w.Keys))
+	for i, k := range w.Keys {
+		keys[i] = fmt.Sprintf("%s=%s", k.Name, k.Value)
+	}
+	return strings.Join(keys, " ")
+}
+
+// percentilesString returns the percentiles as p<percentile>=<value> pairs
+// separated by spaces
+func (w *windowSummary) percentilesString() string {
+	pcts := make([]string, len(w.Percentiles))
+	for i, p := range w.Percentiles {
+		pcts[i] = fmt.Sprintf("p%g=%g", p.Percentile, p.Value)
+	}
+	return strings.Join(pcts, " ")
+}
+
+func (w *windowSummary) Proto() *tracer.ProtobufWindowSummary {
+	if w == nil {
+		return nil
+	}
+	pw := &tracer.ProtobufWindowSummary{
+		Start: w.Start.UnixNano(),
+		End:   w.End.UnixNano(),
+		Count: w.Count,
+		Field: w.Field,
+		Min:   w.Min,
+		Max:   w.Max,
+		Sum:   w.Sum,
+	}
+	for _, k := range w.Keys {
+		pw.Keys = append(pw.Keys, &tracer.ProtobufWindowKey{Name: k.Name, Value: k.Value})
+	}
+	for _, p := range w.Percentiles {
+		pw.Percentiles = append(pw.Percentiles, &tracer.ProtobufPercentile{Percentile: p.Percentile, Value: p.Value})
+	}
+	return pw
+}
@@ -0,0 +1,239 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+func tookEvent(pid int64, duration uint64) *SendEvent {
+	ev := openEvent("/a", pid, 3, 0)
+	ev.Common.Duration = duration
+	return &SendEvent{data: ev}
+}
+
+func mustWindowOutput(t *testing.T, params string, sliding bool) *windowOutput {
+	o, err := newWindowOutput(EventSpec{O: Output{Parameters: params}}, sliding)
+	if err != nil {
+		t.Fatalf("%q: %v", params, err)
+	}
+	return o
+}
+
+func TestWindowOutputParams(t *testing.T) {
+	tests := []struct {
+		params  string
+		sliding bool
+		err     string
+	}{
+		{"", false, ""},
+		{"", true, ""},
+		{"window=1m;group_by=comm,FdPath;field=ret;percentiles=50,99.9;max_samples=10", false, ""},
+		{"window=1m;slide=10s", true, ""},
+		// the default slide is shortened to the window
+		{"window=100ms", true, ""},
+		{"window=0s", false, "parameter window: 0s is not positive"},
+		{"window=-1s", true, "parameter window: -1s is not positive"},
+		{"window=1x", false, "parameter window: "},
+		{"window=1s;slide=2s", true, "parameter slide: 2s is longer than the window 1s"},
+		{"slide=0s", true, "parameter slide: 0s is not positive"},
+		// tumbling windows don't slide
+		{"window=1s;slide=1s", false, "unknown parameters slide"},
+		{"percentiles=50,abc", false, `parameter percentiles: "abc" is not a number in ]0, 100]`},
+		{"percentiles=0", false, `parameter percentiles: "0" is not a number in ]0, 100]`},
+		{"percentiles=100.5", false, `parameter percentiles: "100.5" is not a number in ]0, 100]`},
+		{"max_samples=0", false, "parameter max_samples: 0 is less than 1"},
+		{"window=1s;windw=2s;groupby=pid", false, "unknown parameters groupby, windw"},
+		{"window", false, `malformed parameter "window"`},
+	}
+
+	for _, tt := range tests {
+		_, err := newWindowOutput(EventSpec{O: Output{Parameters: tt.params}}, tt.sliding)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error: %v", tt.params, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: got error %v, want %q", tt.params, err, tt.err)
+		}
+	}
+
+	if o := mustWindowOutput(t, "window=100ms", true); o.slide != 100*time.Millisecond {
+		t.Errorf("got slide %v, want the window", o.slide)
+	}
+}
+
+func TestNewAggregatorOutputParams(t *testing.T) {
+	tests := []struct {
+		output Output
+		err    string
+	}{
+		{Output{Metrics: "sliding_window", Parameters: "window=1s;slide=1m"},
+			`invalid parameters of output function "sliding_window" for event "open": parameter slide`},
+		{Output{Metrics: "alerts_per_sec", Parameters: "window=1s"},
+			`invalid parameters of output function "alerts_per_sec" for event "open": unknown parameters window`},
+	}
+
+	for _, tt := range tests {
+		spec := AggregationSpec{
+			Events: []EventSpec{{Name: "open", F: Function{Id: "count"}, O: tt.output}},
+		}
+		_, err := NewAggregator(AggregatorOptions{}, nil, spec, tracer.Context{})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s %q: got error %v, want %q", tt.output.Metrics, tt.output.Parameters, err, tt.err)
+		}
+	}
+}
+
+func TestWeightedPercentile(t *testing.T) {
+	equal := func(values ...float64) []weightedSample {
+		samples := make([]weightedSample, len(values))
+		for i, v := range values {
+			samples[i] = weightedSample{value: v, weight: 1}
+		}
+		return samples
+	}
+
+	tests := []struct {
+		samples []weightedSample
+		pct     float64
+		want    float64
+	}{
+		// nearest rank with equal weights
+		{equal(10, 2, 8, 4, 6, 1, 3, 5, 7, 9), 50, 5},
+		{equal(10, 2, 8, 4, 6, 1, 3, 5, 7, 9), 90, 9},
+		{equal(10, 2, 8, 4, 6, 1, 3, 5, 7, 9), 99, 10},
+		{equal(10, 2, 8, 4, 6, 1, 3, 5, 7, 9), 100, 10},
+		{equal(3), 1, 3},
+		{[]weightedSample{{1, 1}, {2, 1}, {100, 8}}, 50, 100},
+		{[]weightedSample{{1, 1}, {2, 1}, {100, 8}}, 20, 2},
+		// weights which don't add up exactly
+		{[]weightedSample{{1, 1000.0 / 3}, {2, 1000.0 / 3}, {3, 1000.0 / 3}}, 100, 3},
+		{[]weightedSample{{1, 0.1}, {2, 0.1}, {3, 0.1}}, 100.0 / 3 * 2, 2},
+	}
+
+	for _, tt := range tests {
+		if got := weightedPercentile(tt.samples, tt.pct); got != tt.want {
+			t.Errorf("p%g of %v: got %g, want %g", tt.pct, tt.samples, got, tt.want)
+		}
+	}
+}
+
+func TestTumblingWindowSummaries(t *testing.T) {
+	o := mustWindowOutput(t, "window=1s;group_by=pid;percentiles=50,90,99", false)
+	for i := uint64(1); i <= 10; i++ {
+		o.add(tookEvent(1, i))
+	}
+	o.add(tookEvent(2, 42))
+
+	end := time.Now()
+	events := o.summaries(end)
+	if len(events) != 2 {
+		t.Fatalf("got %d summaries, want one per pid", len(events))
+	}
+	w := events[0].window
+	if w.Count != 10 || w.Min != 1 || w.Max != 10 || w.Sum != 55 || w.Field != "duration" || !w.End.Equal(end) {
+		t.Errorf("unexpected summary %v", w)
+	}
+	if got := w.percentilesString(); got != "p50=5 p90=9 p99=10" {
+		t.Errorf("got percentiles %s", got)
+	}
+	if w := events[1].window; w.Count != 1 || w.keysString() != "pid=2" || w.percentilesString() != "p50=42 p90=42 p99=42" {
+		t.Errorf("unexpected summary %v", w)
+	}
+
+	// the next window starts empty
+	o.slideWindow(end)
+	if events := o.summaries(end.Add(time.Second)); len(events) != 0 {
+		t.Errorf("got %d summaries of an empty window", len(events))
+	}
+}
+
+// TestSlidingWindowPercentiles checks that the samples of a busy slide
+// weigh more than the ones of a quiet slide
+func TestSlidingWindowPercentiles(t *testing.T) {
+	o := mustWindowOutput(t, "window=2s;slide=1s;max_samples=10;percentiles=50,95", true)
+
+	// a quiet slide of fast calls, and a busy one of slow calls
+	for i := 0; i < 10; i++ {
+		o.add(tookEvent(1, 1))
+	}
+	o.slideWindow(time.Now())
+	for i := 0; i < 1000; i++ {
+		o.add(tookEvent(1, 100))
+	}
+
+	events := o.summaries(time.Now())
+	if len(events) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(events))
+	}
+	w := events[0].window
+	if w.Count != 1010 || w.Min != 1 || w.Max != 100 {
+		t.Errorf("unexpected summary %v", w)
+	}
+	// 1% of the calls are fast, although half of the samples are
+	if got := w.percentilesString(); got != "p50=100 p95=100" {
+		t.Errorf("got percentiles %s", got)
+	}
+
+	// the quiet slide leaves the window
+	o.slideWindow(time.Now())
+	if w := o.summaries(time.Now())[0].window; w.Count != 1000 || w.Min != 100 {
+		t.Errorf("unexpected summary %v", w)
+	}
+}
+
+func TestAggregatorStopSendsWindows(t *testing.T) {
+	f, err := ioutil.TempFile("", "output-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	spec := AggregationSpec{
+		Channels: []Channel{{Id: "1", Type: "file", Path: f.Name()}},
+		Events: []EventSpec{{
+			Name:      "open",
+			ChannelId: "1",
+			F:         Function{Id: "count"},
+			O:         Output{Metrics: "tumbling_window", Format: "ndjson", Parameters: "window=1h;percentiles=50"},
+		}},
+	}
+	incoming := make(chan *tracer.EventData)
+	a, err := NewAggregator(AggregatorOptions{}, incoming, spec, tracer.Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []uint64{3, 1, 2} {
+		incoming <- tookEvent(1, d).data
+	}
+
+	// the window is an hour long, the events are only sent on stop
+	a.Stop()
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %q, want one summary", b)
+	}
+	var record struct {
+		Window *windowSummary `json:"window"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if w := record.Window; w == nil || w.Count != 3 || w.Sum != 6 || w.percentilesString() != "p50=2" {
+		t.Errorf("unexpected summary %v", w)
+	}
+}
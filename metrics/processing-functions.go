@@ -3,65 +3,564 @@
 package metrics
 
 import (
+	"fmt"
+	"math/big"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ShiftLeftSecurity/traceleft/tracer"
 )
 
 type processingFunc interface {
-	process(*tracer.EventData, string) *tracer.EventData
+	process(*tracer.EventData) *tracer.EventData
 }
 
+// defaultMaxKeys bounds the number of keys the functions keep a state for
+const defaultMaxKeys = 65536
+
 /* sigma */
 
 func init() {
-	processingFuncBuilder["sigma"] = func(a *Aggregator, i int) processingFunc {
-		return &sigma{
-			counter: make(map[uint64]int),
+	processingFuncBuilder["sigma"] = func(a *Aggregator, i int) (processingFunc, error) {
+		p := newParamParser(a.aggregationSpec.Events[i].F.Parameters)
+		s := &sigma{
+			key:       p.fields("key", "hash"),
+			frequency: p.integer("frequency", 0, 0),
+			threshold: p.integer("threshold", 0, 0),
+			maxKeys:   p.integer("max_keys", defaultMaxKeys, 1),
+			counters:  make(map[string]*sigmaCounter),
 		}
+		return s, p.done()
 	}
 }
 
+type sigmaCounter struct {
+	// events of the key, up to the threshold
+	seen int
+	// events since the last one passed
+	skipped int
+}
+
+// sigma passes one event every frequency+1 events of each key (the hash of
+// the event by default), once threshold events of the key were dropped.
 type sigma struct {
-	counter map[uint64]int
+	key       []string
+	frequency int
+	threshold int
+	maxKeys   int
+	counters  map[string]*sigmaCounter
 }
 
-func (s sigma) process(ev *tracer.EventData, params string) *tracer.EventData {
-	if s.counter == nil {
-		s.counter = make(map[uint64]int)
+func (s *sigma) process(ev *tracer.EventData) *tracer.EventData {
+	key := eventKey(ev, s.key)
+	c, ok := s.counters[key]
+	if !ok {
+		if len(s.counters) >= s.maxKeys {
+			s.counters = make(map[string]*sigmaCounter)
+		}
+		c = &sigmaCounter{}
+		s.counters[key] = c
 	}
-	c, _ := s.counter[ev.Common.Hash]
 
-	p := parseParams(params)
+	if c.seen < s.threshold {
+		c.seen++
+		return nil
+	}
 
-	if c > p["frequency"] {
-		s.counter[ev.Common.Hash] = 0
+	if c.skipped > s.frequency {
+		c.skipped = 0
 		return ev
 	}
 
-	s.counter[ev.Common.Hash]++
+	c.skipped++
 
 	return nil
 }
 
-func parseParams(params string) map[string]int {
-	ret := make(map[string]int)
-	parts := strings.Split(params, ";")
+/* count */
 
-	for _, pt := range parts {
-		param := strings.Split(pt, "=")
-		if len(param) != 2 {
-			continue
+func init() {
+	processingFuncBuilder["count"] = func(a *Aggregator, i int) (processingFunc, error) {
+		p := newParamParser(a.aggregationSpec.Events[i].F.Parameters)
+		c := &count{
+			key:     p.fields("key", ""),
+			every:   p.integer("every", 0, 0),
+			maxKeys: p.integer("max_keys", defaultMaxKeys, 1),
+			counter: make(map[string]int),
+		}
+		return c, p.done()
+	}
+}
+
+// count passes one event every `every` events of each key (all the events
+// share the same key by default).
+type count struct {
+	key     []string
+	every   int
+	maxKeys int
+	counter map[string]int
+}
+
+func (c *count) process(ev *tracer.EventData) *tracer.EventData {
+	key := eventKey(ev, c.key)
+	if _, ok := c.counter[key]; !ok && len(c.counter) >= c.maxKeys {
+		c.counter = make(map[string]int)
+	}
+
+	c.counter[key]++
+	if c.counter[key] < c.every {
+		return nil
+	}
+	c.counter[key] = 0
+
+	return ev
+}
+
+/* distinct */
+
+func init() {
+	processingFuncBuilder["distinct"] = func(a *Aggregator, i int) (processingFunc, error) {
+		p := newParamParser(a.aggregationSpec.Events[i].F.Parameters)
+		d := &distinct{
+			key:     p.fields("key", ""),
+			field:   p.requiredFields("field"),
+			max:     p.integer("max", defaultDistinctMax, 1),
+			maxKeys: p.integer("max_keys", defaultMaxKeys, 1),
+			seen:    make(map[string]map[string]bool),
+		}
+		return d, p.done()
+	}
+}
+
+// defaultDistinctMax bounds the number of values remembered by distinct
+const defaultDistinctMax = 65536
+
+// distinct passes the events whose field has a value not seen before for
+// their key. The values of a key are forgotten once there are max of them.
+type distinct struct {
+	key     []string
+	field   []string
+	max     int
+	maxKeys int
+	seen    map[string]map[string]bool
+}
+
+func (d *distinct) process(ev *tracer.EventData) *tracer.EventData {
+	key := eventKey(ev, d.key)
+	value := eventKey(ev, d.field)
+
+	seen, ok := d.seen[key]
+	if !ok && len(d.seen) >= d.maxKeys {
+		d.seen = make(map[string]map[string]bool)
+	}
+	if !ok || len(seen) >= d.max {
+		seen = make(map[string]bool)
+		d.seen[key] = seen
+	}
+	if seen[value] {
+		return nil
+	}
+	seen[value] = true
+
+	return ev
+}
+
+/* topk */
+
+func init() {
+	processingFuncBuilder["topk"] = func(a *Aggregator, i int) (processingFunc, error) {
+		p := newParamParser(a.aggregationSpec.Events[i].F.Parameters)
+		t := &topk{
+			field:   p.requiredFields("field"),
+			k:       p.integer("k", 10, 1),
+			max:     p.integer("max", defaultMaxKeys, 2),
+			counter: make(map[string]uint64),
+			top:     make(map[string]bool),
+		}
+		if p.err == nil && t.max <= t.k {
+			p.errorf("parameter max: %d values don't leave room beyond the top %d", t.max, t.k)
+		}
+		return t, p.done()
+	}
+}
+
+// topk counts the events by value of their field, and passes the events
+// whose value is among the k most frequent ones. The counters of the values
+// out of the top k are forgotten once there are max of them.
+type topk struct {
+	field   []string
+	k       int
+	max     int
+	counter map[string]uint64
+	top     map[string]bool
+}
+
+func (t *topk) process(ev *tracer.EventData) *tracer.EventData {
+	value := eventKey(ev, t.field)
+	if _, ok := t.counter[value]; !ok && len(t.counter) >= t.max {
+		for v := range t.counter {
+			if !t.top[v] {
+				delete(t.counter, v)
+			}
+		}
+	}
+	t.counter[value]++
+
+	if t.top[value] {
+		return ev
+	}
+	if len(t.top) < t.k {
+		t.top[value] = true
+		return ev
+	}
+
+	// counters only grow: a value enters the top k by overtaking its least
+	// frequent value
+	var least string
+	first := true
+	for v := range t.top {
+		if first || t.counter[v] < t.counter[least] {
+			least = v
+			first = false
+		}
+	}
+	if t.counter[value] <= t.counter[least] {
+		return nil
+	}
+	delete(t.top, least)
+	t.top[value] = true
+
+	return ev
+}
+
+/* threshold */
+
+func init() {
+	processingFuncBuilder["threshold"] = func(a *Aggregator, i int) (processingFunc, error) {
+		p := newParamParser(a.aggregationSpec.Events[i].F.Parameters)
+		t := &threshold{
+			cmp: compareNode{
+				field: p.required("field"),
+				op:    p.str("op", ">="),
+				str:   p.str("value", ""),
+			},
+			key:     p.fields("key", ""),
+			count:   p.integer("count", 0, 0),
+			maxKeys: p.integer("max_keys", defaultMaxKeys, 1),
+			counter: make(map[string]int),
+		}
+		switch t.cmp.op {
+		case "==", "!=", "<", "<=", ">", ">=":
+		default:
+			p.errorf("parameter op: unknown operator %q", t.cmp.op)
+		}
+		if num, ok := new(big.Int).SetString(t.cmp.str, 0); ok {
+			t.cmp.num = num
+		}
+		return t, p.done()
+	}
+}
+
+// threshold passes the events whose field compares to value with op (==, !=,
+// <, <=, >, >=), as numbers if value is one and as strings otherwise, once
+// there were count of them for their key (1 by default).
+type threshold struct {
+	cmp     compareNode
+	key     []string
+	count   int
+	maxKeys int
+	counter map[string]int
+}
+
+func (t *threshold) process(ev *tracer.EventData) *tracer.EventData {
+	if !t.cmp.match(ev) {
+		return nil
+	}
+
+	key := eventKey(ev, t.key)
+	c, ok := t.counter[key]
+	if c >= t.count {
+		return ev
+	}
+	if !ok && len(t.counter) >= t.maxKeys {
+		t.counter = make(map[string]int)
+	}
+	t.counter[key] = c + 1
+	if c+1 < t.count {
+		return nil
+	}
+
+	return ev
+}
+
+/* rate */
+
+func init() {
+	processingFuncBuilder["rate"] = func(a *Aggregator, i int) (processingFunc, error) {
+		p := newParamParser(a.aggregationSpec.Events[i].F.Parameters)
+		r := &rate{
+			key:     p.fields("key", ""),
+			window:  p.duration("window", time.Second),
+			min:     p.integer("min", 0, 0),
+			max:     p.integer("max", 0, 0),
+			maxKeys: p.integer("max_keys", defaultMaxKeys, 1),
+			windows: make(map[string]*rateWindow),
 		}
+		return r, p.done()
+	}
+}
+
+type rateWindow struct {
+	start uint64
+	count int
+}
+
+// rate counts the events of each key in windows of window (a duration, 1s by
+// default) of their timestamps, and passes the events from the min-th (1 by
+// default) to the max-th (unlimited by default) of each window. For
+// instance, min detects bursts and max limits the rate of the events.
+type rate struct {
+	key      []string
+	window   time.Duration
+	min, max int
+	maxKeys  int
+	windows  map[string]*rateWindow
+}
+
+func (r *rate) process(ev *tracer.EventData) *tracer.EventData {
+	key := eventKey(ev, r.key)
+	w, ok := r.windows[key]
+	if !ok && len(r.windows) >= r.maxKeys {
+		r.windows = make(map[string]*rateWindow)
+	}
+	if !ok || ev.Common.Timestamp >= w.start+uint64(r.window) {
+		w = &rateWindow{start: ev.Common.Timestamp}
+		r.windows[key] = w
+	}
+	w.count++
+
+	if w.count < r.min || r.max > 0 && w.count > r.max {
+		return nil
+	}
+
+	return ev
+}
+
+/* latency */
+
+func init() {
+	processingFuncBuilder["latency"] = func(a *Aggregator, i int) (processingFunc, error) {
+		p := newParamParser(a.aggregationSpec.Events[i].F.Parameters)
+		l := &latency{
+			threshold: time.Duration(p.integer("threshold_us", 0, 0)) * time.Microsecond,
+			slowest:   p.integer("slowest", 0, 0) != 0,
+			maxKeys:   p.integer("max_keys", defaultMaxKeys, 1),
+			calls:     make(map[latencyKey]uint64),
+		}
+		return l, p.done()
+	}
+}
+
+// latencyKey identifies the calls of a syscall by a process on a file
+type latencyKey struct {
+	pid  int64
+	name string
+	file string
+}
+
+// latency passes the syscalls which took at least threshold_us microseconds.
+// With slowest=1, only the calls slower than all the previous ones of the
+// same syscall by the same process on the same file are passed.
+type latency struct {
+	threshold time.Duration
+	slowest   bool
+	maxKeys   int
+	// duration of the slowest calls
+	calls map[latencyKey]uint64
+}
+
+func (l *latency) process(ev *tracer.EventData) *tracer.EventData {
+	if ev.Common.Duration < uint64(l.threshold) {
+		return nil
+	}
+	if !l.slowest {
+		return ev
+	}
+
+	key := latencyKey{
+		pid:  ev.Common.Pid,
+		name: ev.Common.Name,
+		file: eventFile(ev),
+	}
+	slowest, ok := l.calls[key]
+	if ev.Common.Duration <= slowest {
+		return nil
+	}
+	if !ok && len(l.calls) >= l.maxKeys {
+		l.calls = make(map[latencyKey]uint64)
+	}
+	l.calls[key] = ev.Common.Duration
+
+	return ev
+}
+
+// eventFile returns the file of an event: the path of its file descriptor or
+// its file name, "" if it has none
+func eventFile(ev *tracer.EventData) string {
+	if ev.Event == nil {
+		return ""
+	}
+	for _, arg := range ev.Event.Args(ev.Common.Ret) {
+		if strings.HasSuffix(arg.Name, "Path") || arg.Name == "Filename" || arg.Name == "Pathname" {
+			return fmt.Sprintf("%v", arg.Value)
+		}
+	}
+	return ""
+}
+
+// eventKey returns the values of the fields of an event (as in the rules),
+// identifying the state of the event in a processing function. Missing
+// fields are empty.
+func eventKey(ev *tracer.EventData, fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		values[i], _ = fieldValue(ev, field)
+	}
+	return strings.Join(values, "\x00")
+}
 
-		val, err := strconv.Atoi(param[1])
-		if err != nil {
+// paramParser parses the parameters of a processing or output function once,
+// when the aggregator is created. It keeps the first error, and the parameters
+// the function doesn't know are errors too.
+type paramParser struct {
+	params map[string]string
+	known  map[string]bool
+	err    error
+}
+
+func newParamParser(params string) *paramParser {
+	p := &paramParser{
+		params: make(map[string]string),
+		known:  make(map[string]bool),
+	}
+	for _, pt := range strings.Split(params, ";") {
+		if strings.TrimSpace(pt) == "" {
 			continue
 		}
+		param := strings.SplitN(pt, "=", 2)
+		if len(param) != 2 {
+			p.errorf("malformed parameter %q, expected name=value", strings.TrimSpace(pt))
+			continue
+		}
+		p.params[strings.TrimSpace(param[0])] = strings.TrimSpace(param[1])
+	}
+	return p
+}
+
+func (p *paramParser) errorf(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf(format, args...)
+	}
+}
+
+// str returns the parameter name, or def if it is not set
+func (p *paramParser) str(name, def string) string {
+	p.known[name] = true
+	if v, ok := p.params[name]; ok {
+		return v
+	}
+	return def
+}
+
+// required returns the parameter name, which must be set
+func (p *paramParser) required(name string) string {
+	v := p.str(name, "")
+	if v == "" {
+		p.errorf("parameter %s is required", name)
+	}
+	return v
+}
+
+// fields returns the fields of the parameter name, separated by commas, or
+// the ones of def if it is not set
+func (p *paramParser) fields(name, def string) []string {
+	return splitFields(p.str(name, def))
+}
+
+// requiredFields returns the fields of the parameter name, which must be set
+func (p *paramParser) requiredFields(name string) []string {
+	return splitFields(p.required(name))
+}
+
+// integer returns the integer parameter name, at least min, or def if it is
+// not set
+func (p *paramParser) integer(name string, def, min int) int {
+	v := p.str(name, "")
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		p.errorf("parameter %s: %q is not an integer", name, v)
+		return def
+	}
+	if n < min {
+		p.errorf("parameter %s: %d is less than %d", name, n, min)
+		return def
+	}
+	return n
+}
+
+// duration returns the positive duration parameter name, or def if it is not
+// set
+func (p *paramParser) duration(name string, def time.Duration) time.Duration {
+	v := p.str(name, "")
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		p.errorf("parameter %s: %v", name, err)
+		return def
+	}
+	if d <= 0 {
+		p.errorf("parameter %s: %s is not positive", name, v)
+		return def
+	}
+	return d
+}
 
-		ret[param[0]] = val
+// done returns the first error of the parameters, or an error if one of them
+// is unknown
+func (p *paramParser) done() error {
+	if p.err != nil {
+		return p.err
+	}
+	var unknown []string
+	for name := range p.params {
+		if !p.known[name] {
+			unknown = append(unknown, name)
+		}
 	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown parameters %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
 
+func splitFields(fields string) []string {
+	if fields == "" {
+		return nil
+	}
+	var ret []string
+	for _, field := range strings.Split(fields, ",") {
+		ret = append(ret, strings.TrimSpace(field))
+	}
 	return ret
 }
@@ -0,0 +1,266 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+func newProcessingFunc(id, params string) (processingFunc, error) {
+	a := &Aggregator{
+		aggregationSpec: AggregationSpec{
+			Events: []EventSpec{{Name: "open", F: Function{Id: id, Parameters: params}}},
+		},
+	}
+	return processingFuncBuilder[id](a, 0)
+}
+
+func mustProcessingFunc(t *testing.T, id, params string) processingFunc {
+	f, err := newProcessingFunc(id, params)
+	if err != nil {
+		t.Fatalf("%s %q: %v", id, params, err)
+	}
+	return f
+}
+
+// passed returns which of the events the function passes, as a string of 0
+// and 1
+func passed(f processingFunc, events ...*tracer.EventData) string {
+	var s []byte
+	for _, ev := range events {
+		if f.process(ev) != nil {
+			s = append(s, '1')
+		} else {
+			s = append(s, '0')
+		}
+	}
+	return string(s)
+}
+
+func repeat(ev *tracer.EventData, n int) []*tracer.EventData {
+	events := make([]*tracer.EventData, n)
+	for i := range events {
+		events[i] = ev
+	}
+	return events
+}
+
+func TestProcessingFuncParams(t *testing.T) {
+	tests := []struct {
+		id     string
+		params string
+		err    string
+	}{
+		{"sigma", "frequency=100;threshold=0", ""},
+		{"sigma", " frequency = 1 ; key = pid,comm ;", ""},
+		{"sigma", "frequency=a", `parameter frequency: "a" is not an integer`},
+		{"sigma", "frequency=-1", "parameter frequency: -1 is less than 0"},
+		{"sigma", "frequency", `malformed parameter "frequency"`},
+		{"sigma", "frequncy=1;treshold=2", "unknown parameters frequncy, treshold"},
+		{"count", "every=10;key=pid", ""},
+		{"count", "every=10;max_keys=0", "parameter max_keys: 0 is less than 1"},
+		{"distinct", "field=Filename;key=pid;max=10", ""},
+		{"distinct", "key=pid", "parameter field is required"},
+		{"topk", "field=comm;k=3", ""},
+		{"topk", "field=comm;k=0", "parameter k: 0 is less than 1"},
+		{"topk", "field=comm;k=3;max=3", "don't leave room beyond the top 3"},
+		{"threshold", "field=duration;op=>;value=1000000;count=10;key=pid", ""},
+		{"threshold", "field=duration;op=>>;value=1", `parameter op: unknown operator ">>"`},
+		{"threshold", "op=>;value=1", "parameter field is required"},
+		{"rate", "window=100ms;min=5;max=10", ""},
+		{"rate", "window=0s", "parameter window: 0s is not positive"},
+		{"rate", "window=1x", "parameter window: "},
+		{"latency", "threshold_us=100;slowest=1", ""},
+		{"latency", "threshold=100", "unknown parameters threshold"},
+	}
+
+	for _, tt := range tests {
+		_, err := newProcessingFunc(tt.id, tt.params)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s %q: unexpected error: %v", tt.id, tt.params, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s %q: got error %v, want %q", tt.id, tt.params, err, tt.err)
+		}
+	}
+}
+
+func TestNewAggregatorParams(t *testing.T) {
+	spec := AggregationSpec{
+		Events: []EventSpec{{
+			Name: "open",
+			F:    Function{Id: "threshold", Parameters: "field=ret;op=~;value=0"},
+			O:    Output{Metrics: "alerts_per_sec"},
+		}},
+	}
+	_, err := NewAggregator(AggregatorOptions{}, nil, spec, tracer.Context{})
+	if err == nil || !strings.Contains(err.Error(), `invalid parameters of processing function "threshold" for event "open"`) {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestSigma(t *testing.T) {
+	a := openEvent("/a", 1, 3, 0)
+	b := openEvent("/b", 2, 3, 0)
+
+	f := mustProcessingFunc(t, "sigma", "frequency=1;key=pid")
+	if got := passed(f, repeat(a, 6)...); got != "001001" {
+		t.Errorf("got %s", got)
+	}
+	// the keys have their own counters
+	if got := passed(f, b, b, b); got != "001" {
+		t.Errorf("got %s", got)
+	}
+
+	f = mustProcessingFunc(t, "sigma", "frequency=0;threshold=3;key=pid")
+	if got := passed(f, repeat(a, 7)...); got != "0000101" {
+		t.Errorf("got %s", got)
+	}
+	// a passes every other event, and b every other event once 3 of them
+	// were dropped
+	if got := passed(f, b, a, b, a, b, b, b); got != "0001001" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestCount(t *testing.T) {
+	a := openEvent("/a", 1, 3, 0)
+	b := openEvent("/b", 2, 3, 0)
+
+	f := mustProcessingFunc(t, "count", "every=3")
+	if got := passed(f, a, b, a, b, a, b); got != "001001" {
+		t.Errorf("got %s", got)
+	}
+	f = mustProcessingFunc(t, "count", "every=3;key=pid")
+	if got := passed(f, a, b, a, b, a, b); got != "000011" {
+		t.Errorf("got %s", got)
+	}
+
+	// the counters are forgotten once there are max_keys keys
+	f = mustProcessingFunc(t, "count", "every=2;key=pid;max_keys=1")
+	if got := passed(f, a, b, a, a); got != "0001" {
+		t.Errorf("got %s", got)
+	}
+	if n := len(f.(*count).counter); n != 1 {
+		t.Errorf("%d keys, want at most 1", n)
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	a1 := openEvent("/a", 1, 3, 0)
+	a2 := openEvent("/a", 2, 3, 0)
+	b1 := openEvent("/b", 1, 3, 0)
+
+	f := mustProcessingFunc(t, "distinct", "field=Filename")
+	if got := passed(f, a1, a2, b1, a1); got != "1010" {
+		t.Errorf("got %s", got)
+	}
+	f = mustProcessingFunc(t, "distinct", "field=Filename;key=pid")
+	if got := passed(f, a1, a2, b1, a1, a2); got != "11100" {
+		t.Errorf("got %s", got)
+	}
+
+	// values are forgotten once there are max of them
+	f = mustProcessingFunc(t, "distinct", "field=Filename;max=2")
+	if got := passed(f, a1, a1, b1, a1); got != "1011" {
+		t.Errorf("got %s", got)
+	}
+	// and keys once there are max_keys of them
+	f = mustProcessingFunc(t, "distinct", "field=Filename;key=pid;max_keys=1")
+	if got := passed(f, a1, a2, a1); got != "111" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestTopK(t *testing.T) {
+	a := openEvent("/a", 1, 3, 0)
+	b := openEvent("/b", 1, 3, 0)
+	c := openEvent("/c", 1, 3, 0)
+
+	f := mustProcessingFunc(t, "topk", "field=Filename;k=2")
+	if got := passed(f, a, a, b, c); got != "1110" {
+		t.Errorf("got %s", got)
+	}
+	// c overtakes b
+	if got := passed(f, c, b, a); got != "101" {
+		t.Errorf("got %s", got)
+	}
+
+	// the counters out of the top are forgotten once there are max values
+	f = mustProcessingFunc(t, "topk", "field=Filename;k=1;max=2")
+	if got := passed(f, a, b, b, c, c, c); got != "101001" {
+		t.Errorf("got %s", got)
+	}
+	if n := len(f.(*topk).counter); n > 2 {
+		t.Errorf("%d counters, want at most 2", n)
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	ok := openEvent("/a", 1, 3, 0)
+	failed := openEvent("/a", 1, -2, 0)
+	other := openEvent("/a", 2, -2, 0)
+
+	f := mustProcessingFunc(t, "threshold", "field=ret;op=<;value=0")
+	if got := passed(f, ok, failed, ok, failed); got != "0101" {
+		t.Errorf("got %s", got)
+	}
+	f = mustProcessingFunc(t, "threshold", "field=ret;op=<;value=0;count=2;key=pid")
+	if got := passed(f, failed, other, ok, failed, failed, other); got != "000111" {
+		t.Errorf("got %s", got)
+	}
+	f = mustProcessingFunc(t, "threshold", "field=Filename;op=!=;value=/a")
+	if got := passed(f, ok, openEvent("/b", 1, 3, 0)); got != "01" {
+		t.Errorf("got %s", got)
+	}
+
+	// the counters are forgotten once there are max_keys keys
+	f = mustProcessingFunc(t, "threshold", "field=ret;op=<;value=0;count=2;key=pid;max_keys=1")
+	if got := passed(f, failed, other, failed, failed); got != "0001" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestRate(t *testing.T) {
+	at := func(pid int64, ms uint64) *tracer.EventData {
+		ev := openEvent("/a", pid, 3, 0)
+		ev.Common.Timestamp = ms * 1000000
+		return ev
+	}
+
+	f := mustProcessingFunc(t, "rate", "window=100ms;min=2;max=3")
+	if got := passed(f, at(1, 0), at(1, 10), at(1, 20), at(1, 30), at(1, 100), at(1, 150)); got != "011001" {
+		t.Errorf("got %s", got)
+	}
+	f = mustProcessingFunc(t, "rate", "window=100ms;max=1;key=pid")
+	if got := passed(f, at(1, 0), at(2, 10), at(1, 20), at(1, 120)); got != "1101" {
+		t.Errorf("got %s", got)
+	}
+
+	// the windows are forgotten once there are max_keys keys
+	f = mustProcessingFunc(t, "rate", "max=1;key=pid;max_keys=1")
+	if got := passed(f, at(1, 0), at(2, 10), at(1, 20)); got != "111" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	took := func(file string, us uint64) *tracer.EventData {
+		ev := openEvent(file, 1, 3, 0)
+		ev.Common.Duration = us * 1000
+		return ev
+	}
+
+	f := mustProcessingFunc(t, "latency", "threshold_us=100")
+	if got := passed(f, took("/a", 50), took("/a", 100), took("/a", 150), took("/a", 120)); got != "0111" {
+		t.Errorf("got %s", got)
+	}
+	f = mustProcessingFunc(t, "latency", "threshold_us=100;slowest=1")
+	if got := passed(f, took("/a", 150), took("/a", 120), took("/b", 120), took("/a", 200)); got != "1011" {
+		t.Errorf("got %s", got)
+	}
+}
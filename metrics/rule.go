@@ -0,0 +1,567 @@
+// rule language for event specs
+
+package metrics
+
+import (
+	"fmt"
+	"math/big"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+// A rule selects which events an event spec cares about. The grammar is:
+//
+//	rule       := or
+//	or         := and { "||" and }
+//	and        := unary { "&&" unary }
+//	unary      := "!" unary | "(" or ")" | comparison
+//	comparison := field op value
+//	            | field "in" number ".." number
+//	            | field "glob" string
+//	op         := "==" | "!=" | "<" | "<=" | ">" | ">=" | "=~" | "!~"
+//
+// Lower case fields refer to the common part of the event (name, pid, ret,
+// program_id, hash, flags, timestamp). Any other field is an argument of the
+// event, named as in the structs generated by the metagenerator (e.g.
+// Filename, Flags, Fd or FdPath). An empty rule matches every event.
+
+type ruleNode interface {
+	match(event *tracer.EventData) bool
+}
+
+type rule struct {
+	src  string
+	root ruleNode
+}
+
+func (r *rule) match(event *tracer.EventData) bool {
+	if r == nil || r.root == nil {
+		return true
+	}
+	return r.root.match(event)
+}
+
+func compileRule(src string) (*rule, error) {
+	if strings.TrimSpace(src) == "" {
+		return &rule{src: src}, nil
+	}
+
+	tokens, err := lexRule(src)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %v", src, err)
+	}
+
+	p := &ruleParser{src: src, tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && !p.at(tokEOF) {
+		err = p.errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %v", src, err)
+	}
+
+	return &rule{src: src, root: root}, nil
+}
+
+/* lexer */
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokRange
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of rule"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+var ruleOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c == '.' || c >= '0' && c <= '9'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func lexRule(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case strings.HasPrefix(src[i:], ".."):
+			tokens = append(tokens, token{tokRange, "..", i})
+			i += 2
+		case c == '\'' || c == '"':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("column %d: %v", i+1, err)
+			}
+			tokens = append(tokens, token{tokString, s, i})
+			i += n
+		case isDigit(c) || c == '-' && i+1 < len(src) && isDigit(src[i+1]):
+			start := i
+			i++
+			for i < len(src) && (isDigit(src[i]) || isIdentStart(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, src[start:i], start})
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentChar(src[i]) {
+				// do not swallow a ".." range operator
+				if strings.HasPrefix(src[i:], "..") {
+					break
+				}
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, src[start:i], start})
+		default:
+			op := ""
+			for _, o := range ruleOperators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("column %d: unexpected character %q", i+1, c)
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	tokens = append(tokens, token{tokEOF, "", len(src)})
+	return tokens, nil
+}
+
+// lexString reads a single or double quoted string at the beginning of s and
+// returns its unescaped value and the number of bytes consumed.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+/* parser */
+
+type ruleParser struct {
+	src    string
+	tokens []token
+	i      int
+}
+
+func (p *ruleParser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *ruleParser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *ruleParser) at(kind tokenKind) bool {
+	return p.peek().kind == kind
+}
+
+func (p *ruleParser) atOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *ruleParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("column %d: %s", p.peek().pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *ruleParser) parseOr() (ruleNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.atOp("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseAnd() (ruleNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.atOp("&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseUnary() (ruleNode, error) {
+	if p.atOp("!") {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	if p.at(tokLParen) {
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.at(tokRParen) {
+			return nil, p.errorf("expected \")\", got %s", p.peek())
+		}
+		p.next()
+		return n, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *ruleParser) parseComparison() (ruleNode, error) {
+	if !p.at(tokIdent) {
+		return nil, p.errorf("expected field name, got %s", p.peek())
+	}
+	field := p.next().text
+
+	opTok := p.peek()
+	switch {
+	case opTok.kind == tokIdent && opTok.text == "in":
+		p.next()
+		lo, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		if !p.at(tokRange) {
+			return nil, p.errorf("expected \"..\", got %s", p.peek())
+		}
+		p.next()
+		hi, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		if lo.Cmp(hi) > 0 {
+			return nil, fmt.Errorf("column %d: empty range", opTok.pos+1)
+		}
+		return rangeNode{field, lo, hi}, nil
+	case opTok.kind == tokIdent && opTok.text == "glob":
+		p.next()
+		valTok := p.peek()
+		if valTok.kind != tokString {
+			return nil, p.errorf("expected glob pattern string, got %s", valTok)
+		}
+		p.next()
+		if _, err := path.Match(valTok.text, ""); err != nil {
+			return nil, fmt.Errorf("column %d: invalid glob pattern: %v", valTok.pos+1, err)
+		}
+		return globNode{field, valTok.text}, nil
+	case opTok.kind == tokOp:
+		switch opTok.text {
+		case "=~", "!~":
+			p.next()
+			valTok := p.peek()
+			if valTok.kind != tokString {
+				return nil, p.errorf("expected regular expression string, got %s", valTok)
+			}
+			p.next()
+			re, err := regexp.Compile(valTok.text)
+			if err != nil {
+				return nil, fmt.Errorf("column %d: invalid regular expression: %v", valTok.pos+1, err)
+			}
+			return regexNode{field, re, opTok.text == "!~"}, nil
+		case "==", "!=", "<", "<=", ">", ">=":
+			p.next()
+			valTok := p.peek()
+			switch valTok.kind {
+			case tokString:
+				p.next()
+				return compareNode{field: field, op: opTok.text, str: valTok.text}, nil
+			case tokNumber:
+				num, err := p.parseNumber()
+				if err != nil {
+					return nil, err
+				}
+				return compareNode{field: field, op: opTok.text, num: num}, nil
+			default:
+				return nil, p.errorf("expected string or number, got %s", valTok)
+			}
+		}
+	}
+	return nil, p.errorf("expected operator after %q, got %s", field, opTok)
+}
+
+func (p *ruleParser) parseNumber() (*big.Int, error) {
+	t := p.peek()
+	if t.kind != tokNumber {
+		return nil, p.errorf("expected number, got %s", t)
+	}
+	n, ok := new(big.Int).SetString(t.text, 0)
+	if !ok {
+		return nil, p.errorf("malformed number %q", t.text)
+	}
+	p.next()
+	return n, nil
+}
+
+/* evaluation */
+
+type orNode struct{ left, right ruleNode }
+
+func (n orNode) match(ev *tracer.EventData) bool {
+	return n.left.match(ev) || n.right.match(ev)
+}
+
+type andNode struct{ left, right ruleNode }
+
+func (n andNode) match(ev *tracer.EventData) bool {
+	return n.left.match(ev) && n.right.match(ev)
+}
+
+type notNode struct{ n ruleNode }
+
+func (n notNode) match(ev *tracer.EventData) bool {
+	return !n.n.match(ev)
+}
+
+type compareNode struct {
+	field string
+	op    string
+	str   string
+	num   *big.Int // nil when comparing against a string
+}
+
+func (n compareNode) match(ev *tracer.EventData) bool {
+	val, ok := fieldValue(ev, n.field)
+	if !ok {
+		return false
+	}
+
+	var cmp int
+	if n.num != nil {
+		v, ok := new(big.Int).SetString(val, 10)
+		if !ok {
+			return n.op == "!="
+		}
+		cmp = v.Cmp(n.num)
+	} else {
+		cmp = strings.Compare(val, n.str)
+	}
+
+	switch n.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+type rangeNode struct {
+	field  string
+	lo, hi *big.Int
+}
+
+func (n rangeNode) match(ev *tracer.EventData) bool {
+	val, ok := fieldValue(ev, n.field)
+	if !ok {
+		return false
+	}
+	v, ok := new(big.Int).SetString(val, 10)
+	if !ok {
+		return false
+	}
+	return v.Cmp(n.lo) >= 0 && v.Cmp(n.hi) <= 0
+}
+
+type globNode struct {
+	field   string
+	pattern string
+}
+
+func (n globNode) match(ev *tracer.EventData) bool {
+	val, ok := fieldValue(ev, n.field)
+	if !ok {
+		return false
+	}
+	matched, _ := path.Match(n.pattern, val)
+	return matched
+}
+
+type regexNode struct {
+	field  string
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (n regexNode) match(ev *tracer.EventData) bool {
+	val, ok := fieldValue(ev, n.field)
+	if !ok {
+		return false
+	}
+	return n.re.MatchString(val) != n.negate
+}
+
+/* field access */
+
+// fieldValue returns the string representation of a common event field
+// (lower case), of a field of the process metadata (comm, exe, cmdline,
+// ppid, uid, gid, start_time, cgroup, the *_ns ones, container and the
+// container_* ones, pod_uid and qos_class) or of an event argument (as named
+// by the metagenerator).
+func fieldValue(ev *tracer.EventData, name string) (string, bool) {
+	c := &ev.Common
+	switch name {
+	case "name":
+		return c.Name, true
+	case "pid":
+		return strconv.FormatInt(c.Pid, 10), true
+	case "ret":
+		return strconv.FormatInt(c.Ret, 10), true
+	case "program_id":
+		return strconv.FormatUint(c.ProgramID, 10), true
+	case "hash":
+		return strconv.FormatUint(c.Hash, 10), true
+	case "flags":
+		return strconv.FormatUint(c.Flags, 10), true
+	case "timestamp":
+		return strconv.FormatUint(c.Timestamp, 10), true
+	case "duration":
+		return strconv.FormatUint(c.Duration, 10), true
+	}
+	if p := ev.Process; p != nil {
+		switch name {
+		case "comm":
+			return p.Comm, true
+		case "exe":
+			return p.Exe, true
+		case "cmdline":
+			return strings.Join(p.Cmdline, " "), true
+		case "ppid":
+			return strconv.FormatInt(p.Ppid, 10), true
+		case "uid":
+			return strconv.FormatUint(uint64(p.UID), 10), true
+		case "gid":
+			return strconv.FormatUint(uint64(p.GID), 10), true
+		case "start_time":
+			if p.StartTime.IsZero() {
+				return "0", true
+			}
+			return strconv.FormatInt(p.StartTime.UnixNano(), 10), true
+		case "cgroup":
+			return p.Cgroup, true
+		case "mnt_ns":
+			return strconv.FormatUint(p.MntNs, 10), true
+		case "pid_ns":
+			return strconv.FormatUint(p.PidNs, 10), true
+		case "net_ns":
+			return strconv.FormatUint(p.NetNs, 10), true
+		case "container":
+			return strconv.FormatBool(p.Container), true
+		}
+		if c := p.ContainerInfo; c != nil {
+			switch name {
+			case "container_runtime":
+				return c.Runtime, true
+			case "container_id":
+				return c.ID, true
+			case "container_name":
+				return c.Name, true
+			case "container_image":
+				return c.Image, true
+			case "pod_uid":
+				return c.PodUID, true
+			case "qos_class":
+				return c.QoSClass, true
+			}
+		}
+	}
+	if ev.Event == nil {
+		return "", false
+	}
+	arg, err := ev.Event.GetArg(name, c.Ret)
+	if err != nil {
+		return "", false
+	}
+	return arg, true
+}
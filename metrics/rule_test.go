@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+func openEvent(filename string, pid, ret int64, flags int64) *tracer.EventData {
+	e := tracer.OpenEvent{Flags: flags, Mode: 0644}
+	copy(e.Filename[:], filename)
+	return &tracer.EventData{
+		Common: tracer.CommonEvent{Name: "open", Pid: pid, Ret: ret, ProgramID: 7},
+		Event:  e,
+		Process: &tracer.ProcessInfo{
+			Comm: "nginx",
+			Exe:  "/usr/sbin/nginx",
+		},
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	ev := openEvent("/tmp/a.txt", 42, -2, 0x80000)
+
+	tests := []struct {
+		rule  string
+		match bool
+	}{
+		{"", true},
+		{"   ", true},
+
+		// string comparisons
+		{"Filename == '/tmp/a.txt'", true},
+		{`Filename == "/tmp/a.txt"`, true},
+		{"Filename != '/tmp/a.txt'", false},
+		{"Filename < '/tmp/b'", true},
+		{"name == 'open'", true},
+		{"comm == 'nginx'", true},
+		{"exe == '/usr/sbin/nginx'", true},
+
+		// numeric comparisons, in decimal, hexadecimal and octal
+		{"pid == 42", true},
+		{"pid > 41 && pid <= 42", true},
+		{"pid >= 43", false},
+		{"ret < 0", true},
+		{"ret == -2", true},
+		{"Flags == 0x80000", true},
+		{"Flags == 524288", true},
+		{"Mode == 0644", true},
+		{"Mode == 644", false},
+		{"program_id != 7", false},
+
+		// numbers compare as numbers, not as strings ("42" > "100")
+		{"pid < 100", true},
+		// and strings as strings
+		{"pid < '100'", false},
+
+		// ranges are inclusive
+		{"ret in -13..-1", true},
+		{"ret in -2..-2", true},
+		{"ret in 0..10", false},
+		{"pid in 0x20..0x30", true},
+
+		// glob and regular expressions
+		{"Filename glob '/tmp/*'", true},
+		{"Filename glob '/etc/*'", false},
+		{"Filename glob '/tmp/?.txt'", true},
+		{"Filename =~ '\\.txt$'", true},
+		{"Filename !~ '\\.txt$'", false},
+		{"Filename =~ '^/etc/'", false},
+
+		// fields the event doesn't have never match, except with !=
+		{"Fd == 3", false},
+		{"!(Fd == 3)", true},
+		{"Fd glob '*'", false},
+		{"Fd in 0..10", false},
+
+		// precedence: ! binds tighter than &&, which binds tighter than ||
+		{"pid == 1 && pid == 2 || pid == 42", true},
+		{"pid == 42 || pid == 1 && pid == 2", true},
+		{"(pid == 42 || pid == 1) && pid == 2", false},
+		{"!pid == 42 || ret < 0", true},
+		{"!(pid == 42) && ret < 0", false},
+		{"!!(pid == 42)", true},
+		{"!(pid == 1 || pid == 2) && Filename glob '/tmp/*' && ret >= -13", true},
+	}
+
+	for _, tt := range tests {
+		r, err := compileRule(tt.rule)
+		if err != nil {
+			t.Errorf("compileRule(%q): unexpected error: %v", tt.rule, err)
+			continue
+		}
+		if got := r.match(ev); got != tt.match {
+			t.Errorf("rule %q: got %t, want %t", tt.rule, got, tt.match)
+		}
+	}
+}
+
+func TestRuleMatchProcessFields(t *testing.T) {
+	ev := openEvent("/tmp/a.txt", 42, 3, 0)
+	ev.Process.Cmdline = []string{"nginx", "-g", "daemon off;"}
+	ev.Process.StartTime = time.Unix(100, 5)
+	ev.Process.MntNs = 4026531840
+	ev.Process.PidNs = 4026531836
+	ev.Process.NetNs = 4026531992
+
+	tests := []struct {
+		rule  string
+		match bool
+	}{
+		{"cmdline == 'nginx -g daemon off;'", true},
+		{"cmdline glob '* -g *'", true},
+		{"start_time == 100000000005", true},
+		{"start_time > 200000000000", false},
+		{"mnt_ns == 4026531840 && pid_ns == 4026531836 && net_ns == 4026531992", true},
+		{"net_ns != 4026531992", false},
+	}
+
+	for _, tt := range tests {
+		r, err := compileRule(tt.rule)
+		if err != nil {
+			t.Errorf("compileRule(%q): unexpected error: %v", tt.rule, err)
+			continue
+		}
+		if got := r.match(ev); got != tt.match {
+			t.Errorf("rule %q: got %t, want %t", tt.rule, got, tt.match)
+		}
+	}
+
+	// an unknown start time is 0
+	ev.Process.StartTime = time.Time{}
+	if v, _ := fieldValue(ev, "start_time"); v != "0" {
+		t.Errorf("got start_time %q for an unknown start time", v)
+	}
+}
+
+func TestRuleMatchNilRule(t *testing.T) {
+	var r *rule
+	if !r.match(openEvent("/", 1, 0, 0)) {
+		t.Errorf("nil rule should match every event")
+	}
+}
+
+func TestRuleErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		// substrings of the error
+		errs []string
+	}{
+		{"pid ==", []string{"column 7", "expected string or number, got end of rule"}},
+		{"pid", []string{"column 4", `expected operator after "pid"`}},
+		{"== 1", []string{"column 1", "expected field name"}},
+		{"(pid == 1", []string{"column 10", `expected ")"`}},
+		{"pid == 1)", []string{"column 9", `unexpected ")"`}},
+		{"pid == 1 pid == 2", []string{"column 10", `unexpected "pid"`}},
+		{"pid == 1 &&", []string{"column 12", "expected field name"}},
+		{"pid == 1 ||| pid == 2", []string{"column 12", `unexpected character '|'`}},
+		{"Filename == 'abc", []string{"column 13", "unterminated string"}},
+		{"pid # 1", []string{"column 5", "unexpected character '#'"}},
+		{"ret in 5..1", []string{"column 5", "empty range"}},
+		{"ret in 1 2", []string{"column 10", `expected ".."`}},
+		{"ret in a..b", []string{"column 8", "expected number"}},
+		{"pid == 0x1g", []string{"column 8", `malformed number "0x1g"`}},
+		{"Filename =~ '('", []string{"column 13", "invalid regular expression"}},
+		{"Filename =~ 1", []string{"column 13", "expected regular expression string"}},
+		{"Filename glob '['", []string{"column 15", "invalid glob pattern"}},
+		{"Filename glob 1", []string{"column 15", "expected glob pattern string"}},
+	}
+
+	for _, tt := range tests {
+		_, err := compileRule(tt.rule)
+		if err == nil {
+			t.Errorf("compileRule(%q): expected an error", tt.rule)
+			continue
+		}
+		for _, s := range tt.errs {
+			if !strings.Contains(err.Error(), s) {
+				t.Errorf("compileRule(%q): error %q doesn't contain %q", tt.rule, err, s)
+			}
+		}
+	}
+}
+
+func TestRuleStringEscapes(t *testing.T) {
+	r, err := compileRule(`Filename == 'it\'s' || Filename == "a\"b"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.match(openEvent("it's", 1, 0, 0)) || !r.match(openEvent(`a"b`, 1, 0, 0)) {
+		t.Errorf("escaped quotes not unescaped")
+	}
+}
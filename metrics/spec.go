@@ -11,6 +11,32 @@ type Channel struct {
 	Id   string `json:"id" yaml:"id"`
 	Type string `json:"type" yaml:"type"`
 	Path string `json:"path" yaml:"path"`
+
+	// grpc channels only, see grpc-channel.go for the defaults
+	BatchSize     int         `json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+	FlushInterval string      `json:"flush_interval,omitempty" yaml:"flush_interval,omitempty"`
+	AckTimeout    string      `json:"ack_timeout,omitempty" yaml:"ack_timeout,omitempty"`
+	StreamBatches int         `json:"stream_batches,omitempty" yaml:"stream_batches,omitempty"`
+	SpoolDir      string      `json:"spool_dir,omitempty" yaml:"spool_dir,omitempty"`
+	SpoolMaxBytes int64       `json:"spool_max_bytes,omitempty" yaml:"spool_max_bytes,omitempty"`
+	TLS           *ChannelTLS `json:"tls,omitempty" yaml:"tls,omitempty"`
+}
+
+// ChannelTLS are the TLS settings of a grpc channel, ignored with
+// AggregatorOptions.DialInsecure
+type ChannelTLS struct {
+	// PEM bundle of the CAs verifying the collector, instead of the system
+	// roots
+	CAFile string `json:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+	// client certificate and key, for mutual TLS
+	CertFile string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	// name verified in the certificate of the collector, instead of the host
+	// of the channel path
+	ServerName string `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+	// base64 SHA-256 digests of public keys (SubjectPublicKeyInfo), one of
+	// which must be in the certificate chain of the collector
+	PinnedSPKI []string `json:"pinned_spki,omitempty" yaml:"pinned_spki,omitempty"`
 }
 
 type EventSpec struct {
@@ -21,6 +47,7 @@ type EventSpec struct {
 	Rule      string   `json:"rule" yaml:"rule"`
 	F         Function `json:"function" yaml:"function"`
 	O         Output   `json:"output" yaml:"output"`
+	rule      *rule
 }
 
 type Function struct {
@@ -31,6 +58,10 @@ type Function struct {
 
 type Output struct {
 	Metrics string `json:"metrics" yaml:"metrics"`
-	Format  string `json:"format" yaml:"format"`
-	state   outputFunc
+	// text (default), json, ndjson, collector_spec_pb or csv, see encoder.go
+	Format string `json:"format" yaml:"format"`
+	// name=value;... like the parameters of the processing functions
+	Parameters string `json:"parameters" yaml:"parameters"`
+	state      outputFunc
+	encoder    encoder
 }
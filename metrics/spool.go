@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/ShiftLeftSecurity/traceleft/tracer"
+)
+
+// spool is a bounded on-disk queue holding the metrics of a grpc channel
+// while the collector is unreachable. Metrics are stored as length-prefixed
+// protobuf messages in a single file, which is kept across restarts.
+type spool struct {
+	path     string
+	maxBytes int64
+	size     int64
+}
+
+// maxSpoolRecord is the largest metric the spool holds, like the default
+// maximum size of a gRPC message. A longer length prefix in the file means it
+// is corrupt.
+const maxSpoolRecord = 4 << 20
+
+func openSpool(dir, channelID string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating spool directory: %v", err)
+	}
+
+	s := &spool{
+		// the id is escaped, e.g. to stay in dir
+		path:     filepath.Join(dir, fmt.Sprintf("channel-%s.spool", url.PathEscape(channelID))),
+		maxBytes: maxBytes,
+	}
+	if fi, err := os.Stat(s.path); err == nil {
+		s.size = fi.Size()
+	}
+
+	return s, nil
+}
+
+func (s *spool) empty() bool {
+	return s.size == 0
+}
+
+// push appends the metrics to the spool, until it is full. It returns how
+// many were written.
+func (s *spool) push(metrics []*tracer.Metric) (int, error) {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("error opening spool: %v", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	written := 0
+	for _, m := range metrics {
+		b, err := proto.Marshal(m)
+		if err != nil {
+			return written, fmt.Errorf("error marshaling metric: %v", err)
+		}
+		if len(b) > maxSpoolRecord {
+			return written, fmt.Errorf("metric of %d bytes too large to spool", len(b))
+		}
+		if s.size+int64(4+len(b)) > s.maxBytes {
+			break
+		}
+		var length [4]byte
+		binary.LittleEndian.PutUint32(length[:], uint32(len(b)))
+		if _, err := w.Write(length[:]); err != nil {
+			return written, fmt.Errorf("error writing spool: %v", err)
+		}
+		if _, err := w.Write(b); err != nil {
+			return written, fmt.Errorf("error writing spool: %v", err)
+		}
+		s.size += int64(4 + len(b))
+		written++
+	}
+	if err := w.Flush(); err != nil {
+		return written, fmt.Errorf("error writing spool: %v", err)
+	}
+
+	return written, nil
+}
+
+// load returns all the metrics of the spool
+func (s *spool) load() ([]*tracer.Metric, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening spool: %v", err)
+	}
+	defer f.Close()
+
+	var metrics []*tracer.Metric
+	r := bufio.NewReader(f)
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(r, length[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			// truncated by a crash while writing
+			break
+		}
+		n := binary.LittleEndian.Uint32(length[:])
+		if n > maxSpoolRecord {
+			// the rest of the file is corrupt
+			break
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			break
+		}
+		m := &tracer.Metric{}
+		if err := proto.Unmarshal(b, m); err != nil {
+			return nil, fmt.Errorf("error unmarshaling spooled metric: %v", err)
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics, nil
+}
+
+// clear empties the spool once its metrics were replayed
+func (s *spool) clear() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing spool: %v", err)
+	}
+	s.size = 0
+	return nil
+}
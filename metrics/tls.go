@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// transportCredentials returns the TLS credentials of a grpc channel: the
+// system roots by default, or the CA bundle of the channel, with a client
+// certificate for mutual TLS and the SPKI pins if any.
+func (c Channel) transportCredentials() (credentials.TransportCredentials, error) {
+	serverName, _, err := net.SplitHostPort(c.Path)
+	if err != nil {
+		serverName = c.Path
+	}
+	config := &tls.Config{ServerName: serverName}
+
+	t := c.TLS
+	if t == nil {
+		return credentials.NewTLS(config), nil
+	}
+
+	if t.ServerName != "" {
+		config.ServerName = t.ServerName
+	}
+
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %v", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in CA bundle %q", t.CAFile)
+		}
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, fmt.Errorf("both a client certificate and a key are needed")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(t.PinnedSPKI) > 0 {
+		pins := make(map[[sha256.Size]byte]bool)
+		for _, p := range t.PinnedSPKI {
+			b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p, "sha256/"))
+			if err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("invalid SPKI pin %q", p)
+			}
+			var pin [sha256.Size]byte
+			copy(pin[:], b)
+			pins[pin] = true
+		}
+		config.VerifyPeerCertificate = func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+			return verifyPins(pins, verifiedChains)
+		}
+	}
+
+	return credentials.NewTLS(config), nil
+}
+
+// verifyPins checks that the public key of a certificate of the chains
+// verified by the TLS handshake is pinned
+func verifyPins(pins map[[sha256.Size]byte]bool, verifiedChains [][]*x509.Certificate) error {
+	for _, chain := range verifiedChains {
+		for _, cert := range chain {
+			if pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("no pinned public key in the certificate chain of the collector")
+}
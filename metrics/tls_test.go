@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+var testSerial int64
+
+// newTestCert returns a certificate for name, a CA one if isCA, signed by
+// parent or self-signed if parent is nil
+func newTestCert(t *testing.T, name string, isCA bool, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSerial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(testSerial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if isCA {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		template.DNSNames = []string{name}
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+// pin returns the SPKI pin of the certificate, as in ChannelTLS.PinnedSPKI
+func (c *testCert) pin() string {
+	sum := sha256.Sum256(c.cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeCert writes the PEM certificate and key, and returns their paths
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	key, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// serveTLS accepts TLS connections with the certificate chain, requiring a
+// client certificate verified by clientCAs if not nil
+func serveTLS(t *testing.T, chain []*testCert, clientCAs *x509.CertPool) (string, func()) {
+	cert := tls.Certificate{PrivateKey: chain[0].key}
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.cert.Raw)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if clientCAs != nil {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = clientCAs
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	return l.Addr().String(), func() { l.Close() }
+}
+
+// handshake connects to the channel path with its transport credentials
+func handshake(c Channel) error {
+	creds, err := c.transportCredentials()
+	if err != nil {
+		return err
+	}
+	conn, err := net.Dial("tcp", c.Path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tlsConn, _, err := creds.ClientHandshake(ctx, c.Path, conn)
+	if err != nil {
+		return err
+	}
+	return tlsConn.Close()
+}
+
+func TestTransportCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// root -> intermediate -> collector, and root -> client
+	root := newTestCert(t, "root", true, nil)
+	intermediate := newTestCert(t, "intermediate", true, root)
+	leaf := newTestCert(t, "collector.test", false, intermediate)
+	client := newTestCert(t, "client.test", false, root)
+	other := newTestCert(t, "other", true, nil)
+
+	caFile, _ := root.write(t, dir, "root")
+	clientCert, clientKey := client.write(t, dir, "client")
+	_, otherKey := other.write(t, dir, "other")
+	garbage := filepath.Join(dir, "garbage.pem")
+	if err := ioutil.WriteFile(garbage, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	addr, stop := serveTLS(t, []*testCert{leaf, intermediate}, nil)
+	defer stop()
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(root.cert)
+	mutualAddr, stopMutual := serveTLS(t, []*testCert{leaf, intermediate}, clientCAs)
+	defer stopMutual()
+
+	tests := []struct {
+		name string
+		path string
+		tls  *ChannelTLS
+		err  string
+	}{
+		{"CA", addr, &ChannelTLS{CAFile: caFile, ServerName: "collector.test"}, ""},
+		{"system roots", addr, &ChannelTLS{ServerName: "collector.test"}, "certificate signed by unknown authority"},
+		{"no TLS settings", addr, nil, "certificate"},
+		{"server name mismatch", addr, &ChannelTLS{CAFile: caFile, ServerName: "other.test"}, "other.test"},
+		{"leaf pin", addr, &ChannelTLS{CAFile: caFile, ServerName: "collector.test", PinnedSPKI: []string{leaf.pin()}}, ""},
+		{"intermediate pin", addr, &ChannelTLS{CAFile: caFile, ServerName: "collector.test", PinnedSPKI: []string{other.pin(), intermediate.pin()}}, ""},
+		{"root pin", addr, &ChannelTLS{CAFile: caFile, ServerName: "collector.test", PinnedSPKI: []string{strings.TrimPrefix(root.pin(), "sha256/")}}, ""},
+		{"pin mismatch", addr, &ChannelTLS{CAFile: caFile, ServerName: "collector.test", PinnedSPKI: []string{other.pin()}}, "no pinned public key"},
+		{"invalid pin", addr, &ChannelTLS{CAFile: caFile, PinnedSPKI: []string{"sha256/abc"}}, `invalid SPKI pin "sha256/abc"`},
+		{"missing CA file", addr, &ChannelTLS{CAFile: filepath.Join(dir, "missing.pem")}, "error reading CA bundle"},
+		{"garbage CA file", addr, &ChannelTLS{CAFile: garbage}, "no certificate found in CA bundle"},
+		{"mutual TLS", mutualAddr, &ChannelTLS{CAFile: caFile, ServerName: "collector.test", CertFile: clientCert, KeyFile: clientKey}, ""},
+		{"mismatched key", mutualAddr, &ChannelTLS{CAFile: caFile, CertFile: clientCert, KeyFile: otherKey}, "error loading client certificate"},
+		{"certificate without key", mutualAddr, &ChannelTLS{CAFile: caFile, CertFile: clientCert}, "both a client certificate and a key are needed"},
+	}
+
+	for _, tt := range tests {
+		err := handshake(Channel{Id: "1", Type: "grpc", Path: tt.path, TLS: tt.tls})
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+
+	elflib "github.com/iovisor/gobpf/elf"
+)
+
+// Backend selects how the global BPF program captures syscalls. Kernel
+// functions which are not syscalls (fd_install, tcp_*, ...) are always
+// traced with kprobes.
+type Backend int
+
+const (
+	// BackendKprobe attaches to the kernel functions implementing the
+	// syscalls (see kprobe.go).
+	BackendKprobe Backend = iota
+	// BackendTracepoint attaches to the syscalls:sys_enter_* and
+	// syscalls:sys_exit_* tracepoints. It is more stable across kernel
+	// versions but needs handlers built with tracepoint programs.
+	BackendTracepoint
+)
+
+const Backends = "kprobe|tracepoint"
+
+func (b Backend) String() string {
+	switch b {
+	case BackendKprobe:
+		return "kprobe"
+	case BackendTracepoint:
+		return "tracepoint"
+	}
+	return fmt.Sprintf("Backend(%d)", int(b))
+}
+
+func ParseBackend(s string) (Backend, error) {
+	switch s {
+	case "kprobe":
+		return BackendKprobe, nil
+	case "tracepoint":
+		return BackendTracepoint, nil
+	}
+	return 0, fmt.Errorf("unknown backend %q (expected %s)", s, Backends)
+}
+
+// prefixes of the syscall tracepoint programs in bpf/trace_events.c, and of
+// the ones following the processes, enabled with every backend
+const (
+	syscallTracepointPrefix = "tracepoint/syscalls/"
+	schedTracepointPrefix   = "tracepoint/sched/"
+)
+
+func enableSyscallTracepoints(module *elflib.Module) error {
+	return enableTracepoints(module, syscallTracepointPrefix)
+}
+
+func enableTracepoints(module *elflib.Module, prefix string) error {
+	for tp := range module.IterTracepointProgram() {
+		if !strings.HasPrefix(tp.Name, prefix) {
+			continue
+		}
+		if err := module.EnableTracepoint(tp.Name); err != nil {
+			return fmt.Errorf("error enabling %q: %v", tp.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Prog arrays are indexed by TGID for handlers registered for a process,
+// followed by slots for handlers registered for a cgroup. This must match
+// bpf/trace_events.c.
+const (
+	maxPids        = 32768
+	maxCgroupSlots = 256
+)
+
+// CgroupID returns the id of the cgroup (in the unified hierarchy) at the
+// given path, as seen by the BPF programs.
+func CgroupID(path string) (uint64, error) {
+	var statfs unix.Statfs_t
+	if err := unix.Statfs(path, &statfs); err != nil {
+		return 0, fmt.Errorf("error getting id of cgroup %q: %v", path, err)
+	}
+	if statfs.Type != unix.CGROUP2_SUPER_MAGIC {
+		return 0, fmt.Errorf("%q is not in a cgroup v2 hierarchy", path)
+	}
+
+	// struct file_handle {
+	//	unsigned int  handle_bytes;
+	//	int           handle_type;
+	//	unsigned char f_handle[0];
+	// };
+	const maxHandleSize = 128
+	buf := make([]byte, 8+maxHandleSize)
+	binary.LittleEndian.PutUint32(buf[0:4], maxHandleSize)
+
+	pathBytes, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var mountID int32
+	dirfd := unix.AT_FDCWD
+	_, _, errno := syscall.Syscall6(unix.SYS_NAME_TO_HANDLE_AT,
+		uintptr(dirfd),
+		uintptr(unsafe.Pointer(pathBytes)),
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&mountID)),
+		0, 0)
+	switch errno {
+	case 0:
+		handleBytes := binary.LittleEndian.Uint32(buf[0:4])
+		if handleBytes != 8 {
+			return 0, fmt.Errorf("unexpected file handle size %d for cgroup %q", handleBytes, path)
+		}
+		return binary.LittleEndian.Uint64(buf[8:16]), nil
+	case syscall.EOPNOTSUPP:
+		// kernels before 4.14 don't support file handles for cgroups,
+		// the BPF programs use the inode number instead
+		var stat syscall.Stat_t
+		if err := syscall.Stat(path, &stat); err != nil {
+			return 0, fmt.Errorf("error getting id of cgroup %q: %v", path, err)
+		}
+		return stat.Ino, nil
+	default:
+		return 0, fmt.Errorf("error getting id of cgroup %q: %v", path, errno)
+	}
+}
+
+func (probe *Probe) allocCgroupSlot(cgroupID uint64) (uint32, error) {
+	if slot, ok := probe.cgroupSlots[cgroupID]; ok {
+		return slot, nil
+	}
+
+	used := make(map[uint32]struct{}, len(probe.cgroupSlots))
+	for _, slot := range probe.cgroupSlots {
+		used[slot] = struct{}{}
+	}
+	for slot := uint32(maxPids); slot < maxPids+maxCgroupSlots; slot++ {
+		if _, ok := used[slot]; !ok {
+			return slot, nil
+		}
+	}
+
+	return 0, fmt.Errorf("too many cgroups traced (maximum %d)", maxCgroupSlots)
+}
+
+// RegisterHandlerForCgroup registers a handler for all the processes in a
+// cgroup, including the ones started later. Handlers registered for a
+// process take precedence.
+func (probe *Probe) RegisterHandlerForCgroup(programID, cgroupID uint64, elfBPF []byte) error {
+	for {
+		handler, err := probe.getHandler(elfBPF)
+		if err != nil {
+			return err
+		}
+
+		probe.mu.Lock()
+		err = probe.registerHandlerForCgroup(programID, cgroupID, handler)
+		probe.mu.Unlock()
+
+		// the handler was evicted since it was looked up, e.g. by the
+		// handlers of another cgroup or process being registered at the
+		// same time, load it again
+		if err != ErrNotInCache {
+			return err
+		}
+	}
+}
+
+// registerHandlerForCgroup must be called with probe.mu held
+func (probe *Probe) registerHandlerForCgroup(programID, cgroupID uint64, handler *Handler) error {
+	if handler.closed {
+		// evicted since it was looked up in the cache
+		return ErrNotInCache
+	}
+
+	slot, err := probe.allocCgroupSlot(cgroupID)
+	if err != nil {
+		return err
+	}
+
+	if err := probe.updateProgArrays(slot, handler); err != nil {
+		return err
+	}
+
+	progIDTable := probe.module.Map("program_id_per_cgroup")
+	if progIDTable == nil {
+		return fmt.Errorf("program_id_per_cgroup doesn't exist")
+	}
+	if err := probe.module.UpdateElement(progIDTable, unsafe.Pointer(&cgroupID), unsafe.Pointer(&programID), 0); err != nil {
+		return fmt.Errorf("error updating the cgroup program id table: %v", err)
+	}
+
+	slotsTable := probe.module.Map("cgroup_slots")
+	if slotsTable == nil {
+		return fmt.Errorf("cgroup_slots doesn't exist")
+	}
+	if err := probe.module.UpdateElement(slotsTable, unsafe.Pointer(&cgroupID), unsafe.Pointer(&slot), 0); err != nil {
+		return fmt.Errorf("error updating %q: %v", slotsTable.Name, err)
+	}
+
+	probe.cgroupSlots[cgroupID] = slot
+	if _, ok := probe.cgroupToHandlers[cgroupID]; !ok {
+		probe.cgroupToHandlers[cgroupID] = make(map[string]*Handler)
+	}
+	probe.setHandler(probe.cgroupToHandlers[cgroupID], handler)
+
+	return nil
+}
+
+// UnregisterCgroup unregisters all the handlers of a cgroup.
+func (probe *Probe) UnregisterCgroup(cgroupID uint64) error {
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+
+	slot, ok := probe.cgroupSlots[cgroupID]
+	if !ok {
+		return nil
+	}
+
+	slotsTable := probe.module.Map("cgroup_slots")
+	if slotsTable == nil {
+		return fmt.Errorf("cgroup_slots doesn't exist")
+	}
+	if err := probe.module.DeleteElement(slotsTable, unsafe.Pointer(&cgroupID)); err != nil {
+		return fmt.Errorf("error deleting %q: %v", slotsTable.Name, err)
+	}
+
+	for handlerName := range probe.cgroupToHandlers[cgroupID] {
+		if err := probe.unregisterHandler(int(slot), handlerName); err != nil {
+			return err
+		}
+	}
+
+	if progIDTable := probe.module.Map("program_id_per_cgroup"); progIDTable != nil {
+		probe.module.DeleteElement(progIDTable, unsafe.Pointer(&cgroupID))
+	}
+
+	for _, handler := range probe.cgroupToHandlers[cgroupID] {
+		probe.releaseHandler(handler)
+	}
+	delete(probe.cgroupSlots, cgroupID)
+	delete(probe.cgroupToHandlers, cgroupID)
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package probe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	elflib "github.com/iovisor/gobpf/elf"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	kprobeEventsPath = "/sys/kernel/debug/tracing/kprobe_events"
+	kprobeIDPath     = "/sys/kernel/debug/tracing/events/kprobes/%s/id"
+
+	// prefix of the syscall probes in bpf/trace_events.c
+	syscallPrefix = "SyS_"
+
+	perfFlagFdCloexec = 0x8 // PERF_FLAG_FD_CLOEXEC
+)
+
+// syscallWrapperPrefixes returns the prefixes of the pt_regs wrappers of the
+// syscalls on the current architecture (kernels >= 4.17).
+func syscallWrapperPrefixes() []string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return []string{"__x64_sys_"}
+	case "386":
+		return []string{"__ia32_sys_"}
+	case "arm64":
+		return []string{"__arm64_sys_"}
+	case "s390x":
+		return []string{"__s390x_sys_"}
+	}
+	return nil
+}
+
+// readKallsyms returns the set of the functions in /proc/kallsyms
+func readKallsyms() (map[string]struct{}, error) {
+	f, err := os.Open("/proc/kallsyms")
+	if err != nil {
+		return nil, fmt.Errorf("error opening kallsyms: %v", err)
+	}
+	defer f.Close()
+
+	return parseKallsyms(f)
+}
+
+// parseKallsyms returns the set of the functions in the kallsyms r, ignoring
+// malformed lines
+func parseKallsyms(r io.Reader) (map[string]struct{}, error) {
+	symbols := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// address type name [module]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		if t := fields[1]; t != "t" && t != "T" {
+			continue
+		}
+		symbols[fields[2]] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading kallsyms: %v", err)
+	}
+
+	return symbols, nil
+}
+
+// resolveSyscallSymbol returns the kernel function to probe for the syscall
+// name (e.g. "read") and whether it is a pt_regs wrapper, with the given
+// prefixes of the wrappers (see syscallWrapperPrefixes).
+func resolveSyscallSymbol(symbols map[string]struct{}, wrapperPrefixes []string, name string) (string, bool, error) {
+	for _, prefix := range wrapperPrefixes {
+		if _, ok := symbols[prefix+name]; ok {
+			return prefix + name, true, nil
+		}
+	}
+	for _, prefix := range []string{"SyS_", "sys_"} {
+		if _, ok := symbols[prefix+name]; ok {
+			return prefix + name, false, nil
+		}
+	}
+
+	return "", false, fmt.Errorf("couldn't find the kernel function of syscall %q", name)
+}
+
+// syscallAttacher enables the syscall kprobes of the global BPF module on the
+// kernel function actually implementing the syscall, which depends on the
+// kernel version. Other kprobes are enabled as they are named.
+type syscallAttacher struct {
+	module  *elflib.Module
+	symbols map[string]struct{}
+
+	// perf event fds of the kprobes enabled by the attacher, the module
+	// only closes the ones it enabled itself
+	efds []int
+}
+
+// enableKprobes enables the kprobes of the module, skipping the syscall ones
+// unless syscalls is set, and the ones of the syscalls the running kernel
+// doesn't have. It returns whether syscalls are probed through their pt_regs
+// wrappers.
+func (a *syscallAttacher) enableKprobes(maxactive int, syscalls bool) (wrapped bool, err error) {
+	for kp := range a.module.IterKprobes() {
+		isKretprobe := strings.HasPrefix(kp.Name, "kretprobe/")
+		funcName := strings.TrimPrefix(strings.TrimPrefix(kp.Name, "kretprobe/"), "kprobe/")
+
+		if strings.HasPrefix(funcName, syscallPrefix) && !syscalls {
+			continue
+		}
+
+		if !strings.HasPrefix(funcName, syscallPrefix) {
+			if err := a.module.EnableKprobe(kp.Name, maxactive); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		symbol, isWrapper, err := resolveSyscallSymbol(a.symbols, syscallWrapperPrefixes(), strings.TrimPrefix(funcName, syscallPrefix))
+		if err != nil {
+			// e.g. a syscall of another architecture, or not
+			// compiled in, whose events never happen
+			log.Printf("warning: not probing %q: %v", kp.Name, err)
+			continue
+		}
+		wrapped = wrapped || isWrapper
+
+		if symbol == funcName {
+			if err := a.module.EnableKprobe(kp.Name, maxactive); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		// use the event name the module uses for this section, so that
+		// closing the module removes the kprobe event
+		probeType, maxactiveStr := "p", ""
+		if isKretprobe {
+			probeType = "r"
+			if maxactive > 0 {
+				maxactiveStr = strconv.Itoa(maxactive)
+			}
+		}
+		eventName := probeType + funcName
+
+		kprobeID, err := writeKprobeEvent(probeType+maxactiveStr, eventName, symbol)
+		if err != nil && maxactiveStr != "" {
+			// kernels before 4.12 don't support maxactive
+			kprobeID, err = writeKprobeEvent(probeType, eventName, symbol)
+		}
+		if err != nil {
+			return false, err
+		}
+
+		efd, err := attachPerfEvent(kprobeID, kp.Fd())
+		if err != nil {
+			return false, fmt.Errorf("error attaching %q to %q: %v", kp.Name, symbol, err)
+		}
+		a.efds = append(a.efds, efd)
+	}
+
+	return wrapped, nil
+}
+
+func writeKprobeEvent(probeType, eventName, funcName string) (int, error) {
+	f, err := os.OpenFile(kprobeEventsPath, os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return -1, fmt.Errorf("cannot open kprobe_events: %v", err)
+	}
+	defer f.Close()
+
+	cmd := fmt.Sprintf("%s:%s %s\n", probeType, eventName, funcName)
+	if _, err := f.WriteString(cmd); err != nil {
+		return -1, fmt.Errorf("cannot write %q to kprobe_events: %v", cmd, err)
+	}
+
+	idBytes, err := ioutil.ReadFile(fmt.Sprintf(kprobeIDPath, eventName))
+	if err != nil {
+		return -1, fmt.Errorf("cannot read kprobe id: %v", err)
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(string(idBytes)))
+	if err != nil {
+		return -1, fmt.Errorf("invalid kprobe id: %v", err)
+	}
+
+	return id, nil
+}
+
+func attachPerfEvent(id int, progFd int) (int, error) {
+	attr := unix.PerfEventAttr{
+		Type:        unix.PERF_TYPE_TRACEPOINT,
+		Config:      uint64(id),
+		Sample_type: unix.PERF_SAMPLE_RAW,
+		Sample:      1,
+		Wakeup:      1,
+	}
+	attr.Size = uint32(unsafe.Sizeof(attr))
+
+	efd, err := unix.PerfEventOpen(&attr, -1 /* pid */, 0 /* cpu */, -1 /* group_fd */, perfFlagFdCloexec)
+	if err != nil {
+		return -1, fmt.Errorf("perf_event_open error: %v", err)
+	}
+
+	if err := unix.IoctlSetInt(efd, unix.PERF_EVENT_IOC_ENABLE, 0); err != nil {
+		unix.Close(efd)
+		return -1, fmt.Errorf("error enabling perf event: %v", err)
+	}
+	if err := unix.IoctlSetInt(efd, unix.PERF_EVENT_IOC_SET_BPF, progFd); err != nil {
+		unix.Close(efd)
+		return -1, fmt.Errorf("error attaching bpf program to perf event: %v", err)
+	}
+
+	return efd, nil
+}
+
+func (a *syscallAttacher) close() error {
+	for _, efd := range a.efds {
+		if err := unix.Close(efd); err != nil {
+			return fmt.Errorf("error closing perf event fd: %v", err)
+		}
+	}
+	a.efds = nil
+	return nil
+}
+
+// setSyscallWrapper tells the handlers whether the syscall arguments are
+// behind a pt_regs wrapper
+func setSyscallWrapper(module *elflib.Module, wrapped bool) error {
+	wrapperMap := module.Map("syscall_wrapper")
+	if wrapperMap == nil {
+		return fmt.Errorf("syscall_wrapper doesn't exist")
+	}
+
+	var key, value uint32 = 0, 0
+	if wrapped {
+		value = 1
+	}
+	if err := module.UpdateElement(wrapperMap, unsafe.Pointer(&key), unsafe.Pointer(&value), 0); err != nil {
+		return fmt.Errorf("error updating %q: %v", wrapperMap.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package probe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKallsyms(t *testing.T) {
+	kallsyms := `ffffffff81000000 T _stext
+ffffffff81001000 t do_one_initcall
+ffffffff812a4b30 T __x64_sys_open
+ffffffff812a4b60 T __ia32_sys_open
+ffffffff812a4c00 W sys_ni_syscall
+ffffffffc0a01000 t nf_conntrack_init	[nf_conntrack]
+ffffffff82000000 D jiffies
+ffffffff82000100 b some_bss
+ffffffff81002000 T
+malformed
+ffffffff81003000 T SyS_read extra fields
+
+`
+	symbols, err := parseKallsyms(strings.NewReader(kallsyms))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct{}{
+		"_stext":            {},
+		"do_one_initcall":   {},
+		"__x64_sys_open":    {},
+		"__ia32_sys_open":   {},
+		"nf_conntrack_init": {},
+		"SyS_read":          {},
+	}
+	if !reflect.DeepEqual(symbols, want) {
+		t.Errorf("got %v, want %v", symbols, want)
+	}
+}
+
+func TestResolveSyscallSymbol(t *testing.T) {
+	symbols := func(names ...string) map[string]struct{} {
+		m := make(map[string]struct{})
+		for _, name := range names {
+			m[name] = struct{}{}
+		}
+		return m
+	}
+	x64 := []string{"__x64_sys_"}
+
+	tests := []struct {
+		symbols  map[string]struct{}
+		prefixes []string
+		name     string
+		symbol   string
+		wrapper  bool
+		err      bool
+	}{
+		// kernels >= 4.17
+		{symbols("__x64_sys_open", "__ia32_sys_open", "do_sys_open"), x64, "open", "__x64_sys_open", true, false},
+		{symbols("__ia32_sys_open"), []string{"__ia32_sys_"}, "open", "__ia32_sys_open", true, false},
+		// the wrappers of other architectures aren't used
+		{symbols("__ia32_sys_open", "sys_open"), x64, "open", "sys_open", false, false},
+		// kernels < 4.17, SyS_ before sys_
+		{symbols("SyS_open", "sys_open"), x64, "open", "SyS_open", false, false},
+		{symbols("sys_open"), x64, "open", "sys_open", false, false},
+		{symbols("sys_open"), nil, "open", "sys_open", false, false},
+		// missing
+		{symbols("sys_openat", "__x64_sys_openat"), x64, "open", "", false, true},
+		{symbols(), x64, "open", "", false, true},
+	}
+
+	for _, tt := range tests {
+		symbol, wrapper, err := resolveSyscallSymbol(tt.symbols, tt.prefixes, tt.name)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s in %v: got %q, expected an error", tt.name, tt.symbols, symbol)
+			}
+			continue
+		}
+		if err != nil || symbol != tt.symbol || wrapper != tt.wrapper {
+			t.Errorf("%s in %v: got %q %t %v, want %q %t", tt.name, tt.symbols, symbol, wrapper, err, tt.symbol, tt.wrapper)
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"crypto/sha512"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"unsafe"
 
 	"github.com/hashicorp/golang-lru"
@@ -14,14 +16,54 @@ import (
 )
 
 type Probe struct {
-	module        *elflib.Module
-	handlerCache  *lru.Cache                  // hash -> *Handler
-	pidToHandlers map[int]map[string]struct{} // pid -> syscalls handled (42 -> ["handle_read": struct{}{}, "handle_write": struct{}{}])
+	module       *elflib.Module
+	attacher     *syscallAttacher
+	backend      Backend
+	handlerCache *lru.Cache // hash -> *Handler
+
+	mu             sync.Mutex
+	pidToHandlers  map[int]map[string]*Handler // pid -> syscalls handled (42 -> ["handle_read": h1, "handle_write": h2])
+	pidToProgramID map[int]uint64
+	followChildren bool
+
+	cgroupSlots      map[uint64]uint32 // cgroup id -> index in the prog arrays
+	cgroupToHandlers map[uint64]map[string]*Handler
 }
 
-func evictHandler(key interface{}, value interface{}) {
-	if h, ok := value.(*Handler); ok {
-		h.Close()
+// evictHandler closes the handlers evicted from the cache, unless they are
+// still registered for processes or cgroups: they are closed when they are
+// not anymore then (see releaseHandler).
+func (probe *Probe) evictHandler(key interface{}, value interface{}) {
+	h, ok := value.(*Handler)
+	if !ok {
+		return
+	}
+
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+
+	h.evicted = true
+	if h.refs == 0 {
+		h.close()
+	}
+}
+
+// setHandler registers a handler in the handlers of a process or cgroup, in
+// place of the one with the same name if any. probe.mu must be held.
+func (probe *Probe) setHandler(handlers map[string]*Handler, handler *Handler) {
+	handler.refs++
+	if old, ok := handlers[handler.name]; ok {
+		probe.releaseHandler(old)
+	}
+	handlers[handler.name] = handler
+}
+
+// releaseHandler drops a registration of a handler, and closes it once it's
+// neither registered nor cached anymore. probe.mu must be held.
+func (probe *Probe) releaseHandler(h *Handler) {
+	h.refs--
+	if h.refs == 0 && h.evicted {
+		h.close()
 	}
 }
 
@@ -34,6 +76,19 @@ type Handler struct {
 
 	fd    int // file descriptor of the kprobe handler bpf program
 	fdRet int // file descriptor of the kretprobe handler bpf program
+
+	// file descriptors of the tracepoint handler bpf programs, for the
+	// tracepoint backend. Only syscall handlers have them.
+	hasTracepoints bool
+	tpFd           int
+	tpFdRet        int
+
+	// number of processes and cgroups the handler is registered for, and
+	// whether it was evicted from the handler cache and closed. Guarded by
+	// Probe.mu.
+	refs    int
+	evicted bool
+	closed  bool
 }
 
 func sha512hex(d []byte) string {
@@ -83,12 +138,33 @@ func newHandler(elfBPF []byte) (*Handler, error) {
 		return nil, fmt.Errorf("malformed ELF file, both kprobe and kretprobe handlers should have the same name")
 	}
 
-	return &Handler{
+	handler := &Handler{
 		module: handlerBPF,
 		name:   name,
 		fd:     fd,
 		fdRet:  fdRet,
-	}, nil
+	}
+
+	var tpName, tpNameRet string
+	for tp := range handlerBPF.IterTracepointProgram() {
+		tpHandlerName := strings.TrimPrefix(tp.Name, "tracepoint/")
+		if strings.HasSuffix(tpHandlerName, "_ret") {
+			handler.tpFdRet = tp.Fd()
+			tpNameRet = strings.TrimSuffix(tpHandlerName, "_ret")
+		} else {
+			handler.tpFd = tp.Fd()
+			tpName = tpHandlerName
+		}
+	}
+
+	if tpName != "" || tpNameRet != "" {
+		if tpName != name || tpNameRet != name {
+			return nil, fmt.Errorf("malformed ELF file, tracepoint handlers should be named %q and %q", "tracepoint/"+name, "tracepoint/"+name+"_ret")
+		}
+		handler.hasTracepoints = true
+	}
+
+	return handler, nil
 }
 
 func generateProgArrayNames(name string) (progArrayName string, progArrayNameRet string) {
@@ -97,8 +173,24 @@ func generateProgArrayNames(name string) (progArrayName string, progArrayNameRet
 	return
 }
 
-func (probe *Probe) registerHandler(programID uint64, pid int, handler *Handler) error {
-	progArrayName, progArrayNameRet := generateProgArrayNames(handler.name)
+// progArrayNames returns the names of the prog arrays of a handler for the
+// backend of the probe, and whether they are for tracepoint programs
+func (probe *Probe) progArrayNames(name string) (progArrayName string, progArrayNameRet string, tracepoint bool) {
+	if probe.backend == BackendTracepoint {
+		tpProgArrayName := fmt.Sprintf("%s_tp_progs", name)
+		// only syscalls are traced with tracepoints
+		if probe.module.Map(tpProgArrayName) != nil {
+			return tpProgArrayName, tpProgArrayName + "_ret", true
+		}
+	}
+
+	progArrayName, progArrayNameRet = generateProgArrayNames(name)
+	return progArrayName, progArrayNameRet, false
+}
+
+// updateProgArrays sets the handler at the given index of its prog arrays
+func (probe *Probe) updateProgArrays(key uint32, handler *Handler) error {
+	progArrayName, progArrayNameRet, tracepoint := probe.progArrayNames(handler.name)
 
 	progTable := probe.module.Map(progArrayName)
 	if progTable == nil {
@@ -110,13 +202,35 @@ func (probe *Probe) registerHandler(programID uint64, pid int, handler *Handler)
 	}
 
 	var fd, fdRet int = handler.fd, handler.fdRet
-	if err := probe.module.UpdateElement(progTable, unsafe.Pointer(&pid), unsafe.Pointer(&fd), 0); err != nil {
+	if tracepoint {
+		if !handler.hasTracepoints {
+			return fmt.Errorf("handler %q has no tracepoint programs, it can't be used with the %s backend", handler.name, probe.backend)
+		}
+		fd, fdRet = handler.tpFd, handler.tpFdRet
+	}
+	if err := probe.module.UpdateElement(progTable, unsafe.Pointer(&key), unsafe.Pointer(&fd), 0); err != nil {
 		return fmt.Errorf("error updating %q: %v", progTable.Name, err)
 	}
-	if err := probe.module.UpdateElement(progTableRet, unsafe.Pointer(&pid), unsafe.Pointer(&fdRet), 0); err != nil {
+	if err := probe.module.UpdateElement(progTableRet, unsafe.Pointer(&key), unsafe.Pointer(&fdRet), 0); err != nil {
 		return fmt.Errorf("error updating %q: %v", progTableRet.Name, err)
 	}
 
+	return nil
+}
+
+func (probe *Probe) registerHandler(programID uint64, pid int, handler *Handler) error {
+	if pid < 0 || pid >= maxPids {
+		return fmt.Errorf("pid %d out of range (maximum %d)", pid, maxPids-1)
+	}
+	if handler.closed {
+		// evicted since it was looked up in the cache
+		return ErrNotInCache
+	}
+
+	if err := probe.updateProgArrays(uint32(pid), handler); err != nil {
+		return err
+	}
+
 	progIDTable := probe.module.Map("program_id_per_pid")
 	if progIDTable == nil {
 		return fmt.Errorf("program_id_per_pid doesn't exist")
@@ -136,11 +250,94 @@ func (probe *Probe) registerHandler(programID uint64, pid int, handler *Handler)
 		return fmt.Errorf("error updating %q: %v", watchMap.Name, err)
 	}
 
+	if probe.followChildren && pid != 0 {
+		if err := probe.follow(pid); err != nil {
+			return err
+		}
+	}
+
 	if _, ok := probe.pidToHandlers[pid]; !ok {
-		probe.pidToHandlers[pid] = make(map[string]struct{})
+		probe.pidToHandlers[pid] = make(map[string]*Handler)
+	}
+
+	probe.setHandler(probe.pidToHandlers[pid], handler)
+	probe.pidToProgramID[pid] = programID
+
+	return nil
+}
+
+// follow makes the children of pid inherit its handlers
+func (probe *Probe) follow(pid int) error {
+	followMap := probe.module.Map("follow_pids")
+	if followMap == nil {
+		return fmt.Errorf("follow_pids doesn't exist")
+	}
+
+	key := uint32(pid)
+	if err := probe.module.UpdateElement(followMap, unsafe.Pointer(&key), unsafe.Pointer(&key), 0); err != nil {
+		return fmt.Errorf("error updating %q: %v", followMap.Name, err)
+	}
+
+	return nil
+}
+
+func (probe *Probe) unfollow(pid int) error {
+	followMap := probe.module.Map("follow_pids")
+	if followMap == nil {
+		return fmt.Errorf("follow_pids doesn't exist")
+	}
+
+	key := uint32(pid)
+	// the entry doesn't exist if following children was not enabled
+	probe.module.DeleteElement(followMap, unsafe.Pointer(&key))
+
+	return nil
+}
+
+// SetFollowChildren enables or disables following children: when enabled,
+// processes forked by a process with registered handlers get the same
+// handlers and program id.
+func (probe *Probe) SetFollowChildren(enable bool) error {
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+
+	probe.followChildren = enable
+
+	for pid := range probe.pidToHandlers {
+		if pid == 0 {
+			continue
+		}
+		var err error
+		if enable {
+			err = probe.follow(pid)
+		} else {
+			err = probe.unfollow(pid)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InheritHandlers registers the handlers and program id of a process for its
+// child. It is called by the tracer when a followed process forks.
+func (probe *Probe) InheritHandlers(parentPid, childPid int) error {
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+
+	handlers, ok := probe.pidToHandlers[parentPid]
+	if !ok {
+		return nil
 	}
+	programID := probe.pidToProgramID[parentPid]
 
-	probe.pidToHandlers[pid][handler.name] = struct{}{}
+	for _, handler := range handlers {
+		if err := probe.registerHandler(programID, childPid, handler); err != nil {
+			return fmt.Errorf("error inheriting handler %q: %v", handler.name, err)
+		}
+	}
 
 	return nil
 }
@@ -156,6 +353,9 @@ func (probe *Probe) RegisterHandlerById(programID uint64, pid int, hash string)
 		return fmt.Errorf("invalid type")
 	}
 
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+
 	return probe.registerHandler(programID, pid, handler)
 }
 
@@ -167,6 +367,7 @@ func (probe *Probe) getHandler(elfBPF []byte) (handler *Handler, err error) {
 		if err != nil {
 			return
 		}
+		handler.id = id
 
 		probe.handlerCache.Add(id, handler)
 
@@ -182,16 +383,25 @@ func (probe *Probe) getHandler(elfBPF []byte) (handler *Handler, err error) {
 }
 
 func (probe *Probe) RegisterHandler(programID uint64, pid int, elfBPF []byte) error {
-	handler, err := probe.getHandler(elfBPF)
-	if err != nil {
-		return err
-	}
+	for {
+		handler, err := probe.getHandler(elfBPF)
+		if err != nil {
+			return err
+		}
 
-	return probe.registerHandler(programID, pid, handler)
+		probe.mu.Lock()
+		err = probe.registerHandler(programID, pid, handler)
+		probe.mu.Unlock()
+
+		// the handler was evicted since it was looked up, load it again
+		if err != ErrNotInCache {
+			return err
+		}
+	}
 }
 
-func (probe *Probe) unregisterHandler(programID uint64, pid int, handlerName string) error {
-	progArrayName, progArrayNameRet := generateProgArrayNames(handlerName)
+func (probe *Probe) unregisterHandler(pid int, handlerName string) error {
+	progArrayName, progArrayNameRet, _ := probe.progArrayNames(handlerName)
 	progTable := probe.module.Map(progArrayName)
 	if progTable == nil {
 		return fmt.Errorf("%q doesn't exist", progArrayName)
@@ -200,10 +410,6 @@ func (probe *Probe) unregisterHandler(programID uint64, pid int, handlerName str
 	if progTableRet == nil {
 		return fmt.Errorf("%q doesn't exist", progArrayNameRet)
 	}
-	progIDTable := probe.module.Map("program_id_per_pid")
-	if progIDTable == nil {
-		return fmt.Errorf("program_id_per_pid doesn't exist")
-	}
 
 	if err := probe.module.DeleteElement(progTable, unsafe.Pointer(&pid)); err != nil {
 		return fmt.Errorf("error deleting %q: %v", progTable.Name, err)
@@ -211,25 +417,57 @@ func (probe *Probe) unregisterHandler(programID uint64, pid int, handlerName str
 	if err := probe.module.DeleteElement(progTableRet, unsafe.Pointer(&pid)); err != nil {
 		return fmt.Errorf("error deleting %q: %v", progTableRet.Name, err)
 	}
-	if err := probe.module.DeleteElement(progIDTable, unsafe.Pointer(&pid)); err != nil {
-		return fmt.Errorf("error deleting program id table: %v", err)
-	}
-	delete(probe.pidToHandlers, pid)
 
 	return nil
 }
 
+// forgetPid deletes the entries of a process in the per-process maps. They
+// don't necessarily exist (e.g. for a followed child which didn't inherit the
+// handlers yet), so errors are ignored.
+func (probe *Probe) forgetPid(pid int) {
+	key := uint32(pid)
+	for _, name := range []string{"program_id_per_pid", "file_events_pids_to_watch", "follow_pids"} {
+		if m := probe.module.Map(name); m != nil {
+			probe.module.DeleteElement(m, unsafe.Pointer(&key))
+		}
+	}
+
+	for _, handler := range probe.pidToHandlers[pid] {
+		probe.releaseHandler(handler)
+	}
+	delete(probe.pidToHandlers, pid)
+	delete(probe.pidToProgramID, pid)
+}
+
+// UnregisterHandler unregisters all the handlers of a process and deletes its
+// entries in the BPF maps. The process is forgotten even if some handlers
+// fail to be unregistered, the errors are then returned together.
 func (probe *Probe) UnregisterHandler(programID uint64, pid int) error {
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+
+	var errs []string
 	for handlerName := range probe.pidToHandlers[pid] {
-		if err := probe.unregisterHandler(programID, pid, handlerName); err != nil {
-			return err
+		if err := probe.unregisterHandler(pid, handlerName); err != nil {
+			errs = append(errs, err.Error())
 		}
 	}
+	probe.forgetPid(pid)
 
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("error unregistering the handlers of pid %d: %s", pid, strings.Join(errs, "; "))
+	}
 	return nil
 }
 
 func (probe *Probe) Close() error {
+	// the kprobe events can only be removed by the module once nothing
+	// uses them
+	if err := probe.attacher.close(); err != nil {
+		return err
+	}
+
 	options := map[string]elflib.CloseOptions{
 		"maps/events": {
 			Unpin: true,
@@ -250,7 +488,13 @@ func (handler *Handler) Close() error {
 	return handler.module.Close()
 }
 
-func New(cacheSize int) (*Probe, error) {
+// close closes a handler evicted from the cache. Probe.mu must be held.
+func (handler *Handler) close() {
+	handler.closed = true
+	handler.Close()
+}
+
+func New(cacheSize int, backend Backend) (*Probe, error) {
 	if err := bpffs.Mount(); err != nil {
 		return nil, err
 	}
@@ -277,19 +521,52 @@ func New(cacheSize int) (*Probe, error) {
 		return nil, fmt.Errorf("error updating %q: %v", untrackedTable.Name, err)
 	}
 
+	symbols, err := readKallsyms()
+	if err != nil {
+		return nil, err
+	}
+
 	// TODO choose something here
-	if err := globalBPF.EnableKprobes(16); err != nil {
+	attacher := &syscallAttacher{module: globalBPF, symbols: symbols}
+	wrapped, err := attacher.enableKprobes(16, backend == BackendKprobe)
+	if err != nil {
+		attacher.close()
+		return nil, err
+	}
+
+	if err := enableTracepoints(globalBPF, schedTracepointPrefix); err != nil {
+		attacher.close()
+		return nil, err
+	}
+
+	if backend == BackendTracepoint {
+		if err := enableSyscallTracepoints(globalBPF); err != nil {
+			attacher.close()
+			return nil, err
+		}
+	}
+
+	if err := setSyscallWrapper(globalBPF, wrapped); err != nil {
+		attacher.close()
 		return nil, err
 	}
 
-	cache, err := lru.NewWithEvict(cacheSize, evictHandler)
+	probe := &Probe{
+		module:         globalBPF,
+		attacher:       attacher,
+		backend:        backend,
+		pidToHandlers:  make(map[int]map[string]*Handler),
+		pidToProgramID: make(map[int]uint64),
+
+		cgroupSlots:      make(map[uint64]uint32),
+		cgroupToHandlers: make(map[uint64]map[string]*Handler),
+	}
+
+	probe.handlerCache, err = lru.NewWithEvict(cacheSize, probe.evictHandler)
 	if err != nil {
+		attacher.close()
 		return nil, err
 	}
 
-	return &Probe{
-		module:        globalBPF,
-		handlerCache:  cache,
-		pidToHandlers: make(map[int]map[string]struct{}),
-	}, nil
+	return probe, nil
 }
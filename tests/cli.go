@@ -36,6 +36,7 @@ var (
 
 	ctx              tracer.Context
 	handlerCacheSize int
+	backendName      string
 
 	quiet bool
 )
@@ -43,6 +44,7 @@ var (
 func init() {
 	flag.StringVar(&outfile, "outfile", "", "where to write output to (defaults to stdout)")
 	flag.IntVar(&handlerCacheSize, "handler-cache-size", 4, "size of the eBPF handler cache")
+	flag.StringVar(&backendName, "backend", "kprobe", "how to capture syscalls: "+probe.Backends)
 	flag.BoolVar(&quiet, "quiet", false, "be quiet")
 	ctx.Fds = tracer.NewFdMap()
 }
@@ -81,6 +83,13 @@ func cmdTrace(args []string, p *probe.Probe) error {
 		if err := p.RegisterHandler(programID, pid, elfBPFBytes); err != nil {
 			return fmt.Errorf("error registering handler: %v", err)
 		}
+		// pid 0 is the default handler, not a process
+		if pid == 0 {
+			continue
+		}
+		if err := ctx.Fds.ScanPid(uint32(pid)); err != nil {
+			return fmt.Errorf("error reading file descriptors: %v", err)
+		}
 	}
 	return nil
 }
@@ -161,7 +170,7 @@ func handleEvent(data *[]byte) {
 		fmt.Fprintf(os.Stderr, "failed to decode common event data: %v\n", err)
 		return
 	}
-	msg := fmt.Sprintf("event %s pid %d return value %d ", commonEvent.Name, commonEvent.Pid, commonEvent.Ret)
+	msg := fmt.Sprintf("event %s pid %d return value %s ", commonEvent.Name, commonEvent.Pid, tracer.DecodeRet(commonEvent.Ret))
 	event, err := tracer.GetStruct(commonEvent, ctx, buf)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to get %q struct: %v\n", commonEvent.Name, err)
@@ -207,7 +216,13 @@ func main() {
 		f.Close()
 	}
 
-	tracer, err := tracer.New(handleEvent, handleLostEvent, handlerCacheSize)
+	backend, err := probe.ParseBackend(backendName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	tracer, err := tracer.New(handleEvent, handleLostEvent, handlerCacheSize, backend)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to get tracer: %v\n", err)
 		os.Exit(1)
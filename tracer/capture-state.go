@@ -0,0 +1,358 @@
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// State of the decoding of events, in captures.
+//
+// Besides the raw samples, decoding events reads /proc: the paths of the file
+// descriptors (on fd_install, and when seeding the FdMap of a newly traced
+// process), the sockets behind them, whether their files still exist, the
+// metadata of the processes and the names of users and groups. When recording
+// a capture (ctx.Capture set), what is read is also written to the capture as
+// state records, right before the sample whose decoding read it. When
+// replaying a capture (ctx.Replay set), these records are applied to the
+// context in the same order and /proc is never read, so that the events of a
+// capture decode the same on any machine.
+
+// CapturedState is the payload of a state record, only one of its fields is
+// set
+type CapturedState struct {
+	Fd      *CapturedFd  `json:"fd,omitempty"`
+	Process *ProcessInfo `json:"process,omitempty"`
+	ID      *CapturedID  `json:"id,omitempty"`
+}
+
+// CapturedFd is an entry of the FdMap or, if Name is set, the name of the
+// file descriptors with this entry when it's not its path or socket, e.g.
+// for deleted files or sockets not connected yet
+type CapturedFd struct {
+	Pid  uint32 `json:"pid"`
+	Fd   uint32 `json:"fd"`
+	Info FdInfo `json:"info"`
+	Name string `json:"name,omitempty"`
+}
+
+// CapturedID is the name of a user or group in the mount namespace of a
+// process
+type CapturedID struct {
+	Pid   int64  `json:"pid"`
+	Group bool   `json:"group,omitempty"`
+	ID    uint32 `json:"id"`
+	Name  string `json:"name"`
+}
+
+type capturedIDKey struct {
+	pid   int64
+	group bool
+	id    uint32
+}
+
+// ReplayState is the state of the processes of a capture being replayed,
+// besides their file descriptors in ctx.Fds
+type ReplayState struct {
+	sync.Mutex
+	processes map[int64]*ProcessInfo
+	names     map[FdInfo]string
+	ids       map[capturedIDKey]string
+}
+
+func NewReplayState() *ReplayState {
+	return &ReplayState{
+		processes: make(map[int64]*ProcessInfo),
+		names:     make(map[FdInfo]string),
+		ids:       make(map[capturedIDKey]string),
+	}
+}
+
+// applyState applies a state record of the capture being replayed
+func (ctx Context) applyState(s *CapturedState) {
+	r := ctx.Replay
+	r.Lock()
+	defer r.Unlock()
+
+	switch {
+	case s.Fd != nil && s.Fd.Name != "":
+		if s.Fd.Name == fdInfoName(s.Fd.Info) {
+			delete(r.names, s.Fd.Info)
+		} else {
+			r.names[s.Fd.Info] = s.Fd.Name
+		}
+	case s.Fd != nil:
+		ctx.Fds.Put(s.Fd.Pid, s.Fd.Fd, s.Fd.Info)
+	case s.Process != nil:
+		r.processes[s.Process.Pid] = s.Process
+	case s.ID != nil:
+		r.ids[capturedIDKey{s.ID.Pid, s.ID.Group, s.ID.ID}] = s.ID.Name
+	}
+}
+
+// recordState writes a state record. Since the following samples can't be
+// decoded without it, an error is kept and returned when writing them.
+// cw.mu must be held.
+func (cw *CaptureWriter) recordState(s CapturedState) {
+	if cw.err != nil {
+		return
+	}
+	data, err := json.Marshal(s)
+	if err == nil {
+		err = cw.writeData(CaptureState, data)
+	}
+	if err != nil {
+		cw.err = fmt.Errorf("error writing state: %v", err)
+	}
+}
+
+func (cw *CaptureWriter) recordFd(pid, fd uint32, info FdInfo) {
+	if cw == nil {
+		return
+	}
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	cw.recordState(CapturedState{Fd: &CapturedFd{Pid: pid, Fd: fd, Info: info}})
+}
+
+// recordFds records all the entries of a process, in the order of the file
+// descriptors
+func (cw *CaptureWriter) recordFds(pid uint32, fds *FdMap) {
+	if cw == nil {
+		return
+	}
+
+	fds.RLock()
+	entries := make(map[uint32]FdInfo, len(fds.items[pid]))
+	for fd, info := range fds.items[pid] {
+		entries[fd] = info
+	}
+	fds.RUnlock()
+
+	sorted := make([]int, 0, len(entries))
+	for fd := range entries {
+		sorted = append(sorted, int(fd))
+	}
+	sort.Ints(sorted)
+	for _, fd := range sorted {
+		cw.recordFd(pid, uint32(fd), entries[uint32(fd)])
+	}
+}
+
+// recordFdName records the name of the file descriptors with the entry info,
+// unless the replay would already find it
+func (cw *CaptureWriter) recordFdName(pid, fd uint32, info FdInfo, name string) {
+	if cw == nil {
+		return
+	}
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	replayed, ok := cw.names[info]
+	if !ok {
+		replayed = fdInfoName(info)
+	}
+	if name == replayed {
+		return
+	}
+	if name == fdInfoName(info) {
+		delete(cw.names, info)
+	} else {
+		cw.names[info] = name
+	}
+	cw.recordState(CapturedState{Fd: &CapturedFd{Pid: pid, Fd: fd, Info: info, Name: name}})
+}
+
+// recordProcess records the metadata of a process, when it was read again
+func (cw *CaptureWriter) recordProcess(info *ProcessInfo) {
+	if cw == nil || info == nil {
+		return
+	}
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if cw.processes[info.Pid] == info {
+		return
+	}
+	cw.processes[info.Pid] = info
+	cw.recordState(CapturedState{Process: info})
+}
+
+func (cw *CaptureWriter) forgetProcess(pid int64) {
+	if cw == nil {
+		return
+	}
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	delete(cw.processes, pid)
+}
+
+func (cw *CaptureWriter) recordID(id CapturedID) {
+	if cw == nil {
+		return
+	}
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	key := capturedIDKey{id.Pid, id.Group, id.ID}
+	if name, ok := cw.ids[key]; ok && name == id.Name {
+		return
+	}
+	cw.ids[key] = id.Name
+	cw.recordState(CapturedState{ID: &id})
+}
+
+// ScanPid adds the file descriptors currently open by a process to ctx.Fds
+// (see FdMap.ScanPid), and records them when recording a capture
+func (ctx Context) ScanPid(pid uint32) error {
+	if err := ctx.Fds.ScanPid(pid); err != nil {
+		return err
+	}
+	ctx.Capture.recordFds(pid, ctx.Fds)
+	return nil
+}
+
+// Process returns the metadata of the process pid (see ProcessCache.Get),
+// or nil if it's unknown
+func (ctx Context) Process(pid int64) *ProcessInfo {
+	if r := ctx.Replay; r != nil {
+		r.Lock()
+		defer r.Unlock()
+		return r.processes[pid]
+	}
+
+	info := ctx.Processes.Get(pid)
+	ctx.Capture.recordProcess(info)
+	return info
+}
+
+// deleteProcess drops the metadata of the process pid, when it exits
+func (ctx Context) deleteProcess(pid int64) {
+	if r := ctx.Replay; r != nil {
+		r.Lock()
+		delete(r.processes, pid)
+		r.Unlock()
+	}
+	ctx.Processes.Delete(pid)
+	ctx.Capture.forgetProcess(pid)
+}
+
+// installFd adds the file descriptor of an fd_install event to ctx.Fds. When
+// replaying, the entry was recorded right before the event.
+func (ctx Context) installFd(pid uint32, ev FileEvent) {
+	if ctx.Replay != nil {
+		return
+	}
+
+	name, err := procLookupPath(pid, uint32(ev.Fd))
+	if err != nil {
+		name = "unknown"
+	}
+
+	info := FdInfo{Path: name, Ino: ev.Ino, Major: ev.Major, Minor: ev.Minor}
+
+	// ignore entries not backed by files or sockets, like anonymous inodes
+	if strings.HasPrefix(info.Path, "/") || isSocketPath(info.Path) {
+		ctx.Fds.Put(pid, uint32(ev.Fd), info)
+		ctx.Capture.recordFd(pid, uint32(ev.Fd), info)
+	}
+}
+
+// fdPath returns the name of the file descriptor fd of the process pid: the
+// path of its file or the description of its socket, or "unknown"
+func (ctx Context) fdPath(pid, fd uint32) string {
+	info, ok := ctx.Fds.Get(pid, fd)
+	if !ok {
+		return "unknown"
+	}
+
+	if r := ctx.Replay; r != nil {
+		r.Lock()
+		defer r.Unlock()
+		if name, ok := r.names[*info]; ok {
+			return name
+		}
+		return fdInfoName(*info)
+	}
+
+	name := ctx.procFdPath(pid, fd, info)
+	if ctx.Capture != nil {
+		// resolving a socket may have updated the entry
+		if current, ok := ctx.Fds.Get(pid, fd); ok && *current != *info {
+			ctx.Capture.recordFd(pid, fd, *current)
+			info = current
+		}
+		ctx.Capture.recordFdName(pid, fd, *info, name)
+	}
+	return name
+}
+
+// fdInfoName is the name of the file descriptors with the entry info, unless
+// recorded otherwise
+func fdInfoName(info FdInfo) string {
+	if info.Socket != "" {
+		return info.Socket
+	}
+	return info.Path
+}
+
+// procFdPath checks in /proc that the entry info of the file descriptor is
+// still current, and resolves sockets
+func (ctx Context) procFdPath(pid, fd uint32, info *FdInfo) string {
+	if isSocketPath(info.Path) {
+		return ctx.Fds.ResolveSocket(pid, fd, info)
+	}
+
+	fileName := "unknown"
+	var stat syscall.Stat_t
+	path := filepath.Join("/proc", strconv.FormatUint(uint64(pid), 10), "root", info.Path)
+	err := syscall.Stat(path, &stat)
+	if err != nil {
+		if err == syscall.ENOENT {
+			// the file doesn't exist anymore, it's probably "info.Path"
+			// but we're not sure
+			fileName = fmt.Sprintf("[deleted] (%q)?", info.Path)
+		}
+	}
+	major, minor := devNumbers(uint64(stat.Dev))
+	if info.Ino == stat.Ino &&
+		info.Major == major &&
+		info.Minor == minor {
+		fileName = info.Path
+	}
+	return fileName
+}
+
+// lookupUser returns the name of the user uid in the mount namespace of the
+// process pid, see LookupUser
+func (ctx Context) lookupUser(pid int64, uid uint32) string {
+	return ctx.lookupID(CapturedID{Pid: pid, ID: uid}, LookupUser)
+}
+
+// lookupGroup returns the name of the group gid in the mount namespace of the
+// process pid, see LookupGroup
+func (ctx Context) lookupGroup(pid int64, gid uint32) string {
+	return ctx.lookupID(CapturedID{Pid: pid, Group: true, ID: gid}, LookupGroup)
+}
+
+func (ctx Context) lookupID(id CapturedID, lookup func(int64, uint32) string) string {
+	if r := ctx.Replay; r != nil {
+		r.Lock()
+		defer r.Unlock()
+		if name, ok := r.ids[capturedIDKey{id.Pid, id.Group, id.ID}]; ok {
+			return name
+		}
+		return "unknown"
+	}
+
+	id.Name = lookup(id.Pid, id.ID)
+	ctx.Capture.recordID(id)
+	return id.Name
+}
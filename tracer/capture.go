@@ -0,0 +1,367 @@
+package tracer
+
+import (
+	"bufio"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// #include <inttypes.h>
+// #include "../bpf/events-struct.h"
+import "C"
+
+// Capture file format:
+//
+//	magic   [8]byte  "TLCAPTUR"
+//	version uint32
+//	hdrLen  uint32
+//	header  [hdrLen]byte (JSON encoded CaptureHeader)
+//	records ...
+//
+// Each record starts with a type byte and the time it was received by the
+// tracer (uint64, nanoseconds since the epoch), followed by:
+//
+//	sample: size uint32, data [size]byte (raw perf sample)
+//	lost:   count uint64
+//	state:  size uint32, data [size]byte (JSON encoded CapturedState)
+//
+// State records hold what was read from /proc to decode the following
+// samples, see capture-state.go. Captures of version 1 have none.
+//
+// All integers are little endian.
+
+const (
+	captureMagic   = "TLCAPTUR"
+	CaptureVersion = 2
+
+	// maximum size of the JSON header and of a single perf sample we are
+	// willing to read, to avoid huge allocations on corrupted files
+	maxCaptureHeaderSize = 1 << 20
+	maxCaptureSampleSize = 1 << 20
+	maxCaptureStateSize  = 1 << 20
+)
+
+type CaptureRecordType uint8
+
+const (
+	CaptureSample CaptureRecordType = iota + 1
+	CaptureLost
+	CaptureState
+)
+
+type CaptureHandler struct {
+	Path   string `json:"path"`
+	SHA512 string `json:"sha512"`
+}
+
+type CaptureHeader struct {
+	Version         uint32           `json:"version"`
+	KernelRelease   string           `json:"kernelRelease"`
+	CommonEventSize uint32           `json:"commonEventSize"`
+	Created         time.Time        `json:"created"`
+	Handlers        []CaptureHandler `json:"handlers"`
+}
+
+type CaptureRecord struct {
+	Type      CaptureRecordType
+	Timestamp time.Time
+	Data      []byte
+	LostCount uint64
+	State     *CapturedState
+}
+
+// NewCaptureHeader returns a header describing the running kernel and the
+// given handlers, indexed by path. The handlers are sorted by path.
+func NewCaptureHeader(handlers map[string][]byte) (CaptureHeader, error) {
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err != nil {
+		return CaptureHeader{}, fmt.Errorf("error getting kernel release: %v", err)
+	}
+
+	hdr := CaptureHeader{
+		Version:         CaptureVersion,
+		KernelRelease:   cstring(uname.Release[:]),
+		CommonEventSize: C.sizeof_common_event_t,
+		Created:         time.Now().UTC(),
+	}
+	for path, elf := range handlers {
+		sum := sha512.Sum512(elf)
+		hdr.Handlers = append(hdr.Handlers, CaptureHandler{
+			Path:   path,
+			SHA512: hex.EncodeToString(sum[:]),
+		})
+	}
+	// in the same order in every capture of the same handlers
+	sort.Slice(hdr.Handlers, func(i, j int) bool { return hdr.Handlers[i].Path < hdr.Handlers[j].Path })
+
+	return hdr, nil
+}
+
+func cstring(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// CaptureWriter writes raw perf samples, lost event counts and the state
+// needed to decode the samples to a capture file. It is safe for concurrent
+// use.
+type CaptureWriter struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+
+	// first error writing a state record, see recordState
+	err error
+
+	// the state already recorded, see capture-state.go
+	processes map[int64]*ProcessInfo
+	names     map[FdInfo]string
+	ids       map[capturedIDKey]string
+}
+
+func NewCaptureWriter(w io.Writer, hdr CaptureHeader) (*CaptureWriter, error) {
+	hdrBytes, err := json.Marshal(hdr)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding capture header: %v", err)
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(captureMagic); err != nil {
+		return nil, fmt.Errorf("error writing capture magic: %v", err)
+	}
+	if err := binary.Write(bw, binary.LittleEndian, uint32(CaptureVersion)); err != nil {
+		return nil, fmt.Errorf("error writing capture version: %v", err)
+	}
+	if err := binary.Write(bw, binary.LittleEndian, uint32(len(hdrBytes))); err != nil {
+		return nil, fmt.Errorf("error writing capture header length: %v", err)
+	}
+	if _, err := bw.Write(hdrBytes); err != nil {
+		return nil, fmt.Errorf("error writing capture header: %v", err)
+	}
+
+	return &CaptureWriter{
+		w:         bw,
+		processes: make(map[int64]*ProcessInfo),
+		names:     make(map[FdInfo]string),
+		ids:       make(map[capturedIDKey]string),
+	}, nil
+}
+
+func (cw *CaptureWriter) writeRecordHeader(t CaptureRecordType) error {
+	if err := cw.w.WriteByte(byte(t)); err != nil {
+		return err
+	}
+	return binary.Write(cw.w, binary.LittleEndian, uint64(time.Now().UnixNano()))
+}
+
+// writeData writes a record made of data prefixed with its size
+func (cw *CaptureWriter) writeData(t CaptureRecordType, data []byte) error {
+	if err := cw.writeRecordHeader(t); err != nil {
+		return err
+	}
+	if err := binary.Write(cw.w, binary.LittleEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err := cw.w.Write(data)
+	return err
+}
+
+// WriteSample writes a raw perf sample. It also returns the error writing
+// the state recorded while decoding it or a previous sample, if any: the
+// samples can't be decoded without it anymore.
+func (cw *CaptureWriter) WriteSample(data []byte) error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if cw.err != nil {
+		return cw.err
+	}
+	if err := cw.writeData(CaptureSample, data); err != nil {
+		return fmt.Errorf("error writing sample: %v", err)
+	}
+	return nil
+}
+
+func (cw *CaptureWriter) WriteLost(count uint64) error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if err := cw.writeRecordHeader(CaptureLost); err != nil {
+		return fmt.Errorf("error writing lost count: %v", err)
+	}
+	if err := binary.Write(cw.w, binary.LittleEndian, count); err != nil {
+		return fmt.Errorf("error writing lost count: %v", err)
+	}
+	return nil
+}
+
+// Flush writes any buffered records to the underlying writer.
+func (cw *CaptureWriter) Flush() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+// CaptureReader reads the records of a capture file written by CaptureWriter.
+type CaptureReader struct {
+	r      *bufio.Reader
+	header CaptureHeader
+}
+
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(captureMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("error reading capture magic: %v", err)
+	}
+	if string(magic) != captureMagic {
+		return nil, fmt.Errorf("not a capture file")
+	}
+
+	var version, hdrLen uint32
+	if err := binary.Read(br, binary.LittleEndian, &version); err != nil {
+		return nil, fmt.Errorf("error reading capture version: %v", err)
+	}
+	if version < 1 || version > CaptureVersion {
+		return nil, fmt.Errorf("unsupported capture version %d (expected at most %d)", version, CaptureVersion)
+	}
+	if err := binary.Read(br, binary.LittleEndian, &hdrLen); err != nil {
+		return nil, fmt.Errorf("error reading capture header length: %v", err)
+	}
+	if hdrLen > maxCaptureHeaderSize {
+		return nil, fmt.Errorf("capture header too large (%d bytes)", hdrLen)
+	}
+
+	hdrBytes := make([]byte, hdrLen)
+	if _, err := io.ReadFull(br, hdrBytes); err != nil {
+		return nil, fmt.Errorf("error reading capture header: %v", err)
+	}
+	var hdr CaptureHeader
+	if err := json.Unmarshal(hdrBytes, &hdr); err != nil {
+		return nil, fmt.Errorf("error decoding capture header: %v", err)
+	}
+
+	return &CaptureReader{r: br, header: hdr}, nil
+}
+
+func (cr *CaptureReader) Header() CaptureHeader {
+	return cr.header
+}
+
+// Next returns the next record of the capture, or io.EOF at the end of the
+// capture.
+func (cr *CaptureReader) Next() (*CaptureRecord, error) {
+	t, err := cr.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	var ts uint64
+	if err := binary.Read(cr.r, binary.LittleEndian, &ts); err != nil {
+		return nil, fmt.Errorf("error reading record timestamp: %v", err)
+	}
+	rec := &CaptureRecord{
+		Type:      CaptureRecordType(t),
+		Timestamp: time.Unix(0, int64(ts)),
+	}
+
+	switch rec.Type {
+	case CaptureSample:
+		var size uint32
+		if err := binary.Read(cr.r, binary.LittleEndian, &size); err != nil {
+			return nil, fmt.Errorf("error reading sample size: %v", err)
+		}
+		if size > maxCaptureSampleSize {
+			return nil, fmt.Errorf("sample too large (%d bytes)", size)
+		}
+		rec.Data = make([]byte, size)
+		if _, err := io.ReadFull(cr.r, rec.Data); err != nil {
+			return nil, fmt.Errorf("error reading sample: %v", err)
+		}
+	case CaptureLost:
+		if err := binary.Read(cr.r, binary.LittleEndian, &rec.LostCount); err != nil {
+			return nil, fmt.Errorf("error reading lost count: %v", err)
+		}
+	case CaptureState:
+		var size uint32
+		if err := binary.Read(cr.r, binary.LittleEndian, &size); err != nil {
+			return nil, fmt.Errorf("error reading state size: %v", err)
+		}
+		if size > maxCaptureStateSize {
+			return nil, fmt.Errorf("state too large (%d bytes)", size)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(cr.r, data); err != nil {
+			return nil, fmt.Errorf("error reading state: %v", err)
+		}
+		rec.State = &CapturedState{}
+		if err := json.Unmarshal(data, rec.State); err != nil {
+			return nil, fmt.Errorf("error decoding state: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown record type %d", t)
+	}
+
+	return rec, nil
+}
+
+// Replay feeds the samples and lost event counts of a capture to the same
+// callbacks as New, and applies its state records to ctx, which the callback
+// must decode the samples with. ctx.Replay must be set. speed scales the
+// delay between records: 1 replays at the original speed, 2 twice as fast and
+// 0 as fast as possible.
+func Replay(cr *CaptureReader, ctx Context, speed float64, callback func(*[]byte), callbackLost func(uint64)) error {
+	if cr.header.CommonEventSize != C.sizeof_common_event_t {
+		return fmt.Errorf("capture has common_event_t of size %d, expected %d", cr.header.CommonEventSize, C.sizeof_common_event_t)
+	}
+	if ctx.Replay == nil {
+		return fmt.Errorf("no replay state in context")
+	}
+
+	var last time.Time
+	for {
+		rec, err := cr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if rec.Type == CaptureState {
+			ctx.applyState(rec.State)
+			continue
+		}
+
+		if speed > 0 && !last.IsZero() {
+			if d := rec.Timestamp.Sub(last); d > 0 {
+				time.Sleep(time.Duration(float64(d) / speed))
+			}
+		}
+		last = rec.Timestamp
+
+		switch rec.Type {
+		case CaptureSample:
+			callback(&rec.Data)
+		case CaptureLost:
+			callbackLost(rec.LostCount)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptureRoundTrip(t *testing.T) {
+	hdr, err := NewCaptureHeader(map[string][]byte{"handle_syscall_open.bpf": []byte("elf")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	cw, err := NewCaptureWriter(&buf, hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := [][]byte{{1, 2, 3}, {}, bytes.Repeat([]byte{0xff}, 5000)}
+	for _, s := range samples {
+		if err := cw.WriteSample(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cw.WriteLost(42); err != nil {
+		t.Fatal(err)
+	}
+	info := FdInfo{Path: "/etc/hosts", Ino: 12, Major: 8, Minor: 1}
+	cw.recordFd(10, 3, info)
+	cw.recordFdName(10, 3, info, "[deleted] (\"/etc/hosts\")?")
+	cw.recordProcess(&ProcessInfo{Pid: 10, Comm: "nginx", Cmdline: []string{"nginx", "-g"}})
+	cw.recordID(CapturedID{Pid: 10, ID: 33, Name: "www-data"})
+	if err := cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	cr, err := NewCaptureReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cr.Header()
+	if got.Version != CaptureVersion || got.KernelRelease != hdr.KernelRelease ||
+		got.CommonEventSize != hdr.CommonEventSize || !got.Created.Equal(hdr.Created) ||
+		!reflect.DeepEqual(got.Handlers, hdr.Handlers) {
+		t.Errorf("header: got %+v, want %+v", got, hdr)
+	}
+
+	want := []CaptureRecord{
+		{Type: CaptureSample, Data: samples[0]},
+		{Type: CaptureSample, Data: samples[1]},
+		{Type: CaptureSample, Data: samples[2]},
+		{Type: CaptureLost, LostCount: 42},
+		{Type: CaptureState, State: &CapturedState{Fd: &CapturedFd{Pid: 10, Fd: 3, Info: info}}},
+		{Type: CaptureState, State: &CapturedState{Fd: &CapturedFd{Pid: 10, Fd: 3, Info: info, Name: "[deleted] (\"/etc/hosts\")?"}}},
+		{Type: CaptureState, State: &CapturedState{Process: &ProcessInfo{Pid: 10, Comm: "nginx", Cmdline: []string{"nginx", "-g"}}}},
+		{Type: CaptureState, State: &CapturedState{ID: &CapturedID{Pid: 10, ID: 33, Name: "www-data"}}},
+	}
+	for i, w := range want {
+		rec, err := cr.Next()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if time.Since(rec.Timestamp) > time.Minute {
+			t.Errorf("record %d: unexpected timestamp %v", i, rec.Timestamp)
+		}
+		rec.Timestamp = time.Time{}
+		if !reflect.DeepEqual(*rec, w) {
+			t.Errorf("record %d: got %+v, want %+v", i, *rec, w)
+		}
+	}
+	if _, err := cr.Next(); err != io.EOF {
+		t.Errorf("expected EOF after the last record, got %v", err)
+	}
+}
+
+// failingWriter fails once more than n bytes are written
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		return 0, errors.New("disk full")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestCaptureWriteErrors(t *testing.T) {
+	hdr := CaptureHeader{Version: CaptureVersion}
+	cw, err := NewCaptureWriter(&failingWriter{n: 100}, hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// larger than the buffer of the writer
+	if err := cw.WriteSample(make([]byte, 10000)); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("expected a write error, got %v", err)
+	}
+
+	// errors writing state records are returned by the next writes
+	cw, err = NewCaptureWriter(&failingWriter{n: 100}, hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.recordProcess(&ProcessInfo{Pid: 1, Cmdline: []string{strings.Repeat("a", 10000)}})
+	if err := cw.WriteSample([]byte{1}); err == nil || !strings.Contains(err.Error(), "error writing state") {
+		t.Errorf("expected a state write error, got %v", err)
+	}
+	if err := cw.Flush(); err == nil {
+		t.Errorf("expected a state write error on flush")
+	}
+}
+
+func TestCaptureReaderErrors(t *testing.T) {
+	header := func(version uint32) []byte {
+		var b bytes.Buffer
+		b.WriteString(captureMagic)
+		binary.Write(&b, binary.LittleEndian, version)
+		binary.Write(&b, binary.LittleEndian, uint32(2))
+		b.WriteString("{}")
+		return b.Bytes()
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"empty", nil, "error reading capture magic"},
+		{"magic", []byte("NOTACAPTURE!"), "not a capture file"},
+		{"version", header(CaptureVersion + 1), "unsupported capture version"},
+		{"truncated header", header(CaptureVersion)[:17], "error reading capture header"},
+	}
+	for _, tt := range tests {
+		_, err := NewCaptureReader(bytes.NewReader(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+
+	// version 1 captures, without state records, are still read
+	if _, err := NewCaptureReader(bytes.NewReader(header(1))); err != nil {
+		t.Errorf("version 1: %v", err)
+	}
+
+	cr, err := NewCaptureReader(bytes.NewReader(append(header(CaptureVersion), 9, 0, 0, 0, 0, 0, 0, 0, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cr.Next(); err == nil || !strings.Contains(err.Error(), "unknown record type 9") {
+		t.Errorf("got error %v, want unknown record type", err)
+	}
+}
+
+// TestCaptureReplayState records what decoding reads from /proc for this
+// process, and checks that the replay finds the same without /proc.
+func TestCaptureReplayState(t *testing.T) {
+	f, err := ioutil.TempFile("", "capture-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	defer os.Remove(f.Name())
+
+	hdr, err := NewCaptureHeader(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	cw, err := NewCaptureWriter(&buf, hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid := uint32(os.Getpid())
+	fd := uint32(f.Fd())
+	ctx := Context{
+		Fds:       NewFdMap(),
+		Processes: NewProcessCache(time.Minute, nil),
+		Capture:   cw,
+	}
+	if err := ctx.ScanPid(pid); err != nil {
+		t.Fatal(err)
+	}
+
+	// the samples are only used as markers here
+	path := ctx.fdPath(pid, fd)
+	if path != f.Name() {
+		t.Errorf("got path %q, want %q", path, f.Name())
+	}
+	cw.WriteSample([]byte{1})
+
+	os.Remove(f.Name())
+	deleted := ctx.fdPath(pid, fd)
+	if !strings.HasPrefix(deleted, "[deleted]") {
+		t.Errorf("got path %q for a deleted file", deleted)
+	}
+	cw.WriteSample([]byte{2})
+
+	user := ctx.lookupUser(int64(pid), uint32(os.Getuid()))
+	process := ctx.Process(int64(pid))
+	if process == nil {
+		t.Fatal("no metadata for the current process")
+	}
+	cw.WriteSample([]byte{3})
+	if err := cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	cr, err := NewCaptureReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayCtx := Context{Fds: NewFdMap(), Replay: NewReplayState()}
+	var replayed int
+	err = Replay(cr, replayCtx, 0, func(data *[]byte) {
+		replayed++
+		switch (*data)[0] {
+		case 1:
+			if got := replayCtx.fdPath(pid, fd); got != path {
+				t.Errorf("replayed path %q, want %q", got, path)
+			}
+		case 2:
+			if got := replayCtx.fdPath(pid, fd); got != deleted {
+				t.Errorf("replayed path %q, want %q", got, deleted)
+			}
+		case 3:
+			if got := replayCtx.lookupUser(int64(pid), uint32(os.Getuid())); got != user {
+				t.Errorf("replayed user %q, want %q", got, user)
+			}
+			if got := replayCtx.Process(int64(pid)); got == nil || got.Comm != process.Comm || got.Exe != process.Exe {
+				t.Errorf("replayed process %+v, want %+v", got, process)
+			}
+		}
+	}, func(uint64) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != 3 {
+		t.Errorf("replayed %d samples, want 3", replayed)
+	}
+
+	if err := Replay(cr, Context{Fds: NewFdMap()}, 0, func(*[]byte) {}, func(uint64) {}); err == nil {
+		t.Errorf("expected an error replaying without replay state")
+	}
+}
+
+func TestCaptureHeaderHandlersSorted(t *testing.T) {
+	handlers := map[string][]byte{
+		"handle_syscall_write.bpf": []byte("w"),
+		"handle_syscall_open.bpf":  []byte("o"),
+		"handle_syscall_close.bpf": []byte("c"),
+		"handle_syscall_read.bpf":  []byte("r"),
+	}
+	// map iteration is random, one run could be sorted by chance
+	for i := 0; i < 10; i++ {
+		hdr, err := NewCaptureHeader(handlers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, h := range hdr.Handlers {
+			paths = append(paths, h.Path)
+		}
+		want := []string{"handle_syscall_close.bpf", "handle_syscall_open.bpf", "handle_syscall_read.bpf", "handle_syscall_write.bpf"}
+		if !reflect.DeepEqual(paths, want) {
+			t.Fatalf("got handlers %v, want %v", paths, want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package tracer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+)
+
+// ContainerInfo identifies the container and the Kubernetes pod of a process
+type ContainerInfo struct {
+	Runtime string `json:"runtime,omitempty"`
+	ID      string `json:"id,omitempty"`
+
+	// only set when resolved through the runtime socket
+	Name  string `json:"name,omitempty"`
+	Image string `json:"image,omitempty"`
+
+	PodUID   string `json:"pod_uid,omitempty"`
+	QoSClass string `json:"qos_class,omitempty"`
+}
+
+// prefixes of the systemd scopes (and cgroupfs directories) of the containers
+// of each runtime, e.g. docker-<id>.scope
+var containerScopePrefixes = []struct {
+	prefix, runtime string
+}{
+	{"docker-", "docker"},
+	{"cri-containerd-", "containerd"},
+	{"crio-", "cri-o"},
+	{"libpod-", "podman"},
+}
+
+func isContainerID(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseContainerCgroup returns the container and pod of a process from one of
+// its cgroup paths, or nil if it is not in a container. The paths look like:
+//
+//	/docker/<id>
+//	/system.slice/docker-<id>.scope
+//	/machine.slice/libpod-<id>.scope
+//	/kubepods/burstable/pod<uid>/<id>
+//	/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod<uid>.slice/cri-containerd-<id>.scope
+func parseContainerCgroup(path string) *ContainerInfo {
+	info := &ContainerInfo{}
+	kubepods := false
+
+	components := strings.Split(strings.Trim(path, "/"), "/")
+	for i, c := range components {
+		switch {
+		case c == "kubepods" || c == "kubepods.slice":
+			kubepods = true
+			continue
+		case !kubepods:
+		case c == "burstable" || c == "kubepods-burstable.slice":
+			info.QoSClass = "burstable"
+			continue
+		case c == "besteffort" || c == "kubepods-besteffort.slice":
+			info.QoSClass = "besteffort"
+			continue
+		case strings.HasPrefix(c, "pod"):
+			// cgroupfs driver: pod<uid>
+			info.PodUID = strings.TrimPrefix(c, "pod")
+			continue
+		case strings.HasSuffix(c, ".slice") && strings.Contains(c, "-pod"):
+			// systemd driver: kubepods-<qos>-pod<uid>.slice, with
+			// underscores instead of the dashes of the uid
+			uid := strings.TrimSuffix(c[strings.LastIndex(c, "-pod")+len("-pod"):], ".slice")
+			info.PodUID = strings.Replace(uid, "_", "-", -1)
+			continue
+		}
+
+		id := strings.TrimSuffix(c, ".scope")
+		switch {
+		case isContainerID(id):
+			// cgroupfs driver: the runtime is only known for docker
+			if i > 0 && components[i-1] == "docker" {
+				info.Runtime = "docker"
+			}
+			info.ID = id
+		default:
+			for _, p := range containerScopePrefixes {
+				if strings.HasPrefix(id, p.prefix) && isContainerID(strings.TrimPrefix(id, p.prefix)) {
+					info.Runtime = p.runtime
+					info.ID = strings.TrimPrefix(id, p.prefix)
+					break
+				}
+			}
+		}
+	}
+
+	if info.PodUID != "" && info.QoSClass == "" {
+		info.QoSClass = "guaranteed"
+	}
+	if info.ID == "" && info.PodUID == "" {
+		return nil
+	}
+
+	return info
+}
+
+// parseContainerCgroups returns the container of a process from the lines of
+// /proc/<pid>/cgroup, or nil if none of its cgroups belongs to a container
+func parseContainerCgroups(lines []string) *ContainerInfo {
+	var pod *ContainerInfo
+	for _, line := range lines {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		info := parseContainerCgroup(parts[2])
+		if info == nil {
+			continue
+		}
+		if info.ID != "" {
+			return info
+		}
+		if pod == nil {
+			pod = info
+		}
+	}
+
+	return pod
+}
+
+/* container names */
+
+const (
+	// number of containers whose names are cached
+	containerCacheSize = 1024
+	// failures are cached briefly, so that the runtime isn't asked for
+	// every process of a container it doesn't know (yet)
+	containerFailureTTL = 10 * time.Second
+)
+
+// ContainerResolver resolves the names and images of containers through the
+// Docker Engine API served on a unix socket, by docker or by podman's
+// compatible service. Results are cached by container id in an LRU cache, and
+// failures for containerFailureTTL.
+type ContainerResolver struct {
+	client     *http.Client
+	names      *lru.Cache // id -> containerNames
+	failureTTL time.Duration
+}
+
+type containerNames struct {
+	name, image string
+
+	// when the lookup failed, zero if it succeeded
+	failed time.Time
+}
+
+func NewContainerResolver(socketPath string) *ContainerResolver {
+	return newContainerResolver(socketPath, containerCacheSize)
+}
+
+func newContainerResolver(socketPath string, cacheSize int) *ContainerResolver {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	// lru.New only fails for a non-positive size
+	names, _ := lru.New(cacheSize)
+
+	return &ContainerResolver{
+		client:     &http.Client{Transport: transport, Timeout: time.Second},
+		names:      names,
+		failureTTL: containerFailureTTL,
+	}
+}
+
+// resolve sets the name and image of the container info, if the runtime
+// knows the container. A nil *ContainerResolver resolves nothing.
+func (r *ContainerResolver) resolve(info *ContainerInfo) {
+	if r == nil || info == nil || info.ID == "" {
+		return
+	}
+
+	v, ok := r.names.Get(info.ID)
+	names, _ := v.(containerNames)
+	if !ok || !names.failed.IsZero() && time.Since(names.failed) >= r.failureTTL {
+		var err error
+		names, err = r.inspect(info.ID)
+		if err != nil {
+			names = containerNames{failed: time.Now()}
+		}
+		r.names.Add(info.ID, names)
+	}
+
+	info.Name = names.name
+	info.Image = names.image
+}
+
+func (r *ContainerResolver) inspect(id string) (containerNames, error) {
+	// the host is ignored by the transport
+	resp, err := r.client.Get(fmt.Sprintf("http://localhost/containers/%s/json", id))
+	if err != nil {
+		return containerNames{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return containerNames{}, fmt.Errorf("unexpected status %q inspecting container %s", resp.Status, id)
+	}
+
+	var container struct {
+		Name   string
+		Config struct {
+			Image string
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&container); err != nil {
+		return containerNames{}, fmt.Errorf("error decoding container %s: %v", id, err)
+	}
+
+	return containerNames{
+		name:  strings.TrimPrefix(container.Name, "/"),
+		image: container.Config.Image,
+	}, nil
+}
@@ -0,0 +1,279 @@
+package tracer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testContainerID = "3f4e2a9b8c7d6e5f40312a1b2c3d4e5f60718293a4b5c6d7e8f9012345678901"
+	testPodUID      = "2c4d6e8f-1a3b-4c5d-8e9f-0a1b2c3d4e5f"
+)
+
+func TestParseContainerCgroup(t *testing.T) {
+	systemdPodUID := strings.Replace(testPodUID, "-", "_", -1)
+
+	tests := []struct {
+		name string
+		path string
+		want *ContainerInfo
+	}{
+		{"host", "/user.slice/user-1000.slice/session-2.scope", nil},
+		{"root", "/", nil},
+		{"init scope", "/init.scope", nil},
+
+		// docker
+		{"docker cgroupfs", "/docker/" + testContainerID,
+			&ContainerInfo{Runtime: "docker", ID: testContainerID}},
+		{"docker systemd", "/system.slice/docker-" + testContainerID + ".scope",
+			&ContainerInfo{Runtime: "docker", ID: testContainerID}},
+		{"docker short id", "/docker/3f4e2a9b8c7d", nil},
+
+		// podman
+		{"podman", "/machine.slice/libpod-" + testContainerID + ".scope",
+			&ContainerInfo{Runtime: "podman", ID: testContainerID}},
+		{"podman conmon", "/machine.slice/libpod-conmon-" + testContainerID + ".scope", nil},
+
+		// kubernetes with the cgroupfs driver, where only the id is known
+		{"kubepods burstable cgroupfs", "/kubepods/burstable/pod" + testPodUID + "/" + testContainerID,
+			&ContainerInfo{ID: testContainerID, PodUID: testPodUID, QoSClass: "burstable"}},
+		{"kubepods besteffort cgroupfs", "/kubepods/besteffort/pod" + testPodUID + "/" + testContainerID,
+			&ContainerInfo{ID: testContainerID, PodUID: testPodUID, QoSClass: "besteffort"}},
+		{"kubepods guaranteed cgroupfs", "/kubepods/pod" + testPodUID + "/" + testContainerID,
+			&ContainerInfo{ID: testContainerID, PodUID: testPodUID, QoSClass: "guaranteed"}},
+		{"kubepods cri-o cgroupfs", "/kubepods/burstable/pod" + testPodUID + "/crio-" + testContainerID,
+			&ContainerInfo{Runtime: "cri-o", ID: testContainerID, PodUID: testPodUID, QoSClass: "burstable"}},
+		// the pause container, or a process in the pod cgroup itself
+		{"kubepods pod only", "/kubepods/burstable/pod" + testPodUID,
+			&ContainerInfo{PodUID: testPodUID, QoSClass: "burstable"}},
+
+		// kubernetes with the systemd driver
+		{"kubepods containerd systemd",
+			"/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" + systemdPodUID + ".slice/cri-containerd-" + testContainerID + ".scope",
+			&ContainerInfo{Runtime: "containerd", ID: testContainerID, PodUID: testPodUID, QoSClass: "besteffort"}},
+		{"kubepods cri-o systemd",
+			"/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + systemdPodUID + ".slice/crio-" + testContainerID + ".scope",
+			&ContainerInfo{Runtime: "cri-o", ID: testContainerID, PodUID: testPodUID, QoSClass: "burstable"}},
+		{"kubepods guaranteed systemd",
+			"/kubepods.slice/kubepods-pod" + systemdPodUID + ".slice/cri-containerd-" + testContainerID + ".scope",
+			&ContainerInfo{Runtime: "containerd", ID: testContainerID, PodUID: testPodUID, QoSClass: "guaranteed"}},
+		{"kubepods cri-o conmon systemd",
+			"/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + systemdPodUID + ".slice/crio-conmon-" + testContainerID + ".scope",
+			&ContainerInfo{PodUID: testPodUID, QoSClass: "burstable"}},
+
+		// pod<uid> outside of kubepods is not a pod
+		{"pod outside of kubepods", "/system.slice/pod" + testPodUID, nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseContainerCgroup(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseContainerCgroup(%q) = %+v, want %+v", tt.name, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseContainerCgroups(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  *ContainerInfo
+	}{
+		{"host", []string{
+			"12:pids:/user.slice/user-1000.slice/session-2.scope",
+			"1:name=systemd:/user.slice/user-1000.slice/session-2.scope",
+			"0::/user.slice/user-1000.slice/session-2.scope",
+		}, nil},
+		{"cgroup v1", []string{
+			"12:pids:/docker/" + testContainerID,
+			"11:cpu,cpuacct:/docker/" + testContainerID,
+		}, &ContainerInfo{Runtime: "docker", ID: testContainerID}},
+		{"cgroup v2", []string{
+			"0::/system.slice/docker-" + testContainerID + ".scope",
+		}, &ContainerInfo{Runtime: "docker", ID: testContainerID}},
+		// a cgroup with the container id wins over one with the pod only
+		{"pod then container", []string{
+			"5:pids:/kubepods/burstable/pod" + testPodUID,
+			"4:memory:/kubepods/burstable/pod" + testPodUID + "/" + testContainerID,
+		}, &ContainerInfo{ID: testContainerID, PodUID: testPodUID, QoSClass: "burstable"}},
+		{"pod only", []string{
+			"5:pids:/kubepods/burstable/pod" + testPodUID,
+		}, &ContainerInfo{PodUID: testPodUID, QoSClass: "burstable"}},
+		{"malformed", []string{"", "garbage", "1:cpu"}, nil},
+		// everything after the second colon is the path
+		{"colons", []string{"1:name=systemd:/docker/" + testContainerID + ":x"}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseContainerCgroups(tt.lines); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// engineAPIServer serves the inspection of the containers, by id, like the
+// Docker Engine API on a unix socket, and counts the requests
+type engineAPIServer struct {
+	*httptest.Server
+	socketPath string
+
+	mu       sync.Mutex
+	requests int
+}
+
+func newEngineAPIServer(t *testing.T, containers map[string]string) *engineAPIServer {
+	dir, err := ioutil.TempDir("", "container-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &engineAPIServer{socketPath: filepath.Join(dir, "docker.sock")}
+
+	l, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		s.mu.Unlock()
+
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/containers/"), "/json")
+		body, ok := containers[id]
+		if !ok || r.Method != "GET" {
+			http.Error(w, `{"message":"No such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	s.Server.Listener = l
+	s.Start()
+
+	return s
+}
+
+func (s *engineAPIServer) Close() {
+	s.Server.Close()
+	os.RemoveAll(filepath.Dir(s.socketPath))
+}
+
+func (s *engineAPIServer) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func TestContainerResolver(t *testing.T) {
+	malformedID := strings.Repeat("b", 64)
+	s := newEngineAPIServer(t, map[string]string{
+		testContainerID: `{"Id":"` + testContainerID + `","Name":"/web","Config":{"Image":"nginx:1.25","Env":["A=1"]}}`,
+		malformedID:     `{"Name":`,
+	})
+	defer s.Close()
+
+	r := NewContainerResolver(s.socketPath)
+
+	tests := []struct {
+		name        string
+		info        *ContainerInfo
+		want        *ContainerInfo
+		newRequests int
+	}{
+		{"known", &ContainerInfo{Runtime: "docker", ID: testContainerID},
+			&ContainerInfo{Runtime: "docker", ID: testContainerID, Name: "web", Image: "nginx:1.25"}, 1},
+		// results are cached
+		{"known again", &ContainerInfo{Runtime: "docker", ID: testContainerID},
+			&ContainerInfo{Runtime: "docker", ID: testContainerID, Name: "web", Image: "nginx:1.25"}, 0},
+		{"unknown", &ContainerInfo{ID: strings.Repeat("a", 64)},
+			&ContainerInfo{ID: strings.Repeat("a", 64)}, 1},
+		// and so are failures, for a while
+		{"unknown again", &ContainerInfo{ID: strings.Repeat("a", 64)},
+			&ContainerInfo{ID: strings.Repeat("a", 64)}, 0},
+		{"malformed response", &ContainerInfo{ID: malformedID},
+			&ContainerInfo{ID: malformedID}, 1},
+		// pods without a container aren't looked up
+		{"pod only", &ContainerInfo{PodUID: testPodUID, QoSClass: "burstable"},
+			&ContainerInfo{PodUID: testPodUID, QoSClass: "burstable"}, 0},
+	}
+
+	for _, tt := range tests {
+		before := s.Requests()
+		r.resolve(tt.info)
+		if !reflect.DeepEqual(tt.info, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, tt.info, tt.want)
+		}
+		if n := s.Requests() - before; n != tt.newRequests {
+			t.Errorf("%s: %d requests, want %d", tt.name, n, tt.newRequests)
+		}
+	}
+
+	// expired failures are looked up again, successes aren't
+	r.failureTTL = 0
+	for _, tt := range []struct {
+		id       string
+		requests int
+	}{
+		{strings.Repeat("a", 64), 2},
+		{testContainerID, 0},
+	} {
+		before := s.Requests()
+		r.resolve(&ContainerInfo{ID: tt.id})
+		r.resolve(&ContainerInfo{ID: tt.id})
+		if n := s.Requests() - before; n != tt.requests {
+			t.Errorf("%s: %d requests, want %d", tt.id, n, tt.requests)
+		}
+	}
+
+	if _, err := r.inspect(strings.Repeat("c", 64)); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("got error %v, want a 404", err)
+	}
+
+	// nil resolvers and infos resolve nothing
+	var nilResolver *ContainerResolver
+	info := &ContainerInfo{ID: testContainerID}
+	nilResolver.resolve(info)
+	if info.Name != "" {
+		t.Errorf("a nil resolver resolved %+v", info)
+	}
+	r.resolve(nil)
+}
+
+// TestContainerResolverEviction checks that the least recently used
+// containers are forgotten
+func TestContainerResolverEviction(t *testing.T) {
+	other := strings.Repeat("d", 64)
+	s := newEngineAPIServer(t, map[string]string{
+		testContainerID: `{"Name":"/web","Config":{"Image":"nginx"}}`,
+		other:           `{"Name":"/db","Config":{"Image":"postgres"}}`,
+	})
+	defer s.Close()
+
+	r := newContainerResolver(s.socketPath, 1)
+	for _, id := range []string{testContainerID, other, testContainerID} {
+		r.resolve(&ContainerInfo{ID: id})
+	}
+	if n := s.Requests(); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+	if n := r.names.Len(); n != 1 {
+		t.Errorf("%d cached containers, want 1", n)
+	}
+}
+
+func TestContainerResolverNoSocket(t *testing.T) {
+	r := NewContainerResolver(filepath.Join(os.TempDir(), "nonexistent-container-test.sock"))
+	info := &ContainerInfo{ID: testContainerID}
+	r.resolve(info)
+	if !reflect.DeepEqual(info, &ContainerInfo{ID: testContainerID}) {
+		t.Errorf("got %+v without a runtime", info)
+	}
+}
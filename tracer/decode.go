@@ -0,0 +1,243 @@
+package tracer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Symbolic decoding of the arguments and return values of syscalls, used by
+// the generated events depending on the kind of their arguments.
+
+type flagName struct {
+	value uint64
+	name  string
+}
+
+// openFlags are the flags of open(2) besides the access mode. Flags made of
+// several bits (e.g. O_SYNC includes O_DSYNC) come first.
+var openFlags = []flagName{
+	{unix.O_TMPFILE, "O_TMPFILE"},
+	{unix.O_SYNC, "O_SYNC"},
+	{unix.O_CREAT, "O_CREAT"},
+	{unix.O_EXCL, "O_EXCL"},
+	{unix.O_NOCTTY, "O_NOCTTY"},
+	{unix.O_TRUNC, "O_TRUNC"},
+	{unix.O_APPEND, "O_APPEND"},
+	{unix.O_NONBLOCK, "O_NONBLOCK"},
+	{unix.O_DSYNC, "O_DSYNC"},
+	{unix.O_ASYNC, "O_ASYNC"},
+	{unix.O_DIRECT, "O_DIRECT"},
+	{unix.O_LARGEFILE, "O_LARGEFILE"},
+	{unix.O_DIRECTORY, "O_DIRECTORY"},
+	{unix.O_NOFOLLOW, "O_NOFOLLOW"},
+	{unix.O_NOATIME, "O_NOATIME"},
+	{unix.O_CLOEXEC, "O_CLOEXEC"},
+	{unix.O_PATH, "O_PATH"},
+}
+
+// atFlags are the AT_* flags of the *at(2) syscalls
+var atFlags = []flagName{
+	{unix.AT_SYMLINK_NOFOLLOW, "AT_SYMLINK_NOFOLLOW"},
+	{unix.AT_REMOVEDIR, "AT_REMOVEDIR"},
+	{unix.AT_SYMLINK_FOLLOW, "AT_SYMLINK_FOLLOW"},
+	{unix.AT_NO_AUTOMOUNT, "AT_NO_AUTOMOUNT"},
+	{unix.AT_EMPTY_PATH, "AT_EMPTY_PATH"},
+}
+
+// decodeFlags appends to names the names of the flags set in value, and the
+// remaining unknown bits in hexadecimal
+func decodeFlags(value uint64, flags []flagName, names []string) string {
+	for _, f := range flags {
+		if f.value != 0 && value&f.value == f.value {
+			names = append(names, f.name)
+			value &^= f.value
+		}
+	}
+	if value != 0 {
+		names = append(names, fmt.Sprintf("%#x", value))
+	}
+	if len(names) == 0 {
+		return "0"
+	}
+	return strings.Join(names, "|")
+}
+
+// DecodeOpenFlags returns the flags of open(2) as e.g. "O_RDONLY|O_CLOEXEC"
+func DecodeOpenFlags(flags uint64) string {
+	var accessMode string
+	switch flags & unix.O_ACCMODE {
+	case unix.O_RDONLY:
+		accessMode = "O_RDONLY"
+	case unix.O_WRONLY:
+		accessMode = "O_WRONLY"
+	case unix.O_RDWR:
+		accessMode = "O_RDWR"
+	default:
+		accessMode = fmt.Sprintf("%#x", flags&unix.O_ACCMODE)
+	}
+	return decodeFlags(flags&^unix.O_ACCMODE, openFlags, []string{accessMode})
+}
+
+// DecodeAtFlags returns the AT_* flags of the *at(2) syscalls as e.g.
+// "AT_SYMLINK_NOFOLLOW"
+func DecodeAtFlags(flags uint64) string {
+	return decodeFlags(flags, atFlags, nil)
+}
+
+// DecodeMode returns a file mode in octal and as ls(1) shows it, e.g.
+// "0644/rw-r--r--"
+func DecodeMode(mode uint64) string {
+	perm := []byte("rwxrwxrwx")
+	for i := range perm {
+		if mode&(1<<uint(8-i)) == 0 {
+			perm[i] = '-'
+		}
+	}
+	special := []struct {
+		bit      uint64
+		index    int
+		set, noX byte
+	}{
+		{unix.S_ISUID, 2, 's', 'S'},
+		{unix.S_ISGID, 5, 's', 'S'},
+		{unix.S_ISVTX, 8, 't', 'T'},
+	}
+	for _, s := range special {
+		if mode&s.bit == 0 {
+			continue
+		}
+		if perm[s.index] == 'x' {
+			perm[s.index] = s.set
+		} else {
+			perm[s.index] = s.noX
+		}
+	}
+
+	return fmt.Sprintf("0%03o/%s", mode&07777, perm)
+}
+
+// DecodeRet returns the return value of a syscall, with the name of the errno
+// for errors, e.g. "-ENOENT"
+func DecodeRet(ret int64) string {
+	if ret < 0 && ret >= -4095 {
+		if name := unix.ErrnoName(syscall.Errno(-ret)); name != "" {
+			return "-" + name
+		}
+	}
+	return strconv.FormatInt(ret, 10)
+}
+
+// Errno returns the name of the errno of a failed syscall (e.g. "ENOENT"),
+// and "" if ret is not an error.
+func Errno(ret int64) string {
+	if ret < 0 && ret >= -4095 {
+		return unix.ErrnoName(syscall.Errno(-ret))
+	}
+	return ""
+}
+
+/* user and group names */
+
+// idFileKey identifies a version of a passwd or group file, which may differ
+// between mount namespaces
+type idFileKey struct {
+	dev, ino uint64
+	mtime    int64
+	size     int64
+}
+
+// idFileCache caches the id -> name mappings of the passwd and group files
+// of the processes
+type idFileCache struct {
+	sync.Mutex
+	files map[idFileKey]map[uint32]string
+}
+
+var idFiles = &idFileCache{
+	files: make(map[idFileKey]map[uint32]string),
+}
+
+func (c *idFileCache) lookup(path string, id uint32) (string, bool) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return "", false
+	}
+	key := idFileKey{
+		dev:   uint64(stat.Dev),
+		ino:   uint64(stat.Ino),
+		mtime: int64(stat.Mtim.Sec)*1e9 + int64(stat.Mtim.Nsec),
+		size:  int64(stat.Size),
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	names, ok := c.files[key]
+	if !ok {
+		var err error
+		names, err = readIDFile(path)
+		if err != nil {
+			return "", false
+		}
+		c.files[key] = names
+	}
+
+	name, ok := names[id]
+	return name, ok
+}
+
+// readIDFile reads a file in the format of /etc/passwd or /etc/group, where
+// the first field is the name and the third one the id
+func readIDFile(path string) (map[uint32]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names := make(map[uint32]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 {
+			continue
+		}
+		id, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			continue
+		}
+		if _, ok := names[uint32(id)]; !ok {
+			names[uint32(id)] = fields[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// LookupUser returns the name of the user uid in the mount namespace of the
+// process pid, or "unknown" if the process exited or the user has no name.
+func LookupUser(pid int64, uid uint32) string {
+	if name, ok := idFiles.lookup(fmt.Sprintf("/proc/%d/root/etc/passwd", pid), uid); ok {
+		return name
+	}
+	return "unknown"
+}
+
+// LookupGroup returns the name of the group gid in the mount namespace of the
+// process pid, or "unknown" if the process exited or the group has no name.
+func LookupGroup(pid int64, gid uint32) string {
+	if name, ok := idFiles.lookup(fmt.Sprintf("/proc/%d/root/etc/group", pid), gid); ok {
+		return name
+	}
+	return "unknown"
+}
@@ -0,0 +1,207 @@
+package tracer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestDecodeOpenFlags(t *testing.T) {
+	tests := []struct {
+		flags uint64
+		want  string
+	}{
+		{0, "O_RDONLY"},
+		{unix.O_WRONLY, "O_WRONLY"},
+		{unix.O_RDWR, "O_RDWR"},
+		{unix.O_ACCMODE, "0x3"},
+		{0x80000, "O_RDONLY|O_CLOEXEC"},
+		{unix.O_WRONLY | unix.O_CREAT | unix.O_TRUNC, "O_WRONLY|O_CREAT|O_TRUNC"},
+		// O_SYNC includes O_DSYNC, which isn't shown again
+		{unix.O_WRONLY | unix.O_SYNC, "O_WRONLY|O_SYNC"},
+		{unix.O_WRONLY | unix.O_DSYNC, "O_WRONLY|O_DSYNC"},
+		// O_TMPFILE includes O_DIRECTORY
+		{unix.O_RDWR | unix.O_TMPFILE, "O_RDWR|O_TMPFILE"},
+		{unix.O_RDONLY | unix.O_DIRECTORY, "O_RDONLY|O_DIRECTORY"},
+		// unknown bits are kept in hexadecimal
+		{unix.O_RDONLY | unix.O_CLOEXEC | 0x40000000, "O_RDONLY|O_CLOEXEC|0x40000000"},
+	}
+	for _, tt := range tests {
+		if got := DecodeOpenFlags(tt.flags); got != tt.want {
+			t.Errorf("DecodeOpenFlags(%#x) = %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeAtFlags(t *testing.T) {
+	tests := []struct {
+		flags uint64
+		want  string
+	}{
+		{0, "0"},
+		{unix.AT_SYMLINK_NOFOLLOW, "AT_SYMLINK_NOFOLLOW"},
+		{unix.AT_SYMLINK_NOFOLLOW | unix.AT_EMPTY_PATH, "AT_SYMLINK_NOFOLLOW|AT_EMPTY_PATH"},
+		{unix.AT_REMOVEDIR | 0x1, "AT_REMOVEDIR|0x1"},
+	}
+	for _, tt := range tests {
+		if got := DecodeAtFlags(tt.flags); got != tt.want {
+			t.Errorf("DecodeAtFlags(%#x) = %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeMode(t *testing.T) {
+	tests := []struct {
+		mode uint64
+		want string
+	}{
+		{0, "0000/---------"},
+		{0644, "0644/rw-r--r--"},
+		{0755, "0755/rwxr-xr-x"},
+		{0777, "0777/rwxrwxrwx"},
+		{04755, "04755/rwsr-xr-x"},
+		{04644, "04644/rwSr--r--"},
+		{02755, "02755/rwxr-sr-x"},
+		{02745, "02745/rwxr-Sr-x"},
+		{01777, "01777/rwxrwxrwt"},
+		{01776, "01776/rwxrwxrwT"},
+		// the file type bits of st_mode are ignored
+		{unix.S_IFREG | 0600, "0600/rw-------"},
+	}
+	for _, tt := range tests {
+		if got := DecodeMode(tt.mode); got != tt.want {
+			t.Errorf("DecodeMode(%#o) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeRet(t *testing.T) {
+	tests := []struct {
+		ret   int64
+		want  string
+		errno string
+	}{
+		{0, "0", ""},
+		{3, "3", ""},
+		{-2, "-ENOENT", "ENOENT"},
+		{-13, "-EACCES", "EACCES"},
+		{-4095, "-4095", ""},
+		// not an errno, e.g. a negative offset
+		{-4096, "-4096", ""},
+		{-100000, "-100000", ""},
+	}
+	for _, tt := range tests {
+		if got := DecodeRet(tt.ret); got != tt.want {
+			t.Errorf("DecodeRet(%d) = %q, want %q", tt.ret, got, tt.want)
+		}
+		if got := Errno(tt.ret); got != tt.errno {
+			t.Errorf("Errno(%d) = %q, want %q", tt.ret, got, tt.errno)
+		}
+	}
+}
+
+func TestDecodedEvents(t *testing.T) {
+	e := OpenEvent{Flags: unix.O_WRONLY | unix.O_CREAT, Mode: 0640}
+	copy(e.Filename[:], "/tmp/x")
+
+	want := `Filename "/tmp/x" Flags O_WRONLY|O_CREAT Mode 0640/rw-r----- `
+	if got := e.String(3); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	data, err := e.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"Filename":"/tmp/x","Flags":"O_WRONLY|O_CREAT","Mode":"0640/rw-r-----"}`
+	if string(data) != wantJSON {
+		t.Errorf("got %s, want %s", data, wantJSON)
+	}
+}
+
+func TestReadIDFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decode-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	passwd := filepath.Join(dir, "passwd")
+	data := strings.Join([]string{
+		"root:x:0:0:root:/root:/bin/bash",
+		"",
+		"# not an entry",
+		"www-data:x:33:33:www-data:/var/www:/usr/sbin/nologin",
+		"invalid:x:notanumber:0::/:",
+		// the first entry of an id wins, like getpwuid(3)
+		"toor:x:0:0::/root:/bin/sh",
+		"nobody:x:65534:65534:nobody:/nonexistent:/usr/sbin/nologin",
+	}, "\n")
+	if err := ioutil.WriteFile(passwd, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := readIDFile(passwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint32]string{0: "root", 33: "www-data", 65534: "nobody"}
+	if len(names) != len(want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+	for id, name := range want {
+		if names[id] != name {
+			t.Errorf("id %d: got %q, want %q", id, names[id], name)
+		}
+	}
+
+	// the cache reads the file again when it changes
+	cache := &idFileCache{files: make(map[idFileKey]map[uint32]string)}
+	if name, ok := cache.lookup(passwd, 33); !ok || name != "www-data" {
+		t.Errorf("got %q, %t, want www-data", name, ok)
+	}
+	if _, ok := cache.lookup(passwd, 1000); ok {
+		t.Errorf("found a name for an unknown id")
+	}
+	data += "\nalice:x:1000:1000::/home/alice:/bin/sh\n"
+	if err := ioutil.WriteFile(passwd, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// in case the size and mtime didn't change on filesystems with coarse
+	// timestamps
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(passwd, future, future)
+	if name, ok := cache.lookup(passwd, 1000); !ok || name != "alice" {
+		t.Errorf("got %q, %t after changing the file, want alice", name, ok)
+	}
+	if _, ok := cache.lookup(filepath.Join(dir, "nonexistent"), 0); ok {
+		t.Errorf("found a name in a nonexistent file")
+	}
+}
+
+func TestLookupUser(t *testing.T) {
+	// processes that don't exist have no users
+	if got := LookupUser(-1, 0); got != "unknown" {
+		t.Errorf("got %q for a nonexistent process", got)
+	}
+	if got := LookupGroup(-1, 0); got != "unknown" {
+		t.Errorf("got %q for a nonexistent process", got)
+	}
+
+	names, err := readIDFile("/etc/passwd")
+	if err != nil {
+		t.Skipf("no /etc/passwd: %v", err)
+	}
+	uid := uint32(os.Getuid())
+	want, ok := names[uid]
+	if !ok {
+		want = "unknown"
+	}
+	if got := LookupUser(int64(os.Getpid()), uid); got != want {
+		t.Errorf("LookupUser(self, %d) = %q, want %q", uid, got, want)
+	}
+}
@@ -6,7 +6,9 @@ package tracer
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"path/filepath"
@@ -24,6 +26,13 @@ type FdInfo struct {
 	Ino   uint64
 	Major uint64
 	Minor uint64
+
+	// protocol and addresses of a socket, once resolved (see ResolveSocket)
+	Socket string
+	// if not 0, Socket may still change (e.g. the socket isn't connected
+	// yet, or wasn't found) and is looked up again after this time, in
+	// nanoseconds since the epoch
+	SocketRetry int64
 }
 
 // Pid -> Fd -> FdInfo
@@ -78,6 +87,87 @@ func (f *FdMap) DeletePid(pid uint32) {
 	delete(f.items, pid)
 }
 
+// CopyPid copies the entries of a process to another one, e.g. to a child
+// inheriting the file descriptors of its parent
+func (f *FdMap) CopyPid(from, to uint32) {
+	f.Lock()
+	defer f.Unlock()
+
+	inner, ok := f.items[from]
+	if !ok {
+		return
+	}
+
+	copied := make(map[uint32]FdInfo, len(inner))
+	for fd, info := range inner {
+		copied[fd] = info
+	}
+	f.items[to] = copied
+}
+
+// Copy copies the entry of a file descriptor to another one of the same
+// process, e.g. on dup2(2)
+func (f *FdMap) Copy(pid, from, to uint32) {
+	f.Lock()
+	defer f.Unlock()
+
+	inner, ok := f.items[pid]
+	if !ok {
+		return
+	}
+
+	info, ok := inner[from]
+	if !ok {
+		// whatever was open at to was closed
+		delete(inner, to)
+		return
+	}
+	inner[to] = info
+}
+
+// ScanPid adds the file descriptors currently open by a process, read from
+// /proc, e.g. the ones it inherited or opened before being traced
+func (f *FdMap) ScanPid(pid uint32) error {
+	fdDir := fmt.Sprintf("/proc/%d/fd", pid)
+	d, err := os.Open(fdDir)
+	if err != nil {
+		return err
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		fd, err := strconv.ParseUint(name, 10, 32)
+		if err != nil {
+			continue
+		}
+		path, err := procLookupPath(pid, uint32(fd))
+		// ignore entries not backed by files or sockets, like anonymous inodes
+		if err != nil || !strings.HasPrefix(path, "/") && !isSocketPath(path) {
+			continue
+		}
+		var stat syscall.Stat_t
+		if err := syscall.Stat(filepath.Join(fdDir, name), &stat); err != nil {
+			continue
+		}
+		major, minor := devNumbers(uint64(stat.Dev))
+		f.Put(pid, uint32(fd), FdInfo{Path: path, Ino: stat.Ino, Major: major, Minor: minor})
+	}
+
+	return nil
+}
+
+// devNumbers returns the major and minor numbers of a device, as the kernel
+// reports them in fd_install events
+func devNumbers(dev uint64) (major, minor uint64) {
+	major = (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor = dev&0xff | (dev>>12)&^0xff
+	return major, minor
+}
+
 func (f *FdMap) Clear() {
 	f.Lock()
 	defer f.Unlock()
@@ -86,7 +176,14 @@ func (f *FdMap) Clear() {
 }
 
 type Context struct {
-	Fds *FdMap
+	Fds       *FdMap
+	Processes *ProcessCache
+
+	// when recording a capture, what is read from /proc to decode events is
+	// also written to Capture; when replaying one, it is read from Replay
+	// instead of /proc (see capture-state.go)
+	Capture *CaptureWriter
+	Replay  *ReplayState
 }
 
 // kernel structures
@@ -238,6 +335,23 @@ func (e FileEvent) GetArgN(n int, ret int64) (string, error) {
 	return "", fmt.Errorf("FileEvent.GetArgN not implemented")
 }
 
+func (e FileEvent) GetArg(name string, ret int64) (string, error) {
+	return getArg("FileEvent", e.Args(ret), name)
+}
+
+func (e FileEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Fd", Value: e.Fd},
+		{Name: "Ino", Value: e.Ino},
+		{Name: "Major", Value: e.Major},
+		{Name: "Minor", Value: e.Minor},
+	}
+}
+
+func (e FileEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 // FileEvent is not meant to be seen by the users
 func (e FileEvent) Metric() *Metric {
 	return nil
@@ -251,9 +365,11 @@ type ChmodEvent struct {
 }
 
 type ChownEvent struct {
-	Filename [256]byte
-	User     uint32
-	Group    uint32
+	Filename  [256]byte
+	User      uint32
+	UserName  string
+	Group     uint32
+	GroupName string
 }
 
 type CloseEvent struct {
@@ -261,6 +377,24 @@ type CloseEvent struct {
 	FdPath string
 }
 
+type DupEvent struct {
+	Fildes     uint64
+	FildesPath string
+}
+
+type Dup2Event struct {
+	Oldfd     uint64
+	OldfdPath string
+	Newfd     uint64
+}
+
+type Dup3Event struct {
+	Oldfd     uint64
+	OldfdPath string
+	Newfd     uint64
+	Flags     int64
+}
+
 type FchmodEvent struct {
 	Fd     uint64
 	FdPath string
@@ -275,19 +409,30 @@ type FchmodatEvent struct {
 }
 
 type FchownEvent struct {
-	Fd     uint64
-	FdPath string
-	User   uint32
-	Group  uint32
+	Fd        uint64
+	FdPath    string
+	User      uint32
+	UserName  string
+	Group     uint32
+	GroupName string
 }
 
 type FchownatEvent struct {
-	Dfd      int64
-	DfdPath  string
-	Filename [256]byte
-	User     uint32
-	Group    uint32
-	Flag     int64
+	Dfd       int64
+	DfdPath   string
+	Filename  [256]byte
+	User      uint32
+	UserName  string
+	Group     uint32
+	GroupName string
+	Flag      int64
+}
+
+type FcntlEvent struct {
+	Fd     uint64
+	FdPath string
+	Cmd    uint64
+	Arg    uint64
 }
 
 type MkdirEvent struct {
@@ -332,7 +477,7 @@ func min(x, y int) int {
 }
 
 // Assume buffer truncates at 0
-func bufLen(buf [256]byte) int {
+func bufLen(buf []byte) int {
 	for idx := 0; idx < len(buf); idx++ {
 		if buf[idx] == 0 {
 			return idx
@@ -341,12 +486,79 @@ func bufLen(buf [256]byte) int {
 	return len(buf)
 }
 
+// cString returns the NUL terminated string in buf
+func cString(buf []byte) string {
+	return string(buf[:bufLen(buf)])
+}
+
+// retBuffer returns the part of buf filled by a syscall returning the length
+// it read or wrote, e.g. read(2). If ret is unknown, buf is assumed to be NUL
+// terminated.
+func retBuffer(buf []byte, ret int64) string {
+	if ret == retUnknown {
+		return cString(buf)
+	}
+	if ret <= 0 {
+		return ""
+	}
+	return string(buf[:min(int(ret), len(buf))])
+}
+
+// getArg returns the value of the argument name of event
+func getArg(event string, args []EventArg, name string) (string, error) {
+	for _, arg := range args {
+		if arg.Name == name {
+			return fmt.Sprintf("%v", arg.Value), nil
+		}
+	}
+	return "", fmt.Errorf("Event %s does not have argument %q", event, name)
+}
+
 type Event interface {
 	String(ret int64) string
 	GetArgN(n int, ret int64) (string, error)
+	GetArg(name string, ret int64) (string, error)
+	Args(ret int64) []EventArg
 	Metric() *Metric
 }
 
+// EventArg is a decoded argument of an event
+type EventArg struct {
+	Name  string
+	Value interface{}
+}
+
+type EventArgs []EventArg
+
+// MarshalJSON encodes the arguments as a JSON object, keeping their order
+func (a EventArgs) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, arg := range a {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(arg.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// retUnknown is passed to Args when the return value of the syscall is not
+// known, e.g. when marshalling an event on its own. Buffers are then assumed
+// to be NUL terminated.
+const retUnknown = math.MinInt64
+
 func procLookupPath(pid, fd uint32) (string, error) {
 	return os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", pid, fd))
 }
@@ -361,312 +573,718 @@ func (e DefaultEvent) GetArgN(n int, ret int64) (string, error) {
 	return "", fmt.Errorf("DefaultEvent.GetArgN not implemented")
 }
 
+func (e DefaultEvent) GetArg(name string, ret int64) (string, error) {
+	return "", fmt.Errorf("DefaultEvent.GetArg not implemented")
+}
+
+func (e DefaultEvent) Args(ret int64) []EventArg {
+	return nil
+}
+
+func (e DefaultEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(nil).MarshalJSON()
+}
+
 func (w DefaultEvent) Metric() *Metric {
 	return nil
 }
 
 func (e ChmodEvent) String(ret int64) string {
-	buffer := (*C.char)(unsafe.Pointer(&e.Filename))
-	length := C.int(0)
-	length = C.int(bufLen(e.Filename))
-	bufferGo := C.GoStringN(buffer, length)
-	return fmt.Sprintf("Filename %q Mode %d ", bufferGo, e.Mode)
+	return fmt.Sprintf("Filename %q Mode %s ", cString(e.Filename[:]), DecodeMode(uint64(e.Mode)))
 }
 
 func (e ChmodEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Filename: type [256]byte
-		buffer := (*C.char)(unsafe.Pointer(&e.Filename))
-		length := C.int(0)
-		length = C.int(bufLen(e.Filename))
-		bufferGo := C.GoStringN(buffer, length)
-		return bufferGo, nil
-	case 1: // Mode: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Mode)
-		return bufferGo, nil
+	case 0: // Filename: string of type [256]byte
+		return cString(e.Filename[:]), nil
+	case 1: // Mode: mode of type uint64
+		return fmt.Sprintf("%v", e.Mode), nil
 	default:
 		return "", fmt.Errorf("Event ChmodEvent does not have argument %d", n)
 	}
 }
 
+func (e ChmodEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Filename":
+		return e.GetArgN(0, ret)
+	case "Mode":
+		return e.GetArgN(1, ret)
+	default:
+		return "", fmt.Errorf("Event ChmodEvent does not have argument %q", name)
+	}
+}
+
+func (e ChmodEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Filename", Value: cString(e.Filename[:])},
+		{Name: "Mode", Value: DecodeMode(uint64(e.Mode))},
+	}
+}
+
+func (e ChmodEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func (e ChownEvent) String(ret int64) string {
-	buffer := (*C.char)(unsafe.Pointer(&e.Filename))
-	length := C.int(0)
-	length = C.int(bufLen(e.Filename))
-	bufferGo := C.GoStringN(buffer, length)
-	return fmt.Sprintf("Filename %q User %d Group %d ", bufferGo, e.User, e.Group)
+	return fmt.Sprintf("Filename %q User %d<%s> Group %d<%s> ", cString(e.Filename[:]), e.User, e.UserName, e.Group, e.GroupName)
 }
 
 func (e ChownEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Filename: type [256]byte
-		buffer := (*C.char)(unsafe.Pointer(&e.Filename))
-		length := C.int(0)
-		length = C.int(bufLen(e.Filename))
-		bufferGo := C.GoStringN(buffer, length)
-		return bufferGo, nil
-	case 1: // User: type uint32
-		bufferGo := fmt.Sprintf("%v", e.User)
-		return bufferGo, nil
-	case 2: // Group: type uint32
-		bufferGo := fmt.Sprintf("%v", e.Group)
-		return bufferGo, nil
+	case 0: // Filename: string of type [256]byte
+		return cString(e.Filename[:]), nil
+	case 1: // User: uid of type uint32
+		return fmt.Sprintf("%v", e.User), nil
+	case 2: // Group: gid of type uint32
+		return fmt.Sprintf("%v", e.Group), nil
 	default:
 		return "", fmt.Errorf("Event ChownEvent does not have argument %d", n)
 	}
 }
 
+func (e ChownEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Filename":
+		return e.GetArgN(0, ret)
+	case "User":
+		return e.GetArgN(1, ret)
+	case "UserName":
+		return e.UserName, nil
+	case "Group":
+		return e.GetArgN(2, ret)
+	case "GroupName":
+		return e.GroupName, nil
+	default:
+		return "", fmt.Errorf("Event ChownEvent does not have argument %q", name)
+	}
+}
+
+func (e ChownEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Filename", Value: cString(e.Filename[:])},
+		{Name: "User", Value: e.User},
+		{Name: "UserName", Value: e.UserName},
+		{Name: "Group", Value: e.Group},
+		{Name: "GroupName", Value: e.GroupName},
+	}
+}
+
+func (e ChownEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func (e CloseEvent) String(ret int64) string {
 	return fmt.Sprintf("Fd %d<%s> ", e.Fd, e.FdPath)
 }
 
 func (e CloseEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Fd: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Fd)
-		return bufferGo, nil
+	case 0: // Fd: fd of type uint64
+		return fmt.Sprintf("%v", e.Fd), nil
 	default:
 		return "", fmt.Errorf("Event CloseEvent does not have argument %d", n)
 	}
 }
 
+func (e CloseEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Fd":
+		return e.GetArgN(0, ret)
+	case "FdPath":
+		return e.FdPath, nil
+	default:
+		return "", fmt.Errorf("Event CloseEvent does not have argument %q", name)
+	}
+}
+
+func (e CloseEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Fd", Value: e.Fd},
+		{Name: "FdPath", Value: e.FdPath},
+	}
+}
+
+func (e CloseEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+func (e DupEvent) String(ret int64) string {
+	return fmt.Sprintf("Fildes %d<%s> ", e.Fildes, e.FildesPath)
+}
+
+func (e DupEvent) GetArgN(n int, ret int64) (string, error) {
+	switch n {
+	case 0: // Fildes: fd of type uint64
+		return fmt.Sprintf("%v", e.Fildes), nil
+	default:
+		return "", fmt.Errorf("Event DupEvent does not have argument %d", n)
+	}
+}
+
+func (e DupEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Fildes":
+		return e.GetArgN(0, ret)
+	case "FildesPath":
+		return e.FildesPath, nil
+	default:
+		return "", fmt.Errorf("Event DupEvent does not have argument %q", name)
+	}
+}
+
+func (e DupEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Fildes", Value: e.Fildes},
+		{Name: "FildesPath", Value: e.FildesPath},
+	}
+}
+
+func (e DupEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+func (e Dup2Event) String(ret int64) string {
+	return fmt.Sprintf("Oldfd %d<%s> Newfd %d ", e.Oldfd, e.OldfdPath, e.Newfd)
+}
+
+func (e Dup2Event) GetArgN(n int, ret int64) (string, error) {
+	switch n {
+	case 0: // Oldfd: fd of type uint64
+		return fmt.Sprintf("%v", e.Oldfd), nil
+	case 1: // Newfd: int of type uint64
+		return fmt.Sprintf("%v", e.Newfd), nil
+	default:
+		return "", fmt.Errorf("Event Dup2Event does not have argument %d", n)
+	}
+}
+
+func (e Dup2Event) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Oldfd":
+		return e.GetArgN(0, ret)
+	case "OldfdPath":
+		return e.OldfdPath, nil
+	case "Newfd":
+		return e.GetArgN(1, ret)
+	default:
+		return "", fmt.Errorf("Event Dup2Event does not have argument %q", name)
+	}
+}
+
+func (e Dup2Event) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Oldfd", Value: e.Oldfd},
+		{Name: "OldfdPath", Value: e.OldfdPath},
+		{Name: "Newfd", Value: e.Newfd},
+	}
+}
+
+func (e Dup2Event) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+func (e Dup3Event) String(ret int64) string {
+	return fmt.Sprintf("Oldfd %d<%s> Newfd %d Flags %d ", e.Oldfd, e.OldfdPath, e.Newfd, e.Flags)
+}
+
+func (e Dup3Event) GetArgN(n int, ret int64) (string, error) {
+	switch n {
+	case 0: // Oldfd: fd of type uint64
+		return fmt.Sprintf("%v", e.Oldfd), nil
+	case 1: // Newfd: int of type uint64
+		return fmt.Sprintf("%v", e.Newfd), nil
+	case 2: // Flags: int of type int64
+		return fmt.Sprintf("%v", e.Flags), nil
+	default:
+		return "", fmt.Errorf("Event Dup3Event does not have argument %d", n)
+	}
+}
+
+func (e Dup3Event) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Oldfd":
+		return e.GetArgN(0, ret)
+	case "OldfdPath":
+		return e.OldfdPath, nil
+	case "Newfd":
+		return e.GetArgN(1, ret)
+	case "Flags":
+		return e.GetArgN(2, ret)
+	default:
+		return "", fmt.Errorf("Event Dup3Event does not have argument %q", name)
+	}
+}
+
+func (e Dup3Event) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Oldfd", Value: e.Oldfd},
+		{Name: "OldfdPath", Value: e.OldfdPath},
+		{Name: "Newfd", Value: e.Newfd},
+		{Name: "Flags", Value: e.Flags},
+	}
+}
+
+func (e Dup3Event) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func (e FchmodEvent) String(ret int64) string {
-	return fmt.Sprintf("Fd %d<%s> Mode %d ", e.Fd, e.FdPath, e.Mode)
+	return fmt.Sprintf("Fd %d<%s> Mode %s ", e.Fd, e.FdPath, DecodeMode(uint64(e.Mode)))
 }
 
 func (e FchmodEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Fd: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Fd)
-		return bufferGo, nil
-	case 1: // Mode: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Mode)
-		return bufferGo, nil
+	case 0: // Fd: fd of type uint64
+		return fmt.Sprintf("%v", e.Fd), nil
+	case 1: // Mode: mode of type uint64
+		return fmt.Sprintf("%v", e.Mode), nil
 	default:
 		return "", fmt.Errorf("Event FchmodEvent does not have argument %d", n)
 	}
 }
 
+func (e FchmodEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Fd":
+		return e.GetArgN(0, ret)
+	case "FdPath":
+		return e.FdPath, nil
+	case "Mode":
+		return e.GetArgN(1, ret)
+	default:
+		return "", fmt.Errorf("Event FchmodEvent does not have argument %q", name)
+	}
+}
+
+func (e FchmodEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Fd", Value: e.Fd},
+		{Name: "FdPath", Value: e.FdPath},
+		{Name: "Mode", Value: DecodeMode(uint64(e.Mode))},
+	}
+}
+
+func (e FchmodEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func (e FchmodatEvent) String(ret int64) string {
-	buffer := (*C.char)(unsafe.Pointer(&e.Filename))
-	length := C.int(0)
-	length = C.int(bufLen(e.Filename))
-	bufferGo := C.GoStringN(buffer, length)
-	return fmt.Sprintf("Dfd %d<%s> Filename %q Mode %d ", e.Dfd, e.DfdPath, bufferGo, e.Mode)
+	return fmt.Sprintf("Dfd %d<%s> Filename %q Mode %s ", e.Dfd, e.DfdPath, cString(e.Filename[:]), DecodeMode(uint64(e.Mode)))
 }
 
 func (e FchmodatEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Dfd: type int64
-		bufferGo := fmt.Sprintf("%v", e.Dfd)
-		return bufferGo, nil
-	case 1: // Filename: type [256]byte
-		buffer := (*C.char)(unsafe.Pointer(&e.Filename))
-		length := C.int(0)
-		length = C.int(bufLen(e.Filename))
-		bufferGo := C.GoStringN(buffer, length)
-		return bufferGo, nil
-	case 2: // Mode: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Mode)
-		return bufferGo, nil
+	case 0: // Dfd: fd of type int64
+		return fmt.Sprintf("%v", e.Dfd), nil
+	case 1: // Filename: string of type [256]byte
+		return cString(e.Filename[:]), nil
+	case 2: // Mode: mode of type uint64
+		return fmt.Sprintf("%v", e.Mode), nil
 	default:
 		return "", fmt.Errorf("Event FchmodatEvent does not have argument %d", n)
 	}
 }
 
+func (e FchmodatEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Dfd":
+		return e.GetArgN(0, ret)
+	case "DfdPath":
+		return e.DfdPath, nil
+	case "Filename":
+		return e.GetArgN(1, ret)
+	case "Mode":
+		return e.GetArgN(2, ret)
+	default:
+		return "", fmt.Errorf("Event FchmodatEvent does not have argument %q", name)
+	}
+}
+
+func (e FchmodatEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Dfd", Value: e.Dfd},
+		{Name: "DfdPath", Value: e.DfdPath},
+		{Name: "Filename", Value: cString(e.Filename[:])},
+		{Name: "Mode", Value: DecodeMode(uint64(e.Mode))},
+	}
+}
+
+func (e FchmodatEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func (e FchownEvent) String(ret int64) string {
-	return fmt.Sprintf("Fd %d<%s> User %d Group %d ", e.Fd, e.FdPath, e.User, e.Group)
+	return fmt.Sprintf("Fd %d<%s> User %d<%s> Group %d<%s> ", e.Fd, e.FdPath, e.User, e.UserName, e.Group, e.GroupName)
 }
 
 func (e FchownEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Fd: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Fd)
-		return bufferGo, nil
-	case 1: // User: type uint32
-		bufferGo := fmt.Sprintf("%v", e.User)
-		return bufferGo, nil
-	case 2: // Group: type uint32
-		bufferGo := fmt.Sprintf("%v", e.Group)
-		return bufferGo, nil
+	case 0: // Fd: fd of type uint64
+		return fmt.Sprintf("%v", e.Fd), nil
+	case 1: // User: uid of type uint32
+		return fmt.Sprintf("%v", e.User), nil
+	case 2: // Group: gid of type uint32
+		return fmt.Sprintf("%v", e.Group), nil
 	default:
 		return "", fmt.Errorf("Event FchownEvent does not have argument %d", n)
 	}
 }
 
+func (e FchownEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Fd":
+		return e.GetArgN(0, ret)
+	case "FdPath":
+		return e.FdPath, nil
+	case "User":
+		return e.GetArgN(1, ret)
+	case "UserName":
+		return e.UserName, nil
+	case "Group":
+		return e.GetArgN(2, ret)
+	case "GroupName":
+		return e.GroupName, nil
+	default:
+		return "", fmt.Errorf("Event FchownEvent does not have argument %q", name)
+	}
+}
+
+func (e FchownEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Fd", Value: e.Fd},
+		{Name: "FdPath", Value: e.FdPath},
+		{Name: "User", Value: e.User},
+		{Name: "UserName", Value: e.UserName},
+		{Name: "Group", Value: e.Group},
+		{Name: "GroupName", Value: e.GroupName},
+	}
+}
+
+func (e FchownEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func (e FchownatEvent) String(ret int64) string {
-	buffer := (*C.char)(unsafe.Pointer(&e.Filename))
-	length := C.int(0)
-	length = C.int(bufLen(e.Filename))
-	bufferGo := C.GoStringN(buffer, length)
-	return fmt.Sprintf("Dfd %d<%s> Filename %q User %d Group %d Flag %d ", e.Dfd, e.DfdPath, bufferGo, e.User, e.Group, e.Flag)
+	return fmt.Sprintf("Dfd %d<%s> Filename %q User %d<%s> Group %d<%s> Flag %s ", e.Dfd, e.DfdPath, cString(e.Filename[:]), e.User, e.UserName, e.Group, e.GroupName, DecodeAtFlags(uint64(e.Flag)))
 }
 
 func (e FchownatEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Dfd: type int64
-		bufferGo := fmt.Sprintf("%v", e.Dfd)
-		return bufferGo, nil
-	case 1: // Filename: type [256]byte
-		buffer := (*C.char)(unsafe.Pointer(&e.Filename))
-		length := C.int(0)
-		length = C.int(bufLen(e.Filename))
-		bufferGo := C.GoStringN(buffer, length)
-		return bufferGo, nil
-	case 2: // User: type uint32
-		bufferGo := fmt.Sprintf("%v", e.User)
-		return bufferGo, nil
-	case 3: // Group: type uint32
-		bufferGo := fmt.Sprintf("%v", e.Group)
-		return bufferGo, nil
-	case 4: // Flag: type int64
-		bufferGo := fmt.Sprintf("%v", e.Flag)
-		return bufferGo, nil
+	case 0: // Dfd: fd of type int64
+		return fmt.Sprintf("%v", e.Dfd), nil
+	case 1: // Filename: string of type [256]byte
+		return cString(e.Filename[:]), nil
+	case 2: // User: uid of type uint32
+		return fmt.Sprintf("%v", e.User), nil
+	case 3: // Group: gid of type uint32
+		return fmt.Sprintf("%v", e.Group), nil
+	case 4: // Flag: at_flags of type int64
+		return fmt.Sprintf("%v", e.Flag), nil
 	default:
 		return "", fmt.Errorf("Event FchownatEvent does not have argument %d", n)
 	}
 }
 
+func (e FchownatEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Dfd":
+		return e.GetArgN(0, ret)
+	case "DfdPath":
+		return e.DfdPath, nil
+	case "Filename":
+		return e.GetArgN(1, ret)
+	case "User":
+		return e.GetArgN(2, ret)
+	case "UserName":
+		return e.UserName, nil
+	case "Group":
+		return e.GetArgN(3, ret)
+	case "GroupName":
+		return e.GroupName, nil
+	case "Flag":
+		return e.GetArgN(4, ret)
+	default:
+		return "", fmt.Errorf("Event FchownatEvent does not have argument %q", name)
+	}
+}
+
+func (e FchownatEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Dfd", Value: e.Dfd},
+		{Name: "DfdPath", Value: e.DfdPath},
+		{Name: "Filename", Value: cString(e.Filename[:])},
+		{Name: "User", Value: e.User},
+		{Name: "UserName", Value: e.UserName},
+		{Name: "Group", Value: e.Group},
+		{Name: "GroupName", Value: e.GroupName},
+		{Name: "Flag", Value: DecodeAtFlags(uint64(e.Flag))},
+	}
+}
+
+func (e FchownatEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+func (e FcntlEvent) String(ret int64) string {
+	return fmt.Sprintf("Fd %d<%s> Cmd %d Arg %d ", e.Fd, e.FdPath, e.Cmd, e.Arg)
+}
+
+func (e FcntlEvent) GetArgN(n int, ret int64) (string, error) {
+	switch n {
+	case 0: // Fd: fd of type uint64
+		return fmt.Sprintf("%v", e.Fd), nil
+	case 1: // Cmd: int of type uint64
+		return fmt.Sprintf("%v", e.Cmd), nil
+	case 2: // Arg: int of type uint64
+		return fmt.Sprintf("%v", e.Arg), nil
+	default:
+		return "", fmt.Errorf("Event FcntlEvent does not have argument %d", n)
+	}
+}
+
+func (e FcntlEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Fd":
+		return e.GetArgN(0, ret)
+	case "FdPath":
+		return e.FdPath, nil
+	case "Cmd":
+		return e.GetArgN(1, ret)
+	case "Arg":
+		return e.GetArgN(2, ret)
+	default:
+		return "", fmt.Errorf("Event FcntlEvent does not have argument %q", name)
+	}
+}
+
+func (e FcntlEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Fd", Value: e.Fd},
+		{Name: "FdPath", Value: e.FdPath},
+		{Name: "Cmd", Value: e.Cmd},
+		{Name: "Arg", Value: e.Arg},
+	}
+}
+
+func (e FcntlEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func (e MkdirEvent) String(ret int64) string {
-	buffer := (*C.char)(unsafe.Pointer(&e.Pathname))
-	length := C.int(0)
-	length = C.int(bufLen(e.Pathname))
-	bufferGo := C.GoStringN(buffer, length)
-	return fmt.Sprintf("Pathname %q Mode %d ", bufferGo, e.Mode)
+	return fmt.Sprintf("Pathname %q Mode %s ", cString(e.Pathname[:]), DecodeMode(uint64(e.Mode)))
 }
 
 func (e MkdirEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Pathname: type [256]byte
-		buffer := (*C.char)(unsafe.Pointer(&e.Pathname))
-		length := C.int(0)
-		length = C.int(bufLen(e.Pathname))
-		bufferGo := C.GoStringN(buffer, length)
-		return bufferGo, nil
-	case 1: // Mode: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Mode)
-		return bufferGo, nil
+	case 0: // Pathname: string of type [256]byte
+		return cString(e.Pathname[:]), nil
+	case 1: // Mode: mode of type uint64
+		return fmt.Sprintf("%v", e.Mode), nil
 	default:
 		return "", fmt.Errorf("Event MkdirEvent does not have argument %d", n)
 	}
 }
 
+func (e MkdirEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Pathname":
+		return e.GetArgN(0, ret)
+	case "Mode":
+		return e.GetArgN(1, ret)
+	default:
+		return "", fmt.Errorf("Event MkdirEvent does not have argument %q", name)
+	}
+}
+
+func (e MkdirEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Pathname", Value: cString(e.Pathname[:])},
+		{Name: "Mode", Value: DecodeMode(uint64(e.Mode))},
+	}
+}
+
+func (e MkdirEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func (e MkdiratEvent) String(ret int64) string {
-	buffer := (*C.char)(unsafe.Pointer(&e.Pathname))
-	length := C.int(0)
-	length = C.int(bufLen(e.Pathname))
-	bufferGo := C.GoStringN(buffer, length)
-	return fmt.Sprintf("Dfd %d<%s> Pathname %q Mode %d ", e.Dfd, e.DfdPath, bufferGo, e.Mode)
+	return fmt.Sprintf("Dfd %d<%s> Pathname %q Mode %s ", e.Dfd, e.DfdPath, cString(e.Pathname[:]), DecodeMode(uint64(e.Mode)))
 }
 
 func (e MkdiratEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Dfd: type int64
-		bufferGo := fmt.Sprintf("%v", e.Dfd)
-		return bufferGo, nil
-	case 1: // Pathname: type [256]byte
-		buffer := (*C.char)(unsafe.Pointer(&e.Pathname))
-		length := C.int(0)
-		length = C.int(bufLen(e.Pathname))
-		bufferGo := C.GoStringN(buffer, length)
-		return bufferGo, nil
-	case 2: // Mode: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Mode)
-		return bufferGo, nil
+	case 0: // Dfd: fd of type int64
+		return fmt.Sprintf("%v", e.Dfd), nil
+	case 1: // Pathname: string of type [256]byte
+		return cString(e.Pathname[:]), nil
+	case 2: // Mode: mode of type uint64
+		return fmt.Sprintf("%v", e.Mode), nil
 	default:
 		return "", fmt.Errorf("Event MkdiratEvent does not have argument %d", n)
 	}
 }
 
+func (e MkdiratEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Dfd":
+		return e.GetArgN(0, ret)
+	case "DfdPath":
+		return e.DfdPath, nil
+	case "Pathname":
+		return e.GetArgN(1, ret)
+	case "Mode":
+		return e.GetArgN(2, ret)
+	default:
+		return "", fmt.Errorf("Event MkdiratEvent does not have argument %q", name)
+	}
+}
+
+func (e MkdiratEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Dfd", Value: e.Dfd},
+		{Name: "DfdPath", Value: e.DfdPath},
+		{Name: "Pathname", Value: cString(e.Pathname[:])},
+		{Name: "Mode", Value: DecodeMode(uint64(e.Mode))},
+	}
+}
+
+func (e MkdiratEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func (e OpenEvent) String(ret int64) string {
-	buffer := (*C.char)(unsafe.Pointer(&e.Filename))
-	length := C.int(0)
-	length = C.int(bufLen(e.Filename))
-	bufferGo := C.GoStringN(buffer, length)
-	return fmt.Sprintf("Filename %q Flags %d Mode %d ", bufferGo, e.Flags, e.Mode)
+	return fmt.Sprintf("Filename %q Flags %s Mode %s ", cString(e.Filename[:]), DecodeOpenFlags(uint64(e.Flags)), DecodeMode(uint64(e.Mode)))
 }
 
 func (e OpenEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Filename: type [256]byte
-		buffer := (*C.char)(unsafe.Pointer(&e.Filename))
-		length := C.int(0)
-		length = C.int(bufLen(e.Filename))
-		bufferGo := C.GoStringN(buffer, length)
-		return bufferGo, nil
-	case 1: // Flags: type int64
-		bufferGo := fmt.Sprintf("%v", e.Flags)
-		return bufferGo, nil
-	case 2: // Mode: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Mode)
-		return bufferGo, nil
+	case 0: // Filename: string of type [256]byte
+		return cString(e.Filename[:]), nil
+	case 1: // Flags: open_flags of type int64
+		return fmt.Sprintf("%v", e.Flags), nil
+	case 2: // Mode: mode of type uint64
+		return fmt.Sprintf("%v", e.Mode), nil
 	default:
 		return "", fmt.Errorf("Event OpenEvent does not have argument %d", n)
 	}
 }
 
-func (e ReadEvent) String(ret int64) string {
-	buffer := (*C.char)(unsafe.Pointer(&e.Buf))
-	length := C.int(0)
-	if ret > 0 {
-		length = C.int(min(int(ret), len(e.Buf)))
+func (e OpenEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Filename":
+		return e.GetArgN(0, ret)
+	case "Flags":
+		return e.GetArgN(1, ret)
+	case "Mode":
+		return e.GetArgN(2, ret)
+	default:
+		return "", fmt.Errorf("Event OpenEvent does not have argument %q", name)
+	}
+}
+
+func (e OpenEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Filename", Value: cString(e.Filename[:])},
+		{Name: "Flags", Value: DecodeOpenFlags(uint64(e.Flags))},
+		{Name: "Mode", Value: DecodeMode(uint64(e.Mode))},
 	}
-	bufferGo := C.GoStringN(buffer, length)
-	return fmt.Sprintf("Fd %d<%s> Buf %q Count %d ", e.Fd, e.FdPath, bufferGo, e.Count)
+}
+
+func (e OpenEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+func (e ReadEvent) String(ret int64) string {
+	return fmt.Sprintf("Fd %d<%s> Buf %q Count %d ", e.Fd, e.FdPath, retBuffer(e.Buf[:], ret), e.Count)
 }
 
 func (e ReadEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Fd: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Fd)
-		return bufferGo, nil
-	case 1: // Buf: type [256]byte
-		buffer := (*C.char)(unsafe.Pointer(&e.Buf))
-		length := C.int(0)
-		if ret > 0 {
-			length = C.int(min(int(ret), len(e.Buf)))
-		}
-		bufferGo := C.GoStringN(buffer, length)
-		return bufferGo, nil
-	case 2: // Count: type int64
-		bufferGo := fmt.Sprintf("%v", e.Count)
-		return bufferGo, nil
+	case 0: // Fd: fd of type uint64
+		return fmt.Sprintf("%v", e.Fd), nil
+	case 1: // Buf: buffer of type [256]byte
+		return retBuffer(e.Buf[:], ret), nil
+	case 2: // Count: int of type int64
+		return fmt.Sprintf("%v", e.Count), nil
 	default:
 		return "", fmt.Errorf("Event ReadEvent does not have argument %d", n)
 	}
 }
 
-func (e WriteEvent) String(ret int64) string {
-	buffer := (*C.char)(unsafe.Pointer(&e.Buf))
-	length := C.int(0)
-	if ret > 0 {
-		length = C.int(min(int(ret), len(e.Buf)))
+func (e ReadEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Fd":
+		return e.GetArgN(0, ret)
+	case "FdPath":
+		return e.FdPath, nil
+	case "Buf":
+		return e.GetArgN(1, ret)
+	case "Count":
+		return e.GetArgN(2, ret)
+	default:
+		return "", fmt.Errorf("Event ReadEvent does not have argument %q", name)
+	}
+}
+
+func (e ReadEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Fd", Value: e.Fd},
+		{Name: "FdPath", Value: e.FdPath},
+		{Name: "Buf", Value: retBuffer(e.Buf[:], ret)},
+		{Name: "Count", Value: e.Count},
 	}
-	bufferGo := C.GoStringN(buffer, length)
-	return fmt.Sprintf("Fd %d<%s> Buf %q Count %d ", e.Fd, e.FdPath, bufferGo, e.Count)
+}
+
+func (e ReadEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+func (e WriteEvent) String(ret int64) string {
+	return fmt.Sprintf("Fd %d<%s> Buf %q Count %d ", e.Fd, e.FdPath, retBuffer(e.Buf[:], ret), e.Count)
 }
 
 func (e WriteEvent) GetArgN(n int, ret int64) (string, error) {
 	switch n {
-	case 0: // Fd: type uint64
-		bufferGo := fmt.Sprintf("%v", e.Fd)
-		return bufferGo, nil
-	case 1: // Buf: type [256]byte
-		buffer := (*C.char)(unsafe.Pointer(&e.Buf))
-		length := C.int(0)
-		if ret > 0 {
-			length = C.int(min(int(ret), len(e.Buf)))
-		}
-		bufferGo := C.GoStringN(buffer, length)
-		return bufferGo, nil
-	case 2: // Count: type int64
-		bufferGo := fmt.Sprintf("%v", e.Count)
-		return bufferGo, nil
+	case 0: // Fd: fd of type uint64
+		return fmt.Sprintf("%v", e.Fd), nil
+	case 1: // Buf: buffer of type [256]byte
+		return retBuffer(e.Buf[:], ret), nil
+	case 2: // Count: int of type int64
+		return fmt.Sprintf("%v", e.Count), nil
 	default:
 		return "", fmt.Errorf("Event WriteEvent does not have argument %d", n)
 	}
 }
 
+func (e WriteEvent) GetArg(name string, ret int64) (string, error) {
+	switch name {
+	case "Fd":
+		return e.GetArgN(0, ret)
+	case "FdPath":
+		return e.FdPath, nil
+	case "Buf":
+		return e.GetArgN(1, ret)
+	case "Count":
+		return e.GetArgN(2, ret)
+	default:
+		return "", fmt.Errorf("Event WriteEvent does not have argument %q", name)
+	}
+}
+
+func (e WriteEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Fd", Value: e.Fd},
+		{Name: "FdPath", Value: e.FdPath},
+		{Name: "Buf", Value: retBuffer(e.Buf[:], ret)},
+		{Name: "Count", Value: e.Count},
+	}
+}
+
+func (e WriteEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func GetStruct(ce *CommonEvent, ctx Context, buf *bytes.Buffer) (Event, error) {
 	switch ce.Name {
 
@@ -681,60 +1299,57 @@ func GetStruct(ce *CommonEvent, ctx Context, buf *bytes.Buffer) (Event, error) {
 		ev := ChownEvent{}
 		copy(ev.Filename[:], buf.Next(256))
 		ev.User = uint32(binary.LittleEndian.Uint32(buf.Next(4)))
+		ev.UserName = ctx.lookupUser(ce.Pid, uint32(ev.User))
 		ev.Group = uint32(binary.LittleEndian.Uint32(buf.Next(4)))
+		ev.GroupName = ctx.lookupGroup(ce.Pid, uint32(ev.Group))
 
 		return ev, nil
 
 	case "close":
 		ev := CloseEvent{}
 		ev.Fd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
-		fileName := "unknown"
-		info, ok := ctx.Fds.Get(uint32(ce.Pid), uint32(ev.Fd))
-		if ok {
-			var stat syscall.Stat_t
-			path := filepath.Join("/proc", strconv.FormatInt(int64(ce.Pid), 10), "root", info.Path)
-			err := syscall.Stat(path, &stat)
-			if err != nil {
-				if err == syscall.ENOENT {
-					// the file doesn't exist anymore, it's probably "info.Path"
-					// but we're not sure
-					fileName = fmt.Sprintf("[deleted] (%q)?", info.Path)
-				}
-			}
-			if info.Ino == stat.Ino &&
-				info.Major == stat.Dev>>8 &&
-				info.Minor == stat.Dev&0xff {
-				fileName = info.Path
-			}
-		}
-		ev.FdPath = fileName
+		ev.FdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Fd))
 		ctx.Fds.Delete(uint32(ce.Pid), uint32(ev.Fd))
 
 		return ev, nil
 
+	case "dup":
+		ev := DupEvent{}
+		ev.Fildes = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
+		ev.FildesPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Fildes))
+		if ce.Ret >= 0 {
+			ctx.Fds.Copy(uint32(ce.Pid), uint32(ev.Fildes), uint32(ce.Ret))
+		}
+
+		return ev, nil
+
+	case "dup2":
+		ev := Dup2Event{}
+		ev.Oldfd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
+		ev.OldfdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Oldfd))
+		ev.Newfd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
+		if ce.Ret >= 0 {
+			ctx.Fds.Copy(uint32(ce.Pid), uint32(ev.Oldfd), uint32(ce.Ret))
+		}
+
+		return ev, nil
+
+	case "dup3":
+		ev := Dup3Event{}
+		ev.Oldfd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
+		ev.OldfdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Oldfd))
+		ev.Newfd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
+		ev.Flags = int64(binary.LittleEndian.Uint64(buf.Next(8)))
+		if ce.Ret >= 0 {
+			ctx.Fds.Copy(uint32(ce.Pid), uint32(ev.Oldfd), uint32(ce.Ret))
+		}
+
+		return ev, nil
+
 	case "fchmod":
 		ev := FchmodEvent{}
 		ev.Fd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
-		fileName := "unknown"
-		info, ok := ctx.Fds.Get(uint32(ce.Pid), uint32(ev.Fd))
-		if ok {
-			var stat syscall.Stat_t
-			path := filepath.Join("/proc", strconv.FormatInt(int64(ce.Pid), 10), "root", info.Path)
-			err := syscall.Stat(path, &stat)
-			if err != nil {
-				if err == syscall.ENOENT {
-					// the file doesn't exist anymore, it's probably "info.Path"
-					// but we're not sure
-					fileName = fmt.Sprintf("[deleted] (%q)?", info.Path)
-				}
-			}
-			if info.Ino == stat.Ino &&
-				info.Major == stat.Dev>>8 &&
-				info.Minor == stat.Dev&0xff {
-				fileName = info.Path
-			}
-		}
-		ev.FdPath = fileName
+		ev.FdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Fd))
 		ev.Mode = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
 
 		return ev, nil
@@ -742,26 +1357,7 @@ func GetStruct(ce *CommonEvent, ctx Context, buf *bytes.Buffer) (Event, error) {
 	case "fchmodat":
 		ev := FchmodatEvent{}
 		ev.Dfd = int64(binary.LittleEndian.Uint64(buf.Next(8)))
-		fileName := "unknown"
-		info, ok := ctx.Fds.Get(uint32(ce.Pid), uint32(ev.Dfd))
-		if ok {
-			var stat syscall.Stat_t
-			path := filepath.Join("/proc", strconv.FormatInt(int64(ce.Pid), 10), "root", info.Path)
-			err := syscall.Stat(path, &stat)
-			if err != nil {
-				if err == syscall.ENOENT {
-					// the file doesn't exist anymore, it's probably "info.Path"
-					// but we're not sure
-					fileName = fmt.Sprintf("[deleted] (%q)?", info.Path)
-				}
-			}
-			if info.Ino == stat.Ino &&
-				info.Major == stat.Dev>>8 &&
-				info.Minor == stat.Dev&0xff {
-				fileName = info.Path
-			}
-		}
-		ev.DfdPath = fileName
+		ev.DfdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Dfd))
 		copy(ev.Filename[:], buf.Next(256))
 		ev.Mode = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
 
@@ -770,61 +1366,39 @@ func GetStruct(ce *CommonEvent, ctx Context, buf *bytes.Buffer) (Event, error) {
 	case "fchown":
 		ev := FchownEvent{}
 		ev.Fd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
-		fileName := "unknown"
-		info, ok := ctx.Fds.Get(uint32(ce.Pid), uint32(ev.Fd))
-		if ok {
-			var stat syscall.Stat_t
-			path := filepath.Join("/proc", strconv.FormatInt(int64(ce.Pid), 10), "root", info.Path)
-			err := syscall.Stat(path, &stat)
-			if err != nil {
-				if err == syscall.ENOENT {
-					// the file doesn't exist anymore, it's probably "info.Path"
-					// but we're not sure
-					fileName = fmt.Sprintf("[deleted] (%q)?", info.Path)
-				}
-			}
-			if info.Ino == stat.Ino &&
-				info.Major == stat.Dev>>8 &&
-				info.Minor == stat.Dev&0xff {
-				fileName = info.Path
-			}
-		}
-		ev.FdPath = fileName
+		ev.FdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Fd))
 		ev.User = uint32(binary.LittleEndian.Uint32(buf.Next(4)))
+		ev.UserName = ctx.lookupUser(ce.Pid, uint32(ev.User))
 		ev.Group = uint32(binary.LittleEndian.Uint32(buf.Next(4)))
+		ev.GroupName = ctx.lookupGroup(ce.Pid, uint32(ev.Group))
 
 		return ev, nil
 
 	case "fchownat":
 		ev := FchownatEvent{}
 		ev.Dfd = int64(binary.LittleEndian.Uint64(buf.Next(8)))
-		fileName := "unknown"
-		info, ok := ctx.Fds.Get(uint32(ce.Pid), uint32(ev.Dfd))
-		if ok {
-			var stat syscall.Stat_t
-			path := filepath.Join("/proc", strconv.FormatInt(int64(ce.Pid), 10), "root", info.Path)
-			err := syscall.Stat(path, &stat)
-			if err != nil {
-				if err == syscall.ENOENT {
-					// the file doesn't exist anymore, it's probably "info.Path"
-					// but we're not sure
-					fileName = fmt.Sprintf("[deleted] (%q)?", info.Path)
-				}
-			}
-			if info.Ino == stat.Ino &&
-				info.Major == stat.Dev>>8 &&
-				info.Minor == stat.Dev&0xff {
-				fileName = info.Path
-			}
-		}
-		ev.DfdPath = fileName
+		ev.DfdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Dfd))
 		copy(ev.Filename[:], buf.Next(256))
 		ev.User = uint32(binary.LittleEndian.Uint32(buf.Next(4)))
+		ev.UserName = ctx.lookupUser(ce.Pid, uint32(ev.User))
 		ev.Group = uint32(binary.LittleEndian.Uint32(buf.Next(4)))
+		ev.GroupName = ctx.lookupGroup(ce.Pid, uint32(ev.Group))
 		ev.Flag = int64(binary.LittleEndian.Uint64(buf.Next(8)))
 
 		return ev, nil
 
+	case "fcntl":
+		ev := FcntlEvent{}
+		ev.Fd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
+		ev.FdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Fd))
+		ev.Cmd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
+		ev.Arg = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
+		if (ev.Cmd == syscall.F_DUPFD || ev.Cmd == syscall.F_DUPFD_CLOEXEC) && ce.Ret >= 0 {
+			ctx.Fds.Copy(uint32(ce.Pid), uint32(ev.Fd), uint32(ce.Ret))
+		}
+
+		return ev, nil
+
 	case "mkdir":
 		ev := MkdirEvent{}
 		copy(ev.Pathname[:], buf.Next(256))
@@ -835,26 +1409,7 @@ func GetStruct(ce *CommonEvent, ctx Context, buf *bytes.Buffer) (Event, error) {
 	case "mkdirat":
 		ev := MkdiratEvent{}
 		ev.Dfd = int64(binary.LittleEndian.Uint64(buf.Next(8)))
-		fileName := "unknown"
-		info, ok := ctx.Fds.Get(uint32(ce.Pid), uint32(ev.Dfd))
-		if ok {
-			var stat syscall.Stat_t
-			path := filepath.Join("/proc", strconv.FormatInt(int64(ce.Pid), 10), "root", info.Path)
-			err := syscall.Stat(path, &stat)
-			if err != nil {
-				if err == syscall.ENOENT {
-					// the file doesn't exist anymore, it's probably "info.Path"
-					// but we're not sure
-					fileName = fmt.Sprintf("[deleted] (%q)?", info.Path)
-				}
-			}
-			if info.Ino == stat.Ino &&
-				info.Major == stat.Dev>>8 &&
-				info.Minor == stat.Dev&0xff {
-				fileName = info.Path
-			}
-		}
-		ev.DfdPath = fileName
+		ev.DfdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Dfd))
 		copy(ev.Pathname[:], buf.Next(256))
 		ev.Mode = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
 
@@ -871,26 +1426,7 @@ func GetStruct(ce *CommonEvent, ctx Context, buf *bytes.Buffer) (Event, error) {
 	case "read":
 		ev := ReadEvent{}
 		ev.Fd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
-		fileName := "unknown"
-		info, ok := ctx.Fds.Get(uint32(ce.Pid), uint32(ev.Fd))
-		if ok {
-			var stat syscall.Stat_t
-			path := filepath.Join("/proc", strconv.FormatInt(int64(ce.Pid), 10), "root", info.Path)
-			err := syscall.Stat(path, &stat)
-			if err != nil {
-				if err == syscall.ENOENT {
-					// the file doesn't exist anymore, it's probably "info.Path"
-					// but we're not sure
-					fileName = fmt.Sprintf("[deleted] (%q)?", info.Path)
-				}
-			}
-			if info.Ino == stat.Ino &&
-				info.Major == stat.Dev>>8 &&
-				info.Minor == stat.Dev&0xff {
-				fileName = info.Path
-			}
-		}
-		ev.FdPath = fileName
+		ev.FdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Fd))
 		copy(ev.Buf[:], buf.Next(256))
 		ev.Count = int64(binary.LittleEndian.Uint64(buf.Next(8)))
 
@@ -899,26 +1435,7 @@ func GetStruct(ce *CommonEvent, ctx Context, buf *bytes.Buffer) (Event, error) {
 	case "write":
 		ev := WriteEvent{}
 		ev.Fd = uint64(binary.LittleEndian.Uint64(buf.Next(8)))
-		fileName := "unknown"
-		info, ok := ctx.Fds.Get(uint32(ce.Pid), uint32(ev.Fd))
-		if ok {
-			var stat syscall.Stat_t
-			path := filepath.Join("/proc", strconv.FormatInt(int64(ce.Pid), 10), "root", info.Path)
-			err := syscall.Stat(path, &stat)
-			if err != nil {
-				if err == syscall.ENOENT {
-					// the file doesn't exist anymore, it's probably "info.Path"
-					// but we're not sure
-					fileName = fmt.Sprintf("[deleted] (%q)?", info.Path)
-				}
-			}
-			if info.Ino == stat.Ino &&
-				info.Major == stat.Dev>>8 &&
-				info.Minor == stat.Dev&0xff {
-				fileName = info.Path
-			}
-		}
-		ev.FdPath = fileName
+		ev.FdPath = ctx.fdPath(uint32(ce.Pid), uint32(ev.Fd))
 		copy(ev.Buf[:], buf.Next(256))
 		ev.Count = int64(binary.LittleEndian.Uint64(buf.Next(8)))
 
@@ -930,18 +1447,20 @@ func GetStruct(ce *CommonEvent, ctx Context, buf *bytes.Buffer) (Event, error) {
 		if err := binary.Read(buf, binary.LittleEndian, &ev); err != nil {
 			return nil, err
 		}
-		name, err := procLookupPath(uint32(ce.Pid), uint32(ev.Fd))
-		if err != nil {
-			name = "unknown"
-		}
-
-		fdInfo := FdInfo{Path: name, Ino: ev.Ino, Major: ev.Major, Minor: ev.Minor}
-
-		// ignore entries not backed by files, like sockets or anonymous inodes
-		if strings.HasPrefix(fdInfo.Path, "/") {
-			ctx.Fds.Put(uint32(ce.Pid), uint32(ev.Fd), fdInfo)
-		}
-
+		ctx.installFd(uint32(ce.Pid), ev)
+		return ev, nil
+	// process events
+	case "fork":
+		ev := ForkEvent{}
+		ev.ChildPid = binary.LittleEndian.Uint64(buf.Next(8))
+		ctx.Fds.CopyPid(uint32(ce.Pid), uint32(ev.ChildPid))
+		return ev, nil
+	case "exit":
+		ev := ExitEvent{}
+		ev.ExitCode = int64(binary.LittleEndian.Uint64(buf.Next(8)))
+		ev.Signal = int64(binary.LittleEndian.Uint64(buf.Next(8)))
+		ctx.Fds.DeletePid(uint32(ce.Pid))
+		ctx.deleteProcess(ce.Pid)
 		return ev, nil
 	// network events
 	case "close_v4":
@@ -1007,10 +1526,46 @@ func (e ConnectV4Event) GetArgN(n int, ret int64) (string, error) {
 	return "", fmt.Errorf("ConnectV4Event.GetArgN not implemented")
 }
 
+func (e ConnectV4Event) GetArg(name string, ret int64) (string, error) {
+	return getArg("ConnectV4Event", e.Args(ret), name)
+}
+
 func (e ConnectV6Event) GetArgN(n int, ret int64) (string, error) {
 	return "", fmt.Errorf("ConnectV6Event.GetArgN not implemented")
 }
 
+func (e ConnectV6Event) GetArg(name string, ret int64) (string, error) {
+	return getArg("ConnectV6Event", e.Args(ret), name)
+}
+
+func (e ConnectV4Event) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Saddr", Value: inet_ntoa(e.Saddr)},
+		{Name: "Daddr", Value: inet_ntoa(e.Daddr)},
+		{Name: "Sport", Value: e.Sport},
+		{Name: "Dport", Value: e.Dport},
+		{Name: "Netns", Value: e.Netns},
+	}
+}
+
+func (e ConnectV6Event) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "Saddr", Value: inet_ntoa6(e.Saddr)},
+		{Name: "Daddr", Value: inet_ntoa6(e.Daddr)},
+		{Name: "Sport", Value: e.Sport},
+		{Name: "Dport", Value: e.Dport},
+		{Name: "Netns", Value: e.Netns},
+	}
+}
+
+func (e ConnectV4Event) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+func (e ConnectV6Event) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
 func (e ConnectV4Event) Metric() *Metric {
 	return &Metric{
 		ConnectV4Event: &ProtobufConnectV4Event{
@@ -1072,6 +1627,33 @@ func (e CloseEvent) Metric() *Metric {
 	}
 }
 
+func (e DupEvent) Metric() *Metric {
+	return &Metric{
+		DupEvent: &ProtobufDupEvent{
+			Fildes: e.Fildes,
+		},
+	}
+}
+
+func (e Dup2Event) Metric() *Metric {
+	return &Metric{
+		Dup2Event: &ProtobufDup2Event{
+			Oldfd: e.Oldfd,
+			Newfd: e.Newfd,
+		},
+	}
+}
+
+func (e Dup3Event) Metric() *Metric {
+	return &Metric{
+		Dup3Event: &ProtobufDup3Event{
+			Oldfd: e.Oldfd,
+			Newfd: e.Newfd,
+			Flags: e.Flags,
+		},
+	}
+}
+
 func (e FchmodEvent) Metric() *Metric {
 	return &Metric{
 		FchmodEvent: &ProtobufFchmodEvent{
@@ -1113,6 +1695,16 @@ func (e FchownatEvent) Metric() *Metric {
 	}
 }
 
+func (e FcntlEvent) Metric() *Metric {
+	return &Metric{
+		FcntlEvent: &ProtobufFcntlEvent{
+			Fd:  e.Fd,
+			Cmd: e.Cmd,
+			Arg: e.Arg,
+		},
+	}
+}
+
 func (e MkdirEvent) Metric() *Metric {
 	return &Metric{
 		MkdirEvent: &ProtobufMkdirEvent{
@@ -1170,3 +1762,81 @@ type FileEvent struct {
 	Major uint64
 	Minor uint64
 }
+
+// process events struct
+
+type ForkEvent struct {
+	ChildPid uint64
+}
+
+func (e ForkEvent) String(ret int64) string {
+	return fmt.Sprintf("ChildPid %d ", e.ChildPid)
+}
+
+func (e ForkEvent) GetArgN(n int, ret int64) (string, error) {
+	switch n {
+	case 0: // ChildPid: type uint64
+		return fmt.Sprintf("%v", e.ChildPid), nil
+	default:
+		return "", fmt.Errorf("Event ForkEvent does not have argument %d", n)
+	}
+}
+
+func (e ForkEvent) GetArg(name string, ret int64) (string, error) {
+	return getArg("ForkEvent", e.Args(ret), name)
+}
+
+func (e ForkEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "ChildPid", Value: e.ChildPid},
+	}
+}
+
+func (e ForkEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+// ForkEvent is not sent to collectors
+func (e ForkEvent) Metric() *Metric {
+	return nil
+}
+
+type ExitEvent struct {
+	ExitCode int64
+	Signal   int64
+}
+
+func (e ExitEvent) String(ret int64) string {
+	return fmt.Sprintf("ExitCode %d Signal %d ", e.ExitCode, e.Signal)
+}
+
+func (e ExitEvent) GetArgN(n int, ret int64) (string, error) {
+	switch n {
+	case 0: // ExitCode: type int64
+		return fmt.Sprintf("%v", e.ExitCode), nil
+	case 1: // Signal: type int64
+		return fmt.Sprintf("%v", e.Signal), nil
+	default:
+		return "", fmt.Errorf("Event ExitEvent does not have argument %d", n)
+	}
+}
+
+func (e ExitEvent) GetArg(name string, ret int64) (string, error) {
+	return getArg("ExitEvent", e.Args(ret), name)
+}
+
+func (e ExitEvent) Args(ret int64) []EventArg {
+	return []EventArg{
+		{Name: "ExitCode", Value: e.ExitCode},
+		{Name: "Signal", Value: e.Signal},
+	}
+}
+
+func (e ExitEvent) MarshalJSON() ([]byte, error) {
+	return EventArgs(e.Args(retUnknown)).MarshalJSON()
+}
+
+// ExitEvent is not sent to collectors
+func (e ExitEvent) Metric() *Metric {
+	return nil
+}
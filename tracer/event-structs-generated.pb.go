@@ -14,10 +14,14 @@ It has these top-level messages:
 	ProtobufChmodEvent
 	ProtobufChownEvent
 	ProtobufCloseEvent
+	ProtobufDupEvent
+	ProtobufDup2Event
+	ProtobufDup3Event
 	ProtobufFchmodEvent
 	ProtobufFchmodatEvent
 	ProtobufFchownEvent
 	ProtobufFchownatEvent
+	ProtobufFcntlEvent
 	ProtobufMkdirEvent
 	ProtobufMkdiratEvent
 	ProtobufOpenEvent
@@ -25,6 +29,11 @@ It has these top-level messages:
 	ProtobufWriteEvent
 	Empty
 	Metric
+	ProtobufContainer
+	ProtobufProcess
+	ProtobufWindowKey
+	ProtobufPercentile
+	ProtobufWindowSummary
 */
 package tracer
 
@@ -55,6 +64,7 @@ type ProtobufCommonEvent struct {
 	Name      string `protobuf:"bytes,4,opt,name=Name" json:"Name,omitempty"`
 	Hash      uint64 `protobuf:"varint,5,opt,name=Hash" json:"Hash,omitempty"`
 	Flags     uint64 `protobuf:"varint,6,opt,name=Flags" json:"Flags,omitempty"`
+	Duration  uint64 `protobuf:"varint,7,opt,name=Duration" json:"Duration,omitempty"`
 }
 
 func (m *ProtobufCommonEvent) Reset()                    { *m = ProtobufCommonEvent{} }
@@ -104,6 +114,13 @@ func (m *ProtobufCommonEvent) GetFlags() uint64 {
 	return 0
 }
 
+func (m *ProtobufCommonEvent) GetDuration() uint64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
 type ProtobufConnectV4Event struct {
 	Saddr uint32 `protobuf:"varint,1,opt,name=Saddr" json:"Saddr,omitempty"`
 	Daddr uint32 `protobuf:"varint,2,opt,name=Daddr" json:"Daddr,omitempty"`
@@ -272,6 +289,78 @@ func (m *ProtobufCloseEvent) GetFd() uint64 {
 	return 0
 }
 
+type ProtobufDupEvent struct {
+	Fildes uint64 `protobuf:"varint,1,opt,name=fildes" json:"fildes,omitempty"`
+}
+
+func (m *ProtobufDupEvent) Reset()                    { *m = ProtobufDupEvent{} }
+func (m *ProtobufDupEvent) String() string            { return proto.CompactTextString(m) }
+func (*ProtobufDupEvent) ProtoMessage()               {}
+func (*ProtobufDupEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *ProtobufDupEvent) GetFildes() uint64 {
+	if m != nil {
+		return m.Fildes
+	}
+	return 0
+}
+
+type ProtobufDup2Event struct {
+	Oldfd uint64 `protobuf:"varint,1,opt,name=oldfd" json:"oldfd,omitempty"`
+	Newfd uint64 `protobuf:"varint,2,opt,name=newfd" json:"newfd,omitempty"`
+}
+
+func (m *ProtobufDup2Event) Reset()                    { *m = ProtobufDup2Event{} }
+func (m *ProtobufDup2Event) String() string            { return proto.CompactTextString(m) }
+func (*ProtobufDup2Event) ProtoMessage()               {}
+func (*ProtobufDup2Event) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *ProtobufDup2Event) GetOldfd() uint64 {
+	if m != nil {
+		return m.Oldfd
+	}
+	return 0
+}
+
+func (m *ProtobufDup2Event) GetNewfd() uint64 {
+	if m != nil {
+		return m.Newfd
+	}
+	return 0
+}
+
+type ProtobufDup3Event struct {
+	Oldfd uint64 `protobuf:"varint,1,opt,name=oldfd" json:"oldfd,omitempty"`
+	Newfd uint64 `protobuf:"varint,2,opt,name=newfd" json:"newfd,omitempty"`
+	Flags int64  `protobuf:"varint,3,opt,name=flags" json:"flags,omitempty"`
+}
+
+func (m *ProtobufDup3Event) Reset()                    { *m = ProtobufDup3Event{} }
+func (m *ProtobufDup3Event) String() string            { return proto.CompactTextString(m) }
+func (*ProtobufDup3Event) ProtoMessage()               {}
+func (*ProtobufDup3Event) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *ProtobufDup3Event) GetOldfd() uint64 {
+	if m != nil {
+		return m.Oldfd
+	}
+	return 0
+}
+
+func (m *ProtobufDup3Event) GetNewfd() uint64 {
+	if m != nil {
+		return m.Newfd
+	}
+	return 0
+}
+
+func (m *ProtobufDup3Event) GetFlags() int64 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
 type ProtobufFchmodEvent struct {
 	Fd   uint64 `protobuf:"varint,1,opt,name=fd" json:"fd,omitempty"`
 	Mode uint64 `protobuf:"varint,2,opt,name=mode" json:"mode,omitempty"`
@@ -280,7 +369,7 @@ type ProtobufFchmodEvent struct {
 func (m *ProtobufFchmodEvent) Reset()                    { *m = ProtobufFchmodEvent{} }
 func (m *ProtobufFchmodEvent) String() string            { return proto.CompactTextString(m) }
 func (*ProtobufFchmodEvent) ProtoMessage()               {}
-func (*ProtobufFchmodEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*ProtobufFchmodEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
 
 func (m *ProtobufFchmodEvent) GetFd() uint64 {
 	if m != nil {
@@ -305,7 +394,7 @@ type ProtobufFchmodatEvent struct {
 func (m *ProtobufFchmodatEvent) Reset()                    { *m = ProtobufFchmodatEvent{} }
 func (m *ProtobufFchmodatEvent) String() string            { return proto.CompactTextString(m) }
 func (*ProtobufFchmodatEvent) ProtoMessage()               {}
-func (*ProtobufFchmodatEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+func (*ProtobufFchmodatEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
 
 func (m *ProtobufFchmodatEvent) GetDfd() int64 {
 	if m != nil {
@@ -337,7 +426,7 @@ type ProtobufFchownEvent struct {
 func (m *ProtobufFchownEvent) Reset()                    { *m = ProtobufFchownEvent{} }
 func (m *ProtobufFchownEvent) String() string            { return proto.CompactTextString(m) }
 func (*ProtobufFchownEvent) ProtoMessage()               {}
-func (*ProtobufFchownEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (*ProtobufFchownEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
 
 func (m *ProtobufFchownEvent) GetFd() uint64 {
 	if m != nil {
@@ -371,7 +460,7 @@ type ProtobufFchownatEvent struct {
 func (m *ProtobufFchownatEvent) Reset()                    { *m = ProtobufFchownatEvent{} }
 func (m *ProtobufFchownatEvent) String() string            { return proto.CompactTextString(m) }
 func (*ProtobufFchownatEvent) ProtoMessage()               {}
-func (*ProtobufFchownatEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+func (*ProtobufFchownatEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
 
 func (m *ProtobufFchownatEvent) GetDfd() int64 {
 	if m != nil {
@@ -408,6 +497,38 @@ func (m *ProtobufFchownatEvent) GetFlag() int64 {
 	return 0
 }
 
+type ProtobufFcntlEvent struct {
+	Fd  uint64 `protobuf:"varint,1,opt,name=fd" json:"fd,omitempty"`
+	Cmd uint64 `protobuf:"varint,2,opt,name=cmd" json:"cmd,omitempty"`
+	Arg uint64 `protobuf:"varint,3,opt,name=arg" json:"arg,omitempty"`
+}
+
+func (m *ProtobufFcntlEvent) Reset()                    { *m = ProtobufFcntlEvent{} }
+func (m *ProtobufFcntlEvent) String() string            { return proto.CompactTextString(m) }
+func (*ProtobufFcntlEvent) ProtoMessage()               {}
+func (*ProtobufFcntlEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *ProtobufFcntlEvent) GetFd() uint64 {
+	if m != nil {
+		return m.Fd
+	}
+	return 0
+}
+
+func (m *ProtobufFcntlEvent) GetCmd() uint64 {
+	if m != nil {
+		return m.Cmd
+	}
+	return 0
+}
+
+func (m *ProtobufFcntlEvent) GetArg() uint64 {
+	if m != nil {
+		return m.Arg
+	}
+	return 0
+}
+
 type ProtobufMkdirEvent struct {
 	Pathname []byte `protobuf:"bytes,1,opt,name=pathname,proto3" json:"pathname,omitempty"`
 	Mode     uint64 `protobuf:"varint,2,opt,name=mode" json:"mode,omitempty"`
@@ -416,7 +537,7 @@ type ProtobufMkdirEvent struct {
 func (m *ProtobufMkdirEvent) Reset()                    { *m = ProtobufMkdirEvent{} }
 func (m *ProtobufMkdirEvent) String() string            { return proto.CompactTextString(m) }
 func (*ProtobufMkdirEvent) ProtoMessage()               {}
-func (*ProtobufMkdirEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+func (*ProtobufMkdirEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
 
 func (m *ProtobufMkdirEvent) GetPathname() []byte {
 	if m != nil {
@@ -441,7 +562,7 @@ type ProtobufMkdiratEvent struct {
 func (m *ProtobufMkdiratEvent) Reset()                    { *m = ProtobufMkdiratEvent{} }
 func (m *ProtobufMkdiratEvent) String() string            { return proto.CompactTextString(m) }
 func (*ProtobufMkdiratEvent) ProtoMessage()               {}
-func (*ProtobufMkdiratEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+func (*ProtobufMkdiratEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
 
 func (m *ProtobufMkdiratEvent) GetDfd() int64 {
 	if m != nil {
@@ -473,7 +594,7 @@ type ProtobufOpenEvent struct {
 func (m *ProtobufOpenEvent) Reset()                    { *m = ProtobufOpenEvent{} }
 func (m *ProtobufOpenEvent) String() string            { return proto.CompactTextString(m) }
 func (*ProtobufOpenEvent) ProtoMessage()               {}
-func (*ProtobufOpenEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+func (*ProtobufOpenEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
 
 func (m *ProtobufOpenEvent) GetFilename() []byte {
 	if m != nil {
@@ -505,7 +626,7 @@ type ProtobufReadEvent struct {
 func (m *ProtobufReadEvent) Reset()                    { *m = ProtobufReadEvent{} }
 func (m *ProtobufReadEvent) String() string            { return proto.CompactTextString(m) }
 func (*ProtobufReadEvent) ProtoMessage()               {}
-func (*ProtobufReadEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+func (*ProtobufReadEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
 
 func (m *ProtobufReadEvent) GetFd() uint64 {
 	if m != nil {
@@ -537,7 +658,7 @@ type ProtobufWriteEvent struct {
 func (m *ProtobufWriteEvent) Reset()                    { *m = ProtobufWriteEvent{} }
 func (m *ProtobufWriteEvent) String() string            { return proto.CompactTextString(m) }
 func (*ProtobufWriteEvent) ProtoMessage()               {}
-func (*ProtobufWriteEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+func (*ProtobufWriteEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
 
 func (m *ProtobufWriteEvent) GetFd() uint64 {
 	if m != nil {
@@ -566,7 +687,7 @@ type Empty struct {
 func (m *Empty) Reset()                    { *m = Empty{} }
 func (m *Empty) String() string            { return proto.CompactTextString(m) }
 func (*Empty) ProtoMessage()               {}
-func (*Empty) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+func (*Empty) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
 
 type Metric struct {
 	Count          uint64                  `protobuf:"varint,1,opt,name=Count" json:"Count,omitempty"`
@@ -576,21 +697,29 @@ type Metric struct {
 	ChmodEvent     *ProtobufChmodEvent     `protobuf:"bytes,5,opt,name=ChmodEvent" json:"ChmodEvent,omitempty"`
 	ChownEvent     *ProtobufChownEvent     `protobuf:"bytes,6,opt,name=ChownEvent" json:"ChownEvent,omitempty"`
 	CloseEvent     *ProtobufCloseEvent     `protobuf:"bytes,7,opt,name=CloseEvent" json:"CloseEvent,omitempty"`
+	DupEvent       *ProtobufDupEvent       `protobuf:"bytes,17,opt,name=DupEvent" json:"DupEvent,omitempty"`
+	Dup2Event      *ProtobufDup2Event      `protobuf:"bytes,18,opt,name=Dup2Event" json:"Dup2Event,omitempty"`
+	Dup3Event      *ProtobufDup3Event      `protobuf:"bytes,19,opt,name=Dup3Event" json:"Dup3Event,omitempty"`
 	FchmodEvent    *ProtobufFchmodEvent    `protobuf:"bytes,8,opt,name=FchmodEvent" json:"FchmodEvent,omitempty"`
 	FchmodatEvent  *ProtobufFchmodatEvent  `protobuf:"bytes,9,opt,name=FchmodatEvent" json:"FchmodatEvent,omitempty"`
 	FchownEvent    *ProtobufFchownEvent    `protobuf:"bytes,10,opt,name=FchownEvent" json:"FchownEvent,omitempty"`
 	FchownatEvent  *ProtobufFchownatEvent  `protobuf:"bytes,11,opt,name=FchownatEvent" json:"FchownatEvent,omitempty"`
+	FcntlEvent     *ProtobufFcntlEvent     `protobuf:"bytes,20,opt,name=FcntlEvent" json:"FcntlEvent,omitempty"`
 	MkdirEvent     *ProtobufMkdirEvent     `protobuf:"bytes,12,opt,name=MkdirEvent" json:"MkdirEvent,omitempty"`
 	MkdiratEvent   *ProtobufMkdiratEvent   `protobuf:"bytes,13,opt,name=MkdiratEvent" json:"MkdiratEvent,omitempty"`
 	OpenEvent      *ProtobufOpenEvent      `protobuf:"bytes,14,opt,name=OpenEvent" json:"OpenEvent,omitempty"`
 	ReadEvent      *ProtobufReadEvent      `protobuf:"bytes,15,opt,name=ReadEvent" json:"ReadEvent,omitempty"`
 	WriteEvent     *ProtobufWriteEvent     `protobuf:"bytes,16,opt,name=WriteEvent" json:"WriteEvent,omitempty"`
+	Process        *ProtobufProcess        `protobuf:"bytes,1000,opt,name=Process" json:"Process,omitempty"`
+	Format         string                  `protobuf:"bytes,1001,opt,name=Format" json:"Format,omitempty"`
+	Encoded        []byte                  `protobuf:"bytes,1002,opt,name=Encoded,proto3" json:"Encoded,omitempty"`
+	Window         *ProtobufWindowSummary  `protobuf:"bytes,1003,opt,name=Window" json:"Window,omitempty"`
 }
 
 func (m *Metric) Reset()                    { *m = Metric{} }
 func (m *Metric) String() string            { return proto.CompactTextString(m) }
 func (*Metric) ProtoMessage()               {}
-func (*Metric) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+func (*Metric) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
 
 func (m *Metric) GetCount() uint64 {
 	if m != nil {
@@ -641,6 +770,27 @@ func (m *Metric) GetCloseEvent() *ProtobufCloseEvent {
 	return nil
 }
 
+func (m *Metric) GetDupEvent() *ProtobufDupEvent {
+	if m != nil {
+		return m.DupEvent
+	}
+	return nil
+}
+
+func (m *Metric) GetDup2Event() *ProtobufDup2Event {
+	if m != nil {
+		return m.Dup2Event
+	}
+	return nil
+}
+
+func (m *Metric) GetDup3Event() *ProtobufDup3Event {
+	if m != nil {
+		return m.Dup3Event
+	}
+	return nil
+}
+
 func (m *Metric) GetFchmodEvent() *ProtobufFchmodEvent {
 	if m != nil {
 		return m.FchmodEvent
@@ -669,6 +819,13 @@ func (m *Metric) GetFchownatEvent() *ProtobufFchownatEvent {
 	return nil
 }
 
+func (m *Metric) GetFcntlEvent() *ProtobufFcntlEvent {
+	if m != nil {
+		return m.FcntlEvent
+	}
+	return nil
+}
+
 func (m *Metric) GetMkdirEvent() *ProtobufMkdirEvent {
 	if m != nil {
 		return m.MkdirEvent
@@ -704,6 +861,330 @@ func (m *Metric) GetWriteEvent() *ProtobufWriteEvent {
 	return nil
 }
 
+func (m *Metric) GetProcess() *ProtobufProcess {
+	if m != nil {
+		return m.Process
+	}
+	return nil
+}
+
+func (m *Metric) GetFormat() string {
+	if m != nil {
+		return m.Format
+	}
+	return ""
+}
+
+func (m *Metric) GetEncoded() []byte {
+	if m != nil {
+		return m.Encoded
+	}
+	return nil
+}
+
+func (m *Metric) GetWindow() *ProtobufWindowSummary {
+	if m != nil {
+		return m.Window
+	}
+	return nil
+}
+
+type ProtobufContainer struct {
+	Runtime  string `protobuf:"bytes,1,opt,name=Runtime" json:"Runtime,omitempty"`
+	Id       string `protobuf:"bytes,2,opt,name=Id" json:"Id,omitempty"`
+	Name     string `protobuf:"bytes,3,opt,name=Name" json:"Name,omitempty"`
+	Image    string `protobuf:"bytes,4,opt,name=Image" json:"Image,omitempty"`
+	PodUid   string `protobuf:"bytes,5,opt,name=PodUid" json:"PodUid,omitempty"`
+	QosClass string `protobuf:"bytes,6,opt,name=QosClass" json:"QosClass,omitempty"`
+}
+
+func (m *ProtobufContainer) Reset()                    { *m = ProtobufContainer{} }
+func (m *ProtobufContainer) String() string            { return proto.CompactTextString(m) }
+func (*ProtobufContainer) ProtoMessage()               {}
+func (*ProtobufContainer) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *ProtobufContainer) GetRuntime() string {
+	if m != nil {
+		return m.Runtime
+	}
+	return ""
+}
+
+func (m *ProtobufContainer) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ProtobufContainer) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ProtobufContainer) GetImage() string {
+	if m != nil {
+		return m.Image
+	}
+	return ""
+}
+
+func (m *ProtobufContainer) GetPodUid() string {
+	if m != nil {
+		return m.PodUid
+	}
+	return ""
+}
+
+func (m *ProtobufContainer) GetQosClass() string {
+	if m != nil {
+		return m.QosClass
+	}
+	return ""
+}
+
+type ProtobufProcess struct {
+	Comm        string             `protobuf:"bytes,1,opt,name=Comm" json:"Comm,omitempty"`
+	Exe         string             `protobuf:"bytes,2,opt,name=Exe" json:"Exe,omitempty"`
+	Ppid        int64              `protobuf:"varint,3,opt,name=Ppid" json:"Ppid,omitempty"`
+	Uid         uint32             `protobuf:"varint,4,opt,name=Uid" json:"Uid,omitempty"`
+	Gid         uint32             `protobuf:"varint,5,opt,name=Gid" json:"Gid,omitempty"`
+	Cgroup      string             `protobuf:"bytes,6,opt,name=Cgroup" json:"Cgroup,omitempty"`
+	InContainer bool               `protobuf:"varint,7,opt,name=InContainer" json:"InContainer,omitempty"`
+	Container   *ProtobufContainer `protobuf:"bytes,8,opt,name=Container" json:"Container,omitempty"`
+	Cmdline     []string           `protobuf:"bytes,9,rep,name=Cmdline" json:"Cmdline,omitempty"`
+	StartTime   int64              `protobuf:"varint,10,opt,name=StartTime" json:"StartTime,omitempty"`
+	MntNs       uint64             `protobuf:"varint,11,opt,name=MntNs" json:"MntNs,omitempty"`
+	PidNs       uint64             `protobuf:"varint,12,opt,name=PidNs" json:"PidNs,omitempty"`
+	NetNs       uint64             `protobuf:"varint,13,opt,name=NetNs" json:"NetNs,omitempty"`
+}
+
+func (m *ProtobufProcess) Reset()                    { *m = ProtobufProcess{} }
+func (m *ProtobufProcess) String() string            { return proto.CompactTextString(m) }
+func (*ProtobufProcess) ProtoMessage()               {}
+func (*ProtobufProcess) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *ProtobufProcess) GetComm() string {
+	if m != nil {
+		return m.Comm
+	}
+	return ""
+}
+
+func (m *ProtobufProcess) GetExe() string {
+	if m != nil {
+		return m.Exe
+	}
+	return ""
+}
+
+func (m *ProtobufProcess) GetPpid() int64 {
+	if m != nil {
+		return m.Ppid
+	}
+	return 0
+}
+
+func (m *ProtobufProcess) GetUid() uint32 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+func (m *ProtobufProcess) GetGid() uint32 {
+	if m != nil {
+		return m.Gid
+	}
+	return 0
+}
+
+func (m *ProtobufProcess) GetCgroup() string {
+	if m != nil {
+		return m.Cgroup
+	}
+	return ""
+}
+
+func (m *ProtobufProcess) GetInContainer() bool {
+	if m != nil {
+		return m.InContainer
+	}
+	return false
+}
+
+func (m *ProtobufProcess) GetContainer() *ProtobufContainer {
+	if m != nil {
+		return m.Container
+	}
+	return nil
+}
+
+func (m *ProtobufProcess) GetCmdline() []string {
+	if m != nil {
+		return m.Cmdline
+	}
+	return nil
+}
+
+func (m *ProtobufProcess) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *ProtobufProcess) GetMntNs() uint64 {
+	if m != nil {
+		return m.MntNs
+	}
+	return 0
+}
+
+func (m *ProtobufProcess) GetPidNs() uint64 {
+	if m != nil {
+		return m.PidNs
+	}
+	return 0
+}
+
+func (m *ProtobufProcess) GetNetNs() uint64 {
+	if m != nil {
+		return m.NetNs
+	}
+	return 0
+}
+
+type ProtobufWindowKey struct {
+	Name  string `protobuf:"bytes,1,opt,name=Name" json:"Name,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=Value" json:"Value,omitempty"`
+}
+
+func (m *ProtobufWindowKey) Reset()                    { *m = ProtobufWindowKey{} }
+func (m *ProtobufWindowKey) String() string            { return proto.CompactTextString(m) }
+func (*ProtobufWindowKey) ProtoMessage()               {}
+func (*ProtobufWindowKey) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *ProtobufWindowKey) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ProtobufWindowKey) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+type ProtobufPercentile struct {
+	Percentile float64 `protobuf:"fixed64,1,opt,name=Percentile" json:"Percentile,omitempty"`
+	Value      float64 `protobuf:"fixed64,2,opt,name=Value" json:"Value,omitempty"`
+}
+
+func (m *ProtobufPercentile) Reset()                    { *m = ProtobufPercentile{} }
+func (m *ProtobufPercentile) String() string            { return proto.CompactTextString(m) }
+func (*ProtobufPercentile) ProtoMessage()               {}
+func (*ProtobufPercentile) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *ProtobufPercentile) GetPercentile() float64 {
+	if m != nil {
+		return m.Percentile
+	}
+	return 0
+}
+
+func (m *ProtobufPercentile) GetValue() float64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+type ProtobufWindowSummary struct {
+	Start       int64                 `protobuf:"varint,1,opt,name=Start" json:"Start,omitempty"`
+	End         int64                 `protobuf:"varint,2,opt,name=End" json:"End,omitempty"`
+	Keys        []*ProtobufWindowKey  `protobuf:"bytes,3,rep,name=Keys" json:"Keys,omitempty"`
+	Count       uint64                `protobuf:"varint,4,opt,name=Count" json:"Count,omitempty"`
+	Field       string                `protobuf:"bytes,5,opt,name=Field" json:"Field,omitempty"`
+	Min         float64               `protobuf:"fixed64,6,opt,name=Min" json:"Min,omitempty"`
+	Max         float64               `protobuf:"fixed64,7,opt,name=Max" json:"Max,omitempty"`
+	Sum         float64               `protobuf:"fixed64,8,opt,name=Sum" json:"Sum,omitempty"`
+	Percentiles []*ProtobufPercentile `protobuf:"bytes,9,rep,name=Percentiles" json:"Percentiles,omitempty"`
+}
+
+func (m *ProtobufWindowSummary) Reset()                    { *m = ProtobufWindowSummary{} }
+func (m *ProtobufWindowSummary) String() string            { return proto.CompactTextString(m) }
+func (*ProtobufWindowSummary) ProtoMessage()               {}
+func (*ProtobufWindowSummary) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *ProtobufWindowSummary) GetStart() int64 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *ProtobufWindowSummary) GetEnd() int64 {
+	if m != nil {
+		return m.End
+	}
+	return 0
+}
+
+func (m *ProtobufWindowSummary) GetKeys() []*ProtobufWindowKey {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *ProtobufWindowSummary) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *ProtobufWindowSummary) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *ProtobufWindowSummary) GetMin() float64 {
+	if m != nil {
+		return m.Min
+	}
+	return 0
+}
+
+func (m *ProtobufWindowSummary) GetMax() float64 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
+func (m *ProtobufWindowSummary) GetSum() float64 {
+	if m != nil {
+		return m.Sum
+	}
+	return 0
+}
+
+func (m *ProtobufWindowSummary) GetPercentiles() []*ProtobufPercentile {
+	if m != nil {
+		return m.Percentiles
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*ProtobufCommonEvent)(nil), "tracer.ProtobufCommonEvent")
 	proto.RegisterType((*ProtobufConnectV4Event)(nil), "tracer.ProtobufConnectV4Event")
@@ -711,10 +1192,14 @@ func init() {
 	proto.RegisterType((*ProtobufChmodEvent)(nil), "tracer.ProtobufChmodEvent")
 	proto.RegisterType((*ProtobufChownEvent)(nil), "tracer.ProtobufChownEvent")
 	proto.RegisterType((*ProtobufCloseEvent)(nil), "tracer.ProtobufCloseEvent")
+	proto.RegisterType((*ProtobufDupEvent)(nil), "tracer.ProtobufDupEvent")
+	proto.RegisterType((*ProtobufDup2Event)(nil), "tracer.ProtobufDup2Event")
+	proto.RegisterType((*ProtobufDup3Event)(nil), "tracer.ProtobufDup3Event")
 	proto.RegisterType((*ProtobufFchmodEvent)(nil), "tracer.ProtobufFchmodEvent")
 	proto.RegisterType((*ProtobufFchmodatEvent)(nil), "tracer.ProtobufFchmodatEvent")
 	proto.RegisterType((*ProtobufFchownEvent)(nil), "tracer.ProtobufFchownEvent")
 	proto.RegisterType((*ProtobufFchownatEvent)(nil), "tracer.ProtobufFchownatEvent")
+	proto.RegisterType((*ProtobufFcntlEvent)(nil), "tracer.ProtobufFcntlEvent")
 	proto.RegisterType((*ProtobufMkdirEvent)(nil), "tracer.ProtobufMkdirEvent")
 	proto.RegisterType((*ProtobufMkdiratEvent)(nil), "tracer.ProtobufMkdiratEvent")
 	proto.RegisterType((*ProtobufOpenEvent)(nil), "tracer.ProtobufOpenEvent")
@@ -722,6 +1207,11 @@ func init() {
 	proto.RegisterType((*ProtobufWriteEvent)(nil), "tracer.ProtobufWriteEvent")
 	proto.RegisterType((*Empty)(nil), "tracer.Empty")
 	proto.RegisterType((*Metric)(nil), "tracer.Metric")
+	proto.RegisterType((*ProtobufContainer)(nil), "tracer.ProtobufContainer")
+	proto.RegisterType((*ProtobufProcess)(nil), "tracer.ProtobufProcess")
+	proto.RegisterType((*ProtobufWindowKey)(nil), "tracer.ProtobufWindowKey")
+	proto.RegisterType((*ProtobufPercentile)(nil), "tracer.ProtobufPercentile")
+	proto.RegisterType((*ProtobufWindowSummary)(nil), "tracer.ProtobufWindowSummary")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -833,54 +1323,90 @@ var _MetricCollector_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("event-structs-generated.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 778 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x5d, 0x6f, 0xd3, 0x3c,
-	0x14, 0x7e, 0xd3, 0xf4, 0x63, 0x3d, 0x5d, 0xbb, 0xbe, 0x79, 0xfb, 0x22, 0x33, 0x36, 0x34, 0x55,
-	0x5c, 0xf4, 0x82, 0xed, 0xa2, 0xa0, 0x21, 0x90, 0x10, 0x48, 0xdd, 0x26, 0x24, 0xd8, 0x87, 0x3c,
-	0xc4, 0xd7, 0x5d, 0x96, 0xb8, 0x5d, 0x45, 0x13, 0x57, 0x89, 0xcb, 0xc4, 0x35, 0x12, 0xff, 0x80,
-	0x9f, 0xc6, 0xff, 0x41, 0x3e, 0x4e, 0x62, 0xa7, 0x73, 0xa7, 0x09, 0xb8, 0xf3, 0x39, 0x7a, 0x9e,
-	0xc7, 0x8f, 0x8f, 0x9d, 0xa7, 0x85, 0x6d, 0xf6, 0x85, 0xc5, 0x62, 0x37, 0x15, 0xc9, 0x22, 0x10,
-	0xe9, 0xee, 0x84, 0xc5, 0x2c, 0xf1, 0x05, 0x0b, 0xf7, 0xe6, 0x09, 0x17, 0xdc, 0xab, 0x8b, 0xc4,
-	0x0f, 0x58, 0xd2, 0xff, 0xe1, 0xc0, 0x7f, 0x67, 0xb2, 0x73, 0xb1, 0x18, 0x8f, 0x78, 0x14, 0xf1,
-	0xf8, 0x50, 0xf2, 0xbc, 0x2d, 0x68, 0xbe, 0x9d, 0x46, 0x2c, 0x15, 0x7e, 0x34, 0x27, 0xce, 0x8e,
-	0x33, 0xa8, 0x52, 0xdd, 0xf0, 0xba, 0xe0, 0x9e, 0x4d, 0x43, 0x52, 0xd9, 0x71, 0x06, 0x2e, 0x95,
-	0x4b, 0xd9, 0xa1, 0x4c, 0x10, 0x57, 0x75, 0x28, 0x13, 0x9e, 0x07, 0xd5, 0x13, 0x3f, 0x62, 0xa4,
-	0xba, 0xe3, 0x0c, 0x9a, 0x14, 0xd7, 0xb2, 0xf7, 0xca, 0x4f, 0x2f, 0x49, 0x0d, 0x05, 0x71, 0xed,
-	0xf5, 0xa0, 0x76, 0x34, 0xf3, 0x27, 0x29, 0xa9, 0x63, 0x53, 0x15, 0xfd, 0xef, 0x0e, 0xdc, 0xd1,
-	0xbe, 0xe2, 0x98, 0x05, 0xe2, 0xdd, 0x63, 0x65, 0xad, 0x07, 0xb5, 0x73, 0x3f, 0x0c, 0x13, 0xb4,
-	0xd5, 0xa6, 0xaa, 0x90, 0xdd, 0x03, 0xec, 0x56, 0x54, 0xf7, 0x20, 0xef, 0x9e, 0xcf, 0x79, 0xa2,
-	0x8c, 0x49, 0xac, 0x2c, 0x10, 0x8b, 0xdd, 0x6a, 0x86, 0xcd, 0xbb, 0x27, 0x4c, 0xc4, 0x29, 0xba,
-	0x6b, 0x53, 0x55, 0x58, 0x8d, 0xec, 0x5b, 0x8c, 0x34, 0xad, 0x46, 0x9a, 0x7f, 0xcf, 0xc8, 0x01,
-	0x78, 0x85, 0x8f, 0xcb, 0x88, 0x87, 0xca, 0xc3, 0x26, 0xac, 0x8d, 0xa7, 0x33, 0x16, 0xcb, 0x49,
-	0x4b, 0x1b, 0xeb, 0xb4, 0xa8, 0xe5, 0xb4, 0x23, 0x1e, 0x32, 0x34, 0x52, 0xa5, 0xb8, 0xee, 0x7f,
-	0x32, 0x55, 0xf8, 0x55, 0x7c, 0x2b, 0x95, 0x45, 0xca, 0xf2, 0xb9, 0xe2, 0x5a, 0x3a, 0x9c, 0x24,
-	0x7c, 0x31, 0xcf, 0x4f, 0x83, 0x45, 0xff, 0x81, 0xa1, 0x3d, 0xe3, 0x29, 0x53, 0xda, 0x1d, 0xa8,
-	0x8c, 0xc3, 0xec, 0x09, 0x55, 0xc6, 0x61, 0xff, 0xa9, 0x7e, 0x70, 0x47, 0x81, 0x3e, 0xc8, 0x12,
-	0xcc, 0x6a, 0xfe, 0x23, 0xfc, 0x5f, 0xa6, 0xfa, 0x42, 0x91, 0xbb, 0xe0, 0x86, 0x19, 0xdb, 0xa5,
-	0x72, 0x59, 0x3a, 0x51, 0x65, 0xc5, 0x5c, 0x5c, 0x43, 0xfa, 0xb4, 0xe4, 0xaa, 0x18, 0x8c, 0xc5,
-	0xd5, 0x2d, 0x87, 0xf1, 0xcd, 0x29, 0x99, 0xe5, 0x57, 0xf1, 0x6f, 0x9b, 0xc5, 0x1d, 0x5d, 0xdb,
-	0x8e, 0x55, 0x63, 0x47, 0x89, 0x1c, 0xcf, 0xfc, 0x09, 0xbe, 0x1a, 0x97, 0xe2, 0xda, 0x7c, 0x34,
-	0xc7, 0x9f, 0xc3, 0x69, 0x52, 0x5c, 0xf7, 0xdc, 0x17, 0x97, 0xe6, 0x75, 0xe7, 0xb5, 0x75, 0xee,
-	0x1f, 0xa0, 0x57, 0x52, 0xb9, 0xf1, 0x24, 0x85, 0x72, 0x65, 0x85, 0xb2, 0x5b, 0xba, 0xd1, 0x7f,
-	0x73, 0xe5, 0xd3, 0x39, 0xbb, 0xc5, 0x6b, 0xec, 0x41, 0x6d, 0x8c, 0x69, 0xa1, 0xb2, 0x47, 0x15,
-	0x56, 0xe9, 0xd7, 0x5a, 0x9a, 0x32, 0x7f, 0xc5, 0x2b, 0xeb, 0x82, 0x7b, 0xb1, 0x18, 0x67, 0x56,
-	0xe5, 0x52, 0x6e, 0x10, 0xf0, 0x45, 0x9c, 0x47, 0x99, 0x2a, 0xfa, 0x6f, 0xf4, 0x1c, 0xdf, 0x27,
-	0x53, 0xc1, 0xfe, 0x4c, 0xad, 0x01, 0xb5, 0xc3, 0x68, 0x2e, 0xbe, 0xf6, 0x7f, 0x36, 0xa0, 0x7e,
-	0xcc, 0x44, 0x32, 0x0d, 0x24, 0x72, 0x84, 0x48, 0x25, 0xa7, 0x0a, 0xef, 0x39, 0xb4, 0x8c, 0x54,
-	0x46, 0xe5, 0xd6, 0xf0, 0xde, 0x9e, 0x0a, 0xef, 0x3d, 0x4b, 0x70, 0x53, 0x13, 0xef, 0x1d, 0x41,
-	0xa7, 0x1c, 0x9e, 0xe8, 0xa3, 0x35, 0xbc, 0x7f, 0x5d, 0xc1, 0x44, 0xd1, 0x25, 0x96, 0xa9, 0xa3,
-	0xb2, 0x0f, 0x5f, 0xde, 0x0d, 0x3a, 0xfb, 0x4b, 0x3a, 0x59, 0x62, 0x3e, 0x03, 0xd0, 0xd9, 0x85,
-	0x0f, 0xb5, 0x35, 0xdc, 0xbc, 0xa6, 0x51, 0x20, 0xa8, 0x81, 0x56, 0xdc, 0xfc, 0xc3, 0xc4, 0x1f,
-	0x0b, 0x2b, 0x37, 0x47, 0x50, 0x03, 0x8d, 0xdc, 0x22, 0x91, 0x48, 0x63, 0x05, 0xb7, 0x40, 0x50,
-	0x03, 0x2d, 0xaf, 0xc0, 0xc8, 0x29, 0xb2, 0x66, 0xbf, 0x02, 0x03, 0x42, 0x4d, 0xbc, 0x37, 0x82,
-	0x76, 0x29, 0xab, 0x48, 0x13, 0x05, 0xb6, 0xed, 0x02, 0x19, 0x88, 0x96, 0x39, 0x99, 0x87, 0xe2,
-	0xf0, 0xb0, 0xd2, 0x43, 0x71, 0x7a, 0x13, 0x9f, 0x79, 0xd0, 0x11, 0x44, 0x5a, 0x2b, 0x3d, 0x68,
-	0x10, 0x2d, 0x73, 0xe4, 0x0c, 0x75, 0x84, 0x90, 0x75, 0xfb, 0x0c, 0x35, 0x82, 0x1a, 0x68, 0xef,
-	0x25, 0xac, 0x9b, 0xc1, 0x41, 0xda, 0xc8, 0xde, 0xb2, 0xb2, 0xf3, 0xed, 0x4b, 0x0c, 0xef, 0x09,
-	0x34, 0x8b, 0x80, 0x20, 0x1d, 0xa4, 0xdf, 0x5d, 0xa6, 0x17, 0x00, 0xaa, 0xb1, 0x92, 0x58, 0x7c,
-	0xfe, 0x64, 0xc3, 0x4e, 0x2c, 0x00, 0x54, 0x63, 0xe5, 0x79, 0xf5, 0xa7, 0x4e, 0xba, 0xf6, 0xf3,
-	0x6a, 0x04, 0x35, 0xd0, 0xc3, 0x17, 0xb0, 0xa1, 0x3e, 0xeb, 0x11, 0x9f, 0xcd, 0x58, 0x20, 0x78,
-	0xe2, 0x3d, 0x84, 0xc6, 0x59, 0xc2, 0x03, 0x96, 0xa6, 0x5e, 0x27, 0x57, 0x51, 0x98, 0xcd, 0x76,
-	0x5e, 0xab, 0x50, 0xf8, 0x67, 0xe0, 0x5c, 0xd4, 0xf1, 0x5f, 0xda, 0xa3, 0x5f, 0x01, 0x00, 0x00,
-	0xff, 0xff, 0xb7, 0x56, 0x82, 0x5d, 0xc6, 0x09, 0x00, 0x00,
+	// 1350 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0xcd, 0x6e, 0xdc, 0x36,
+	0x10, 0xae, 0x56, 0xeb, 0x5d, 0x2f, 0x37, 0xeb, 0x38, 0x8c, 0x93, 0x30, 0x6e, 0x12, 0x18, 0x42,
+	0x0f, 0x46, 0xd1, 0xe4, 0xe0, 0x04, 0x29, 0x5a, 0x34, 0x48, 0x81, 0xb5, 0xdd, 0xb8, 0xa9, 0x9d,
+	0x2d, 0xdd, 0x24, 0x6d, 0x6f, 0x8a, 0xc8, 0x75, 0x84, 0x4a, 0xd4, 0x42, 0xa2, 0xea, 0xf8, 0x5c,
+	0xa0, 0xc7, 0xbe, 0x40, 0x9f, 0xa2, 0x8f, 0x53, 0xa0, 0x0f, 0xd0, 0x9f, 0x97, 0x28, 0x86, 0xa4,
+	0x48, 0xca, 0xd1, 0x06, 0xe9, 0xcf, 0x6d, 0xbe, 0xd1, 0x37, 0xa3, 0x4f, 0x9c, 0xe1, 0x90, 0x42,
+	0x37, 0xf9, 0xf7, 0x5c, 0xc8, 0xdb, 0x95, 0x2c, 0xeb, 0x44, 0x56, 0xb7, 0x4f, 0xb8, 0xe0, 0x65,
+	0x2c, 0x39, 0xbb, 0xb3, 0x28, 0x0b, 0x59, 0xe0, 0x81, 0x2c, 0xe3, 0x84, 0x97, 0xd1, 0x2f, 0x01,
+	0xba, 0x3c, 0x03, 0xcf, 0x8b, 0x7a, 0x3e, 0x2d, 0xf2, 0xbc, 0x10, 0x7b, 0x10, 0x87, 0x6f, 0xa0,
+	0xd1, 0x57, 0x69, 0xce, 0x2b, 0x19, 0xe7, 0x0b, 0x12, 0x6c, 0x05, 0xdb, 0x7d, 0xea, 0x1c, 0x78,
+	0x1d, 0x85, 0xb3, 0x94, 0x91, 0xde, 0x56, 0xb0, 0x1d, 0x52, 0x30, 0xc1, 0x43, 0xb9, 0x24, 0xa1,
+	0xf6, 0x50, 0x2e, 0x31, 0x46, 0xfd, 0xa3, 0x38, 0xe7, 0xa4, 0xbf, 0x15, 0x6c, 0x8f, 0xa8, 0xb2,
+	0xc1, 0xf7, 0x28, 0xae, 0x5e, 0x92, 0x15, 0x95, 0x50, 0xd9, 0x78, 0x03, 0xad, 0xec, 0x67, 0xf1,
+	0x49, 0x45, 0x06, 0xca, 0xa9, 0x01, 0xde, 0x44, 0xab, 0xbb, 0x75, 0x19, 0xcb, 0xb4, 0x10, 0x64,
+	0xa8, 0x1e, 0x58, 0x1c, 0xfd, 0x18, 0xa0, 0xab, 0x4e, 0xb3, 0x10, 0x3c, 0x91, 0xcf, 0xee, 0x69,
+	0xd9, 0x1b, 0x68, 0xe5, 0x38, 0x66, 0xac, 0x54, 0x92, 0x27, 0x54, 0x03, 0xf0, 0xee, 0x2a, 0x6f,
+	0x4f, 0x7b, 0x77, 0x1b, 0xef, 0xf1, 0xa2, 0x28, 0xb5, 0xe8, 0x09, 0xd5, 0x40, 0x71, 0x95, 0xb7,
+	0x6f, 0xb8, 0x8d, 0xf7, 0x88, 0x4b, 0x51, 0x29, 0xe5, 0x13, 0xaa, 0x41, 0xa7, 0x90, 0xfb, 0x1d,
+	0x42, 0x46, 0x9d, 0x42, 0x46, 0xff, 0x9f, 0x90, 0x5d, 0x84, 0xad, 0x8e, 0x97, 0x79, 0xc1, 0xb4,
+	0x86, 0x4d, 0xb4, 0x3a, 0x4f, 0x33, 0x2e, 0xa0, 0x0a, 0x20, 0xe3, 0x02, 0xb5, 0x18, 0x2a, 0x91,
+	0x17, 0x8c, 0x2b, 0x21, 0x7d, 0xaa, 0xec, 0xe8, 0x5b, 0x3f, 0x4b, 0x71, 0x2a, 0xde, 0x2a, 0x4b,
+	0x5d, 0xf1, 0x66, 0x5d, 0x95, 0x0d, 0x0a, 0x4f, 0xca, 0xa2, 0x5e, 0x34, 0x5f, 0xa3, 0x40, 0xf4,
+	0x9e, 0x97, 0x3b, 0x2b, 0x2a, 0xae, 0x73, 0xaf, 0xa1, 0xde, 0x9c, 0x99, 0xf6, 0xea, 0xcd, 0x59,
+	0xf4, 0x3e, 0x5a, 0x6f, 0x58, 0xbb, 0xf5, 0x42, 0x73, 0xae, 0xa2, 0xc1, 0x3c, 0xcd, 0x18, 0xaf,
+	0x0c, 0xcf, 0xa0, 0xe8, 0x21, 0xba, 0xe4, 0x71, 0x77, 0xec, 0xb2, 0x17, 0x19, 0xb3, 0x39, 0x35,
+	0x00, 0xaf, 0xe0, 0xa7, 0x73, 0x66, 0xbe, 0x56, 0x83, 0xe8, 0x69, 0x2b, 0xc1, 0xdd, 0x7f, 0x9c,
+	0x00, 0xbc, 0x73, 0xd5, 0xb9, 0xba, 0xeb, 0x35, 0x88, 0x3e, 0x72, 0x1b, 0x6a, 0x3f, 0x71, 0xc5,
+	0x38, 0xf7, 0xa9, 0x9d, 0x05, 0xf8, 0x06, 0x5d, 0x69, 0x87, 0xc6, 0x52, 0x07, 0xaf, 0xa3, 0xb0,
+	0xd1, 0x14, 0x52, 0x30, 0x5b, 0x55, 0xe9, 0x2d, 0xa9, 0x6d, 0xe8, 0xa5, 0x7e, 0xd2, 0x52, 0x65,
+	0x8b, 0xdb, 0xa1, 0xea, 0x2d, 0x0b, 0xfa, 0x43, 0xd0, 0x12, 0x5b, 0x9c, 0x8a, 0x7f, 0x2d, 0x56,
+	0xbd, 0x31, 0xec, 0x7a, 0x63, 0xdf, 0x7b, 0x23, 0x30, 0x61, 0x85, 0x55, 0xe7, 0x87, 0x54, 0xd9,
+	0xd1, 0x23, 0xd7, 0x56, 0xfb, 0x89, 0x90, 0x59, 0xf7, 0x57, 0xad, 0xa3, 0x30, 0xc9, 0x9b, 0xe2,
+	0x81, 0x09, 0x9e, 0xb8, 0x3c, 0x31, 0x2b, 0x04, 0xa6, 0xbf, 0x85, 0x0e, 0xbf, 0x63, 0x69, 0x69,
+	0x9b, 0x7f, 0x11, 0xcb, 0x97, 0x7e, 0xf3, 0x37, 0xb8, 0xb3, 0x82, 0x5f, 0xa3, 0x8d, 0x56, 0x96,
+	0x37, 0xae, 0x89, 0xcd, 0xdc, 0x5b, 0x92, 0x39, 0x6c, 0xf5, 0x86, 0xed, 0xd6, 0x27, 0x0b, 0xfe,
+	0x16, 0x7b, 0xd3, 0x76, 0x67, 0xcf, 0xeb, 0xce, 0xce, 0xd4, 0x8f, 0x5d, 0x6a, 0xca, 0x63, 0xb6,
+	0x74, 0x0d, 0x5f, 0xd4, 0x73, 0x23, 0x15, 0x4c, 0x78, 0x41, 0x52, 0xd4, 0xa2, 0x19, 0xfa, 0x1a,
+	0x44, 0x5f, 0xb8, 0x75, 0x7c, 0x5e, 0xa6, 0x92, 0xff, 0xb7, 0x6c, 0x43, 0xb4, 0xb2, 0x97, 0x2f,
+	0xe4, 0x59, 0xf4, 0x2b, 0x42, 0x83, 0x43, 0x2e, 0xcb, 0x34, 0x01, 0xe6, 0x54, 0x31, 0xcd, 0x16,
+	0x55, 0x00, 0x3f, 0x40, 0x63, 0xef, 0xfc, 0x52, 0x99, 0xc7, 0x3b, 0xef, 0xde, 0xd1, 0xc7, 0xdc,
+	0x9d, 0x8e, 0x23, 0x8e, 0xfa, 0x7c, 0xbc, 0x8f, 0xd6, 0xda, 0x47, 0x89, 0xd2, 0x31, 0xde, 0xb9,
+	0xf5, 0x7a, 0x06, 0x9f, 0x45, 0xcf, 0x45, 0xf9, 0x79, 0xf4, 0x49, 0x40, 0xfa, 0x6f, 0xce, 0x73,
+	0xff, 0x5c, 0x1e, 0x8d, 0xf1, 0xc7, 0x08, 0xb9, 0x49, 0xae, 0x5a, 0x7e, 0xbc, 0xb3, 0xf9, 0x5a,
+	0x0e, 0xcb, 0xa0, 0x1e, 0x5b, 0xc7, 0x36, 0x5b, 0x9c, 0x0c, 0x96, 0xc5, 0x36, 0x0c, 0xea, 0xb1,
+	0x55, 0xac, 0x9d, 0xcf, 0x64, 0xb8, 0x24, 0xd6, 0x32, 0xa8, 0xc7, 0xc6, 0xf7, 0xd0, 0x6a, 0x33,
+	0xb5, 0xc9, 0x25, 0x15, 0x49, 0xce, 0x47, 0x36, 0xcf, 0xa9, 0x65, 0xe2, 0x0f, 0xd1, 0xc8, 0xce,
+	0x6f, 0x82, 0x55, 0xd8, 0xf5, 0x8e, 0x30, 0x4d, 0xa0, 0x8e, 0x6b, 0x02, 0xf5, 0xdc, 0x26, 0x97,
+	0x97, 0x06, 0xde, 0x75, 0x81, 0xda, 0x84, 0x56, 0xf1, 0x26, 0x33, 0x59, 0xed, 0x6e, 0x15, 0x8f,
+	0x42, 0x7d, 0x3e, 0x9e, 0xa2, 0x49, 0x6b, 0x3a, 0x93, 0x91, 0x4a, 0x70, 0xb3, 0x3b, 0x81, 0x21,
+	0xd1, 0x76, 0x8c, 0xd1, 0x60, 0x8b, 0x84, 0x96, 0x6a, 0xb0, 0x55, 0xf2, 0xf9, 0x46, 0x83, 0x1b,
+	0xba, 0x64, 0xbc, 0x54, 0x83, 0x23, 0xd1, 0x76, 0x0c, 0xd4, 0xda, 0x0d, 0x4d, 0xb2, 0xd1, 0x5d,
+	0x6b, 0xc7, 0xa0, 0x1e, 0x1b, 0x62, 0xdd, 0x98, 0x24, 0x17, 0xba, 0x63, 0x1d, 0x83, 0x7a, 0x6c,
+	0xfc, 0x29, 0xba, 0xe0, 0x0f, 0x47, 0x32, 0x51, 0xd1, 0x37, 0x3a, 0xa3, 0x1b, 0xe9, 0xad, 0x08,
+	0x28, 0xbd, 0x1d, 0x82, 0x64, 0xad, 0xbb, 0xf4, 0x96, 0x40, 0x1d, 0x17, 0x02, 0xed, 0x88, 0x23,
+	0x17, 0xbb, 0x03, 0x2d, 0x81, 0x3a, 0x2e, 0x7c, 0xaf, 0x1b, 0x67, 0x64, 0xbd, 0xfb, 0x7b, 0x1d,
+	0x83, 0x7a, 0x6c, 0xbc, 0x83, 0x86, 0xb3, 0xb2, 0x48, 0x78, 0x55, 0x91, 0xdf, 0xf5, 0x8e, 0xba,
+	0x76, 0x3e, 0xd2, 0x3c, 0xa7, 0x0d, 0x11, 0x5f, 0x43, 0x83, 0xfd, 0xa2, 0xcc, 0x63, 0x49, 0xfe,
+	0x18, 0xaa, 0xbb, 0xa2, 0x81, 0xf8, 0x3a, 0x1a, 0xee, 0x89, 0xa4, 0x60, 0x9c, 0x91, 0x3f, 0x87,
+	0x6a, 0x7c, 0x36, 0x18, 0xdf, 0x47, 0x83, 0xe7, 0xa9, 0x60, 0xc5, 0x29, 0xf9, 0x6b, 0xd8, 0xdd,
+	0x0e, 0xfa, 0xf1, 0x71, 0x9d, 0xe7, 0x71, 0x79, 0x46, 0x0d, 0x3b, 0xfa, 0x39, 0x70, 0x07, 0xc0,
+	0xb4, 0x10, 0x32, 0x4e, 0x05, 0x2f, 0x31, 0x41, 0x43, 0x5a, 0x0b, 0x99, 0x9a, 0xa3, 0x65, 0x44,
+	0x1b, 0x08, 0xc3, 0xfc, 0x80, 0x99, 0x2b, 0x6c, 0xef, 0x80, 0xd9, 0x9b, 0x7e, 0xe8, 0xdd, 0xf4,
+	0x37, 0xd0, 0xca, 0x41, 0x1e, 0x9f, 0x34, 0xd7, 0x7f, 0x0d, 0xe0, 0x2e, 0x37, 0x2b, 0xd8, 0xd3,
+	0x94, 0xa9, 0x89, 0x36, 0xa2, 0x06, 0xc1, 0x39, 0xf6, 0x65, 0x51, 0x4d, 0xb3, 0xb8, 0xd2, 0xbf,
+	0x01, 0x23, 0x6a, 0x71, 0xf4, 0x5b, 0x0f, 0x5d, 0x3c, 0xb7, 0x4c, 0xf0, 0x46, 0x18, 0xde, 0x46,
+	0x98, 0xb2, 0xe1, 0x48, 0xd9, 0x7b, 0xc5, 0x8d, 0x2c, 0x30, 0x81, 0x35, 0x5b, 0xa4, 0xcc, 0x9c,
+	0x28, 0xca, 0x06, 0x16, 0xbc, 0x5e, 0x5f, 0x2c, 0xc2, 0xa7, 0xda, 0xf3, 0x99, 0x11, 0x34, 0xa1,
+	0x60, 0x82, 0xca, 0xa9, 0xbe, 0x7f, 0x68, 0x2d, 0x06, 0xe1, 0x2d, 0x34, 0x3e, 0x10, 0x76, 0x81,
+	0xd4, 0x70, 0x5c, 0xa5, 0xbe, 0x0b, 0xda, 0xcb, 0x3d, 0x5f, 0xed, 0x6e, 0x2f, 0x4b, 0xa0, 0xa3,
+	0xd6, 0x62, 0x4f, 0x73, 0x96, 0xa5, 0x82, 0x93, 0xd1, 0x56, 0x08, 0x8b, 0x6d, 0x20, 0xfc, 0x88,
+	0x1d, 0xcb, 0xb8, 0x94, 0xf0, 0xf3, 0xa5, 0xc6, 0x44, 0x48, 0x9d, 0x03, 0x96, 0xf9, 0x50, 0xc8,
+	0xa3, 0x4a, 0xed, 0xff, 0x3e, 0xd5, 0x00, 0xbc, 0xb3, 0x94, 0x1d, 0x55, 0x6a, 0x5f, 0xf6, 0xa9,
+	0x06, 0xe6, 0xd7, 0xe1, 0xa8, 0x52, 0xfb, 0xad, 0x4f, 0x35, 0x88, 0x1e, 0xb8, 0xda, 0xeb, 0x76,
+	0x78, 0xcc, 0xcf, 0x6c, 0x45, 0x83, 0x76, 0x45, 0x9f, 0xc5, 0x59, 0xdd, 0xac, 0xb0, 0x06, 0xd1,
+	0xe7, 0xee, 0xb8, 0x9f, 0xf1, 0x32, 0xe1, 0x42, 0xa6, 0x19, 0xc7, 0xb7, 0x10, 0x72, 0x48, 0x65,
+	0x09, 0xa8, 0xe7, 0x69, 0xe7, 0x0a, 0x9a, 0x5c, 0x3f, 0xf5, 0xd0, 0x95, 0xb6, 0x16, 0xd3, 0xa9,
+	0xea, 0x0f, 0x09, 0xbe, 0xd9, 0x5c, 0xa0, 0x34, 0x50, 0x15, 0x17, 0xf6, 0x2f, 0x74, 0x4f, 0x30,
+	0x7c, 0x1b, 0xf5, 0x1f, 0xf3, 0x33, 0xb8, 0x90, 0x87, 0x5d, 0x4b, 0x6f, 0x3f, 0x90, 0x2a, 0x9a,
+	0xbb, 0x49, 0xf4, 0xfd, 0x9b, 0x04, 0xfc, 0x90, 0xa6, 0x3c, 0x6b, 0x7a, 0x54, 0x03, 0x78, 0xd9,
+	0x61, 0x2a, 0x54, 0x47, 0x04, 0x14, 0x4c, 0xe5, 0x89, 0x5f, 0x91, 0xa1, 0xf1, 0xc4, 0xaf, 0xc0,
+	0x73, 0x5c, 0xe7, 0xaa, 0xf0, 0x01, 0x05, 0x13, 0x7f, 0x82, 0xc6, 0xee, 0xb3, 0x2b, 0x55, 0xdb,
+	0x8e, 0xb9, 0xe1, 0x28, 0xd4, 0xa7, 0xef, 0x3c, 0x44, 0x17, 0xf5, 0x9d, 0x67, 0x5a, 0x64, 0x19,
+	0x4f, 0x64, 0x51, 0xe2, 0x0f, 0xec, 0x2c, 0xc1, 0x6b, 0x4d, 0x1a, 0xcd, 0xd9, 0x9c, 0x34, 0x58,
+	0xdf, 0x98, 0xde, 0xd9, 0x0e, 0x5e, 0x0c, 0xd4, 0xcf, 0xfe, 0xdd, 0xbf, 0x07, 0x00, 0xec, 0xfc,
+	0xf9, 0xac, 0x0d, 0x10, 0x00, 0x00,
 }
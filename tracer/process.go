@@ -0,0 +1,313 @@
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// #include <unistd.h>
+import "C"
+
+// ProcessInfo is the metadata of a traced process, read from /proc
+type ProcessInfo struct {
+	Pid       int64     `json:"pid"`
+	Comm      string    `json:"comm"`
+	Exe       string    `json:"exe"`
+	Cmdline   []string  `json:"cmdline"`
+	Ppid      int64     `json:"ppid"`
+	UID       uint32    `json:"uid"`
+	GID       uint32    `json:"gid"`
+	StartTime time.Time `json:"start_time"`
+	Cgroup    string    `json:"cgroup"`
+	MntNs     uint64    `json:"mnt_ns"`
+	PidNs     uint64    `json:"pid_ns"`
+	NetNs     uint64    `json:"net_ns"`
+
+	// whether the process runs in another mount namespace than init or in
+	// a container cgroup
+	Container bool `json:"container"`
+
+	// container and pod of the process, nil if it's in none
+	ContainerInfo *ContainerInfo `json:"container_info,omitempty"`
+}
+
+type processEntry struct {
+	info    *ProcessInfo
+	fetched time.Time
+}
+
+// ProcessCache caches the metadata of processes for ttl, so that /proc is not
+// read for every event. Entries are dropped when the processes exit (see the
+// exit event in GetStruct) or when they expire. A nil *ProcessCache is valid
+// and caches nothing. If a container resolver is given, it resolves the names
+// and images of the containers.
+type ProcessCache struct {
+	sync.Mutex
+	ttl       time.Duration
+	entries   map[int64]processEntry
+	lastSweep time.Time
+
+	hostMntNs uint64
+	bootTime  time.Time
+	clockTick int64
+
+	resolver *ContainerResolver
+}
+
+func NewProcessCache(ttl time.Duration, resolver *ContainerResolver) *ProcessCache {
+	c := &ProcessCache{
+		ttl:       ttl,
+		resolver:  resolver,
+		entries:   make(map[int64]processEntry),
+		lastSweep: time.Now(),
+		clockTick: int64(C.sysconf(C._SC_CLK_TCK)),
+	}
+	c.hostMntNs, _ = nsInode(1, "mnt")
+	c.bootTime, _ = readBootTime()
+	return c
+}
+
+// Get returns the metadata of the process pid, or nil if it exited before it
+// was ever read.
+func (c *ProcessCache) Get(pid int64) *ProcessInfo {
+	if c == nil {
+		return nil
+	}
+
+	now := time.Now()
+	c.Lock()
+	if now.Sub(c.lastSweep) > c.ttl {
+		c.sweep(now)
+	}
+	entry, ok := c.entries[pid]
+	c.Unlock()
+	if ok && now.Sub(entry.fetched) <= c.ttl {
+		return entry.info
+	}
+
+	// /proc is read and the container resolved without the lock, the
+	// resolver may wait for the container engine
+	info, err := c.read(pid)
+	if err != nil {
+		// the process exited: the stale entry is still better than nothing
+		return entry.info
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// another goroutine read the process meanwhile, keep its entry
+	if newer, ok := c.entries[pid]; ok && newer.fetched.After(entry.fetched) {
+		return newer.info
+	}
+	c.entries[pid] = processEntry{info: info, fetched: now}
+
+	return info
+}
+
+// Delete drops the metadata of the process pid
+func (c *ProcessCache) Delete(pid int64) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.entries, pid)
+}
+
+// sweep drops the expired entries, e.g. of processes whose exit was missed
+func (c *ProcessCache) sweep(now time.Time) {
+	for pid, entry := range c.entries {
+		if now.Sub(entry.fetched) > c.ttl {
+			delete(c.entries, pid)
+		}
+	}
+	c.lastSweep = now
+}
+
+func (c *ProcessCache) read(pid int64) (*ProcessInfo, error) {
+	procDir := fmt.Sprintf("/proc/%d", pid)
+
+	comm, err := ioutil.ReadFile(procDir + "/comm")
+	if err != nil {
+		return nil, err
+	}
+	info := &ProcessInfo{
+		Pid:  pid,
+		Comm: strings.TrimSuffix(string(comm), "\n"),
+	}
+
+	// kernel threads have neither an executable nor a command line
+	info.Exe, _ = os.Readlink(procDir + "/exe")
+	if cmdline, err := ioutil.ReadFile(procDir + "/cmdline"); err == nil && len(cmdline) > 0 {
+		info.Cmdline = strings.Split(string(bytes.TrimSuffix(cmdline, []byte{0})), "\x00")
+	}
+
+	if stat, err := ioutil.ReadFile(procDir + "/stat"); err == nil {
+		var startTicks int64
+		info.Ppid, startTicks = parseStat(stat)
+		if c.clockTick > 0 && !c.bootTime.IsZero() && startTicks > 0 {
+			info.StartTime = c.bootTime.Add(time.Duration(startTicks) * time.Second / time.Duration(c.clockTick))
+		}
+	}
+
+	if status, err := ioutil.ReadFile(procDir + "/status"); err == nil {
+		info.UID, info.GID = parseStatus(string(status))
+	}
+
+	if cgroups, err := ioutil.ReadFile(procDir + "/cgroup"); err == nil {
+		lines := strings.Split(strings.TrimSpace(string(cgroups)), "\n")
+		info.Cgroup = parseCgroup(lines)
+		info.ContainerInfo = parseContainerCgroups(lines)
+		c.resolver.resolve(info.ContainerInfo)
+	}
+
+	info.MntNs, _ = nsInode(pid, "mnt")
+	info.PidNs, _ = nsInode(pid, "pid")
+	info.NetNs, _ = nsInode(pid, "net")
+	info.Container = c.hostMntNs != 0 && info.MntNs != 0 && info.MntNs != c.hostMntNs ||
+		info.ContainerInfo != nil && info.ContainerInfo.ID != ""
+
+	return info, nil
+}
+
+// parseStat returns the parent pid and the start time, in clock ticks since
+// boot, of a process from its /proc/<pid>/stat
+func parseStat(stat []byte) (ppid int64, startTicks int64) {
+	// the fields after the command name, which may contain spaces and
+	// parentheses: state ppid ... starttime (22nd field of the file)
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, 0
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) <= 19 {
+		return 0, 0
+	}
+	ppid, _ = strconv.ParseInt(fields[1], 10, 64)
+	startTicks, _ = strconv.ParseInt(fields[19], 10, 64)
+	return ppid, startTicks
+}
+
+// parseStatus returns the real uid and gid of a process from its
+// /proc/<pid>/status
+func parseStatus(status string) (uid, gid uint32) {
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		// real ids, followed by the effective, saved and fs ones
+		id, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "Uid:":
+			uid = uint32(id)
+		case "Gid:":
+			gid = uint32(id)
+		}
+	}
+	return uid, gid
+}
+
+// parseCgroup returns the cgroup of a process from the lines of
+// /proc/<pid>/cgroup: the cgroup2 one if any, the one of the first hierarchy
+// otherwise
+func parseCgroup(lines []string) string {
+	first := ""
+	for _, line := range lines {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			return parts[2]
+		}
+		if first == "" {
+			first = parts[2]
+		}
+	}
+
+	return first
+}
+
+// nsInode returns the inode identifying the namespace ns (e.g. "mnt") of the
+// process pid
+func nsInode(pid int64, ns string) (uint64, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(fmt.Sprintf("/proc/%d/ns/%s", pid, ns), &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Ino), nil
+}
+
+func readBootTime() (time.Time, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "btime" {
+			btime, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(btime, 0), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Time{}, fmt.Errorf("btime not found in /proc/stat")
+}
+
+func (p *ProcessInfo) Proto() *ProtobufProcess {
+	if p == nil {
+		return nil
+	}
+	pp := &ProtobufProcess{
+		Comm:        p.Comm,
+		Exe:         p.Exe,
+		Ppid:        p.Ppid,
+		Uid:         p.UID,
+		Gid:         p.GID,
+		Cgroup:      p.Cgroup,
+		InContainer: p.Container,
+		Cmdline:     p.Cmdline,
+		MntNs:       p.MntNs,
+		PidNs:       p.PidNs,
+		NetNs:       p.NetNs,
+	}
+	if !p.StartTime.IsZero() {
+		pp.StartTime = p.StartTime.UnixNano()
+	}
+	if c := p.ContainerInfo; c != nil {
+		pp.Container = &ProtobufContainer{
+			Runtime:  c.Runtime,
+			Id:       c.ID,
+			Name:     c.Name,
+			Image:    c.Image,
+			PodUid:   c.PodUID,
+			QosClass: c.QoSClass,
+		}
+	}
+	return pp
+}
@@ -0,0 +1,102 @@
+package tracer
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseStat(t *testing.T) {
+	// the fields after the command name, up to starttime
+	const rest = " S 1 1234 1234 0 -1 4194560 1011 0 0 0 3 2 0 0 20 0 1 0 4567 123456 789"
+
+	tests := []struct {
+		stat       string
+		ppid       int64
+		startTicks int64
+	}{
+		{"1234 (nginx)" + rest, 1, 4567},
+		{"1234 (my app)" + rest, 1, 4567},
+		// the last parenthesis ends the command name
+		{"1234 (a) b (c))" + rest, 1, 4567},
+		{"1234 ((sd-pam))" + rest, 1, 4567},
+		{"1234 () 1 2 3)" + rest, 1, 4567},
+		// truncated or malformed
+		{"1234 (nginx) S 1 1234", 0, 0},
+		{"1234 nginx" + rest, 0, 0},
+		{"", 0, 0},
+	}
+
+	for _, tt := range tests {
+		ppid, startTicks := parseStat([]byte(tt.stat))
+		if ppid != tt.ppid || startTicks != tt.startTicks {
+			t.Errorf("%q: got ppid %d and start %d, want %d and %d", tt.stat, ppid, startTicks, tt.ppid, tt.startTicks)
+		}
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		uid    uint32
+		gid    uint32
+	}{
+		{"Name:\tnginx\nUmask:\t0022\nState:\tS (sleeping)\nUid:\t101\t102\t103\t104\nGid:\t201\t202\t203\t204\n", 101, 201},
+		// the name may contain spaces and parentheses
+		{"Name:\tmy (app) Uid: 5\nUid:\t0\t0\t0\t0\nGid:\t7\t7\t7\t7\n", 0, 7},
+		{"Name:\tnginx\n", 0, 0},
+		{"Uid:\t-1\t0\t0\t0\nGid:\tx\n", 0, 0},
+		{"", 0, 0},
+	}
+
+	for _, tt := range tests {
+		uid, gid := parseStatus(tt.status)
+		if uid != tt.uid || gid != tt.gid {
+			t.Errorf("%q: got uid %d and gid %d, want %d and %d", tt.status, uid, gid, tt.uid, tt.gid)
+		}
+	}
+}
+
+func TestProcessCacheGet(t *testing.T) {
+	c := NewProcessCache(time.Minute, nil)
+	pid := int64(os.Getpid())
+
+	// concurrent lookups all get the same entry
+	infos := make([]*ProcessInfo, 8)
+	var wg sync.WaitGroup
+	for i := range infos {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			infos[i] = c.Get(pid)
+		}(i)
+	}
+	wg.Wait()
+
+	info := c.Get(pid)
+	if info == nil {
+		t.Fatalf("no metadata for the test process")
+	}
+	for _, other := range infos {
+		if other == nil || !reflect.DeepEqual(other, info) {
+			t.Errorf("got %+v, want %+v", other, info)
+		}
+	}
+	if info.Ppid != int64(os.Getppid()) || info.UID != uint32(os.Getuid()) || info.GID != uint32(os.Getgid()) {
+		t.Errorf("unexpected ids in %+v", info)
+	}
+	if len(info.Cmdline) == 0 || info.Cmdline[0] != os.Args[0] {
+		t.Errorf("got command line %q, want %q", info.Cmdline, os.Args)
+	}
+	if info.StartTime.After(time.Now()) || time.Since(info.StartTime) > time.Hour {
+		t.Errorf("unexpected start time %v", info.StartTime)
+	}
+
+	pp := info.Proto()
+	if !reflect.DeepEqual(pp.Cmdline, info.Cmdline) || pp.StartTime != info.StartTime.UnixNano() ||
+		pp.MntNs != info.MntNs || pp.PidNs != info.PidNs || pp.NetNs != info.NetNs {
+		t.Errorf("got %v for %+v", pp, info)
+	}
+}
@@ -0,0 +1,186 @@
+package tracer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resolution of socket file descriptors (whose /proc/<pid>/fd link is
+// "socket:[inode]") to their protocol and addresses, from the socket tables
+// of the network namespace of the process in /proc/<pid>/net.
+
+const socketPathPrefix = "socket:["
+
+func isSocketPath(path string) bool {
+	return strings.HasPrefix(path, socketPathPrefix)
+}
+
+// states of the TCP sockets in /proc/net/tcp, see include/net/tcp_states.h
+const (
+	tcpClose  = 0x07
+	tcpListen = 0x0a
+)
+
+// socketRetryInterval is how long the description of a socket that may still
+// change is cached, to avoid reading the socket tables on every read and write
+const socketRetryInterval = time.Second
+
+// ResolveSocket returns the protocol and addresses of the socket behind the
+// file descriptor fd of the process pid, e.g. "tcp 10.0.0.2:5432->10.0.0.9:41000",
+// or info.Path if it can't be found. The description is cached in the map,
+// for socketRetryInterval if it may still change.
+func (f *FdMap) ResolveSocket(pid, fd uint32, info *FdInfo) string {
+	return f.resolveSocket(fmt.Sprintf("/proc/%d/net", pid), pid, fd, info, time.Now())
+}
+
+func (f *FdMap) resolveSocket(netDir string, pid, fd uint32, info *FdInfo, now time.Time) string {
+	if info.Socket != "" && (info.SocketRetry == 0 || now.UnixNano() < info.SocketRetry) {
+		return info.Socket
+	}
+
+	name, final := lookupSocket(netDir, info.Ino)
+	if name == "" {
+		name = info.Path
+	}
+
+	resolved := *info
+	resolved.Socket = name
+	resolved.SocketRetry = 0
+	if !final {
+		resolved.SocketRetry = now.Add(socketRetryInterval).UnixNano()
+	}
+	f.Put(pid, fd, resolved)
+
+	return name
+}
+
+// lookupSocket looks for the socket inode in the socket tables of a network
+// namespace, in netDir (e.g. /proc/<pid>/net). It returns its description and
+// whether it's final, or "" and false if it wasn't found. Descriptions can
+// still change for TCP sockets not connected yet and UDP sockets that aren't
+// connected, which may be later.
+func lookupSocket(netDir string, inode uint64) (name string, final bool) {
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		local, remote, state, ok := findInetSocket(filepath.Join(netDir, proto), inode)
+		if !ok {
+			continue
+		}
+		proto = strings.TrimSuffix(proto, "6")
+		switch {
+		case proto == "tcp" && state == tcpListen:
+			return fmt.Sprintf("%s %s (listen)", proto, local), true
+		case proto == "tcp" && state == tcpClose:
+			return fmt.Sprintf("%s %s", proto, local), false
+		case strings.HasSuffix(remote, ":0"):
+			// unconnected UDP socket
+			return fmt.Sprintf("%s %s", proto, local), false
+		}
+		return fmt.Sprintf("%s %s->%s", proto, local, remote), true
+	}
+
+	if path, ok := findUnixSocket(filepath.Join(netDir, "unix"), inode); ok {
+		if path == "" {
+			return "unix", true
+		}
+		return "unix " + path, true
+	}
+
+	return "", false
+}
+
+// findInetSocket returns the addresses and state of a socket in a table like
+// /proc/net/tcp, whose lines look like:
+//
+//	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//	 0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 21764 ...
+func findInetSocket(path string, inode uint64) (local, remote string, state uint64, ok bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", 0, false
+	}
+	defer f.Close()
+
+	want := strconv.FormatUint(inode, 10)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[9] != want {
+			continue
+		}
+		local, err := parseInetAddr(fields[1])
+		if err != nil {
+			return "", "", 0, false
+		}
+		remote, err := parseInetAddr(fields[2])
+		if err != nil {
+			return "", "", 0, false
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return "", "", 0, false
+		}
+		return local, remote, state, true
+	}
+
+	return "", "", 0, false
+}
+
+// parseInetAddr parses an address of /proc/net/tcp{,6}, e.g. "0100007F:0050"
+// for 127.0.0.1:80. Addresses are made of 32-bit words in host byte order.
+func parseInetAddr(s string) (string, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid address %q", s)
+	}
+	words, err := hex.DecodeString(parts[0])
+	if err != nil || (len(words) != net.IPv4len && len(words) != net.IPv6len) {
+		return "", fmt.Errorf("invalid address %q", s)
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "", fmt.Errorf("invalid port in %q", s)
+	}
+
+	ip := make(net.IP, len(words))
+	for i := 0; i < len(words); i += 4 {
+		binary.LittleEndian.PutUint32(ip[i:], binary.BigEndian.Uint32(words[i:]))
+	}
+
+	return net.JoinHostPort(ip.String(), strconv.FormatUint(port, 10)), nil
+}
+
+// findUnixSocket returns the path of a socket in /proc/net/unix, whose lines
+// look like:
+//
+//	Num       RefCount Protocol Flags    Type St Inode Path
+//	0000000000000000: 00000002 00000000 00010000 0001 01 20437 /run/systemd/notify
+func findUnixSocket(path string, inode uint64) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	want := strconv.FormatUint(inode, 10)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 || fields[6] != want {
+			continue
+		}
+		if len(fields) > 7 {
+			return fields[7], true
+		}
+		return "", true
+	}
+
+	return "", false
+}
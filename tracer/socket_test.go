@@ -0,0 +1,252 @@
+package tracer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// socket tables of a network namespace, as in /proc/<pid>/net
+var socketTables = map[string]string{
+	"tcp": `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0200000A:1538 0900000A:A028 01 00000000:00000000 02:000A7214 00000000   999        0 1002 2 0000000000000000 20 4 30 10 -1
+   2: 0100007F:1F90 00000000:0000 07 00000000:00000000 00:00000000 00000000  1000        0 1003 1 0000000000000000 100 0 0 10 0
+   3: 0100007F:ZZZZ 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1004 1 0000000000000000 100 0 0 10 0
+`,
+	"tcp6": `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000001000000:0277 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2001 1 0000000000000000 100 0 0 10 0
+   1: 0000000000000000FFFF00000100007F:0050 0000000000000000FFFF00000200007F:D431 01 00000000:00000000 00:00000000 00000000    33        0 2002 1 0000000000000000 20 4 29 10 -1
+`,
+	"udp": `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  123: 00000000:0044 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 3001 2 0000000000000000 0
+  456: 0F02000A:E1B5 0101A8C0:0035 01 00000000:00000000 00:00000000 00000000   101        0 3002 2 0000000000000000 0
+`,
+	"udp6": `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+`,
+	"unix": `Num       RefCount Protocol Flags    Type St Inode Path
+0000000000000000: 00000002 00000000 00010000 0001 01 4001 /run/systemd/notify
+0000000000000000: 00000003 00000000 00000000 0001 03 4002
+0000000000000000: 00000002 00000000 00010000 0001 01 4003 @/tmp/.X11-unix/X0
+`,
+}
+
+func writeSocketTables(t *testing.T, tables map[string]string) string {
+	dir, err := ioutil.TempDir("", "socket-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, table := range tables {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(table), 0644); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestParseInetAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+		err  bool
+	}{
+		{"0100007F:0050", "127.0.0.1:80", false},
+		{"0200000A:1538", "10.0.0.2:5432", false},
+		{"00000000:0000", "0.0.0.0:0", false},
+		{"00000000000000000000000001000000:0277", "[::1]:631", false},
+		{"00000000000000000000000000000000:0016", "[::]:22", false},
+		// IPv4-mapped addresses of tcp6 sockets
+		{"0000000000000000FFFF00000100007F:0050", "127.0.0.1:80", false},
+		{"B80D0120000000000000000001000000:01BB", "[2001:db8::1]:443", false},
+
+		{"0100007F", "", true},
+		{"0100007F:0050:1", "", true},
+		{"0100:0050", "", true},
+		{"XX00007F:0050", "", true},
+		{"0100007F:10000", "", true},
+		{"0100007F:ZZ", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseInetAddr(tt.addr)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseInetAddr(%q) = %q, expected an error", tt.addr, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseInetAddr(%q) = %q, %v, want %q", tt.addr, got, err, tt.want)
+		}
+	}
+}
+
+func TestFindInetSocket(t *testing.T) {
+	dir := writeSocketTables(t, socketTables)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		table  string
+		inode  uint64
+		local  string
+		remote string
+		state  uint64
+		ok     bool
+	}{
+		{"tcp", 1001, "0.0.0.0:80", "0.0.0.0:0", tcpListen, true},
+		{"tcp", 1002, "10.0.0.2:5432", "10.0.0.9:41000", 0x01, true},
+		{"tcp", 1003, "127.0.0.1:8080", "0.0.0.0:0", tcpClose, true},
+		// malformed port
+		{"tcp", 1004, "", "", 0, false},
+		// the inode column must match exactly, not the other columns
+		{"tcp", 100, "", "", 0, false},
+		{"tcp", 0, "", "", 0, false},
+		{"tcp6", 2001, "[::1]:631", "[::]:0", tcpListen, true},
+		{"tcp6", 2002, "127.0.0.1:80", "127.0.0.2:54321", 0x01, true},
+		{"udp", 3001, "0.0.0.0:68", "0.0.0.0:0", tcpClose, true},
+		{"udp", 3002, "10.0.2.15:57781", "192.168.1.1:53", 0x01, true},
+		{"udp6", 3001, "", "", 0, false},
+		{"nonexistent", 1001, "", "", 0, false},
+	}
+	for _, tt := range tests {
+		local, remote, state, ok := findInetSocket(filepath.Join(dir, tt.table), tt.inode)
+		if local != tt.local || remote != tt.remote || state != tt.state || ok != tt.ok {
+			t.Errorf("%s %d: got %q %q %#x %t, want %q %q %#x %t", tt.table, tt.inode,
+				local, remote, state, ok, tt.local, tt.remote, tt.state, tt.ok)
+		}
+	}
+}
+
+func TestFindUnixSocket(t *testing.T) {
+	dir := writeSocketTables(t, socketTables)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		inode uint64
+		path  string
+		ok    bool
+	}{
+		{4001, "/run/systemd/notify", true},
+		// unnamed socket, e.g. of a socketpair
+		{4002, "", true},
+		// abstract socket
+		{4003, "@/tmp/.X11-unix/X0", true},
+		{4004, "", false},
+		{1, "", false},
+	}
+	for _, tt := range tests {
+		path, ok := findUnixSocket(filepath.Join(dir, "unix"), tt.inode)
+		if path != tt.path || ok != tt.ok {
+			t.Errorf("%d: got %q %t, want %q %t", tt.inode, path, ok, tt.path, tt.ok)
+		}
+	}
+
+	if _, ok := findUnixSocket(filepath.Join(dir, "nonexistent"), 4001); ok {
+		t.Errorf("found a socket in a nonexistent table")
+	}
+}
+
+func TestLookupSocket(t *testing.T) {
+	dir := writeSocketTables(t, socketTables)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		inode uint64
+		name  string
+		final bool
+	}{
+		{1001, "tcp 0.0.0.0:80 (listen)", true},
+		{1002, "tcp 10.0.0.2:5432->10.0.0.9:41000", true},
+		// not connected yet
+		{1003, "tcp 127.0.0.1:8080", false},
+		{2001, "tcp [::1]:631 (listen)", true},
+		{2002, "tcp 127.0.0.1:80->127.0.0.2:54321", true},
+		// may be connected later
+		{3001, "udp 0.0.0.0:68", false},
+		{3002, "udp 10.0.2.15:57781->192.168.1.1:53", true},
+		{4001, "unix /run/systemd/notify", true},
+		{4002, "unix", true},
+		{9999, "", false},
+	}
+	for _, tt := range tests {
+		name, final := lookupSocket(dir, tt.inode)
+		if name != tt.name || final != tt.final {
+			t.Errorf("%d: got %q %t, want %q %t", tt.inode, name, final, tt.name, tt.final)
+		}
+	}
+}
+
+func TestResolveSocketCache(t *testing.T) {
+	dir := writeSocketTables(t, socketTables)
+	defer os.RemoveAll(dir)
+
+	fds := NewFdMap()
+	now := time.Unix(1000, 0)
+	resolve := func(fd uint32, now time.Time) string {
+		info, ok := fds.Get(1, fd)
+		if !ok {
+			t.Fatalf("no entry for fd %d", fd)
+		}
+		return fds.resolveSocket(dir, 1, fd, info, now)
+	}
+
+	fds.Put(1, 3, FdInfo{Path: "socket:[1002]", Ino: 1002})
+	fds.Put(1, 4, FdInfo{Path: "socket:[3001]", Ino: 3001})
+	fds.Put(1, 5, FdInfo{Path: "socket:[5001]", Ino: 5001})
+
+	if got := resolve(3, now); got != "tcp 10.0.0.2:5432->10.0.0.9:41000" {
+		t.Errorf("got %q", got)
+	}
+	if got := resolve(4, now); got != "udp 0.0.0.0:68" {
+		t.Errorf("got %q", got)
+	}
+	// sockets that aren't found fall back to the path, cached too
+	if got := resolve(5, now); got != "socket:[5001]" {
+		t.Errorf("got %q", got)
+	}
+
+	// the UDP socket gets connected, the missing one appears, and the
+	// connected TCP one is gone from the tables
+	tables := make(map[string]string)
+	for name, table := range socketTables {
+		tables[name] = table
+	}
+	tables["udp"] = strings.Replace(tables["udp"], "00000000:0044 00000000:0000 07", "00000000:0044 0101A8C0:0043 01", 1)
+	tables["tcp"] = strings.Replace(tables["tcp"], " 1002 ", " 1005 ", 1)
+	tables["unix"] += "0000000000000000: 00000002 00000000 00010000 0001 01 5001 /run/new.sock\n"
+	for name, table := range tables {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(table), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the tables aren't read again before socketRetryInterval
+	soon := now.Add(socketRetryInterval / 2)
+	for fd, want := range map[uint32]string{
+		3: "tcp 10.0.0.2:5432->10.0.0.9:41000",
+		4: "udp 0.0.0.0:68",
+		5: "socket:[5001]",
+	} {
+		if got := resolve(fd, soon); got != want {
+			t.Errorf("fd %d before the retry: got %q, want %q", fd, got, want)
+		}
+	}
+
+	// then only the entries that could change are looked up again
+	later := now.Add(2 * socketRetryInterval)
+	for fd, want := range map[uint32]string{
+		3: "tcp 10.0.0.2:5432->10.0.0.9:41000",
+		4: "udp 0.0.0.0:68->192.168.1.1:67",
+		5: "unix /run/new.sock",
+	} {
+		if got := resolve(fd, later); got != want {
+			t.Errorf("fd %d after the retry: got %q, want %q", fd, got, want)
+		}
+		if info, _ := fds.Get(1, fd); info.SocketRetry != 0 {
+			t.Errorf("fd %d: final entry %+v will be looked up again", fd, info)
+		}
+	}
+}
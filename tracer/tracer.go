@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"log"
 	"unsafe"
 
 	elflib "github.com/iovisor/gobpf/elf"
@@ -24,6 +25,7 @@ type CommonEvent struct {
 	Name      string
 	Hash      uint64
 	Flags     uint64
+	Duration  uint64
 }
 
 func CommonEventFromBuffer(buf *bytes.Buffer) (*CommonEvent, error) {
@@ -40,18 +42,23 @@ func CommonEventFromBuffer(buf *bytes.Buffer) (*CommonEvent, error) {
 	e.Name = C.GoString(nameCstr)
 	e.Hash = binary.LittleEndian.Uint64(buf.Next(8))
 	e.Flags = binary.LittleEndian.Uint64(buf.Next(8))
+	e.Duration = binary.LittleEndian.Uint64(buf.Next(8))
 	return e, nil
 }
 
 type EventData struct {
 	Common CommonEvent
 	Event  Event
+
+	// metadata of the process, nil if unknown (e.g. when replaying)
+	Process *ProcessInfo
 }
 
 type Tracer struct {
 	Probe    *probe.Probe
 	perfMap  *elflib.PerfMap
 	stopChan chan struct{}
+	doneChan chan struct{}
 }
 
 func (e *CommonEvent) Proto() *ProtobufCommonEvent {
@@ -61,6 +68,7 @@ func (e *CommonEvent) Proto() *ProtobufCommonEvent {
 		Ret:       e.Ret,
 		Name:      e.Name,
 		Hash:      e.Hash,
+		Duration:  e.Duration,
 	}
 }
 
@@ -68,8 +76,58 @@ func timestamp(data *[]byte) uint64 {
 	return binary.LittleEndian.Uint64(*data)
 }
 
-func New(callback func(*[]byte), callbackLost func(uint64), cacheSize int) (*Tracer, error) {
-	p, err := probe.New(cacheSize)
+// offset of the name in common_event_t
+const commonEventNameOffset = 32
+
+var (
+	forkEventName = []byte("fork\x00")
+	exitEventName = []byte("exit\x00")
+)
+
+// handleProcessEvent keeps the probe up to date with the life cycle of the
+// traced processes, before the event is passed to the callback.
+func handleProcessEvent(p *probe.Probe, data []byte) {
+	if len(data) < C.sizeof_common_event_t {
+		return
+	}
+	name := data[commonEventNameOffset:]
+	isFork := bytes.HasPrefix(name, forkEventName)
+	isExit := bytes.HasPrefix(name, exitEventName)
+	if !isFork && !isExit {
+		return
+	}
+
+	buf := bytes.NewBuffer(data)
+	ce, err := CommonEventFromBuffer(buf)
+	if err != nil {
+		return
+	}
+
+	if isExit {
+		// drop the handlers and BPF map entries of the process, so they
+		// don't fill up the maps in long running tracers
+		if err := p.UnregisterHandler(ce.ProgramID, int(ce.Pid)); err != nil {
+			log.Printf("%v", err)
+		}
+		return
+	}
+
+	if buf.Len() < 8 {
+		return
+	}
+	child := binary.LittleEndian.Uint64(buf.Next(8))
+
+	// the global BPF program already routes the child's syscalls to the
+	// handlers of its parent, registering them for the child directly only
+	// makes it independent of the parent's registration, so errors are not
+	// fatal
+	if err := p.InheritHandlers(int(ce.Pid), int(child)); err != nil {
+		log.Printf("error inheriting the handlers of pid %d for pid %d: %v", ce.Pid, child, err)
+	}
+}
+
+func New(callback func(*[]byte), callbackLost func(uint64), cacheSize int, backend probe.Backend) (*Tracer, error) {
+	p, err := probe.New(cacheSize, backend)
 	if err != nil {
 		return nil, fmt.Errorf("error loading probe: %v", err)
 	}
@@ -84,7 +142,9 @@ func New(callback func(*[]byte), callbackLost func(uint64), cacheSize int) (*Tra
 	perfMap.SetTimestampFunc(timestamp)
 
 	stopChan := make(chan struct{})
+	doneChan := make(chan struct{})
 	go func() {
+		defer close(doneChan)
 		for {
 			select {
 			case <-stopChan:
@@ -96,6 +156,7 @@ func New(callback func(*[]byte), callbackLost func(uint64), cacheSize int) (*Tra
 				if !ok {
 					return // see explanation above
 				}
+				handleProcessEvent(p, data)
 				callback(&data)
 			case lostCount, ok := <-channelLost:
 				if !ok {
@@ -112,12 +173,16 @@ func New(callback func(*[]byte), callbackLost func(uint64), cacheSize int) (*Tra
 		Probe:    p,
 		perfMap:  perfMap,
 		stopChan: stopChan,
+		doneChan: doneChan,
 	}, nil
 }
 
 func (t *Tracer) Stop() {
 	t.perfMap.PollStop()
 	close(t.stopChan)
+	// wait for a callback in progress to return, callers can then safely
+	// release the resources used by the callbacks
+	<-t.doneChan
 
 	t.Probe.Close()
 }